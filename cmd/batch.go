@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/batch"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+)
+
+// newBatchCmd builds the batch command, which sends the requests described in a JSONL file.
+func newBatchCmd() *cobra.Command {
+	var concurrency int
+	var results string
+	cmd := &cobra.Command{
+		Use:   "batch [flags] <requests.jsonl|->",
+		Short: "Send the requests described in a JSONL file, one per line",
+		Long: `Send the requests described in a JSONL file, one per line.
+
+Every line is a JSON object with the fields:
+  url            Url to request, or the resource sent through socket. Required.
+  method         Request method. Defaults to GET.
+  headers        Headers, as an object or a list of "Name: value".
+  body           Body, as a string sent as is, or any other JSON value sent as JSON.
+  expect_status  Fails the request unless the response status is one of these: a code, such as 201, or
+                 a string of codes and classes, as taken by --expect-status, such as "2xx" or "200,204".
+  socket         Sends url through this Unix domain socket instead of over HTTP.
+  name           Name shown in the summary of failures.
+
+A JSON record of every request, with the request, the response, timings and whether it met its
+expectations, is written on the same line of the results file as the request in the input. Blank
+input lines stay blank. A summary of the failures is printed to stderr.`,
+		Example: `  scour batch requests.jsonl > results.ndjson
+  scour batch -j 16 -r results.ndjson requests.jsonl`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if concurrency < 1 {
+				return fmt.Errorf("--concurrency must be at least 1, got %d", concurrency)
+			}
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			return runBatch(args[0], results, concurrency)
+		},
+	}
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "j", batch.DefaultConcurrency, "Maximum number of requests in flight at once.")
+	cmd.Flags().StringVarP(&results, "results", "r", transfer.Stdout, "Write the NDJSON results to <file> instead of stdout.")
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	return cmd
+}
+
+// runBatch sends the requests of the batch file, writes their records to the results file and prints
+// a summary of the failures. It returns an exitError for the first failed request.
+func runBatch(file, results string, concurrency int) error {
+	printBanner()
+	var r io.Reader = os.Stdin
+	if file != transfer.Stdout {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	lines, err := batch.Read(r)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+
+	var jobs []transfer.Job
+	for i, line := range lines {
+		if line.Request == nil {
+			continue
+		}
+		job := line.Request.Job()
+		if err := expandJob(&job, Vars); err != nil {
+			lines[i].Request, lines[i].Err = nil, err
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	res, err := transferJobs(jobs, true, concurrency)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if results != transfer.Stdout {
+		f, err := os.Create(results)
+		if err != nil {
+			log.Printf("Error writing results to %s: %s\n", results, err.Error())
+			return &exitError{exitcode.WriteError}
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(maskWriter{w})
+	var failed []*batch.Record
+	var requests int
+	for _, line := range lines {
+		if line.Request == nil && line.Err == nil {
+			_, err = io.WriteString(w, "\n")
+		} else {
+			var result *transfer.Result
+			if line.Request != nil {
+				result = &res[0]
+				res = res[1:]
+			}
+			rec := batch.NewRecord(line, result)
+			if !rec.OK {
+				failed = append(failed, rec)
+			}
+			requests++
+			err = enc.Encode(rec)
+		}
+		if err != nil {
+			log.Printf("Error writing results: %s\n", err.Error())
+			return &exitError{exitcode.WriteError}
+		}
+	}
+
+	log.Printf("batch: %d requests, %d failed\n", requests, len(failed))
+	for _, rec := range failed {
+		name := ""
+		if len(rec.Name) > 0 {
+			name = " (" + rec.Name + ")"
+		}
+		target := ""
+		if rec.Envelope != nil {
+			target = " " + rec.Request.Method + " " + rec.Url + ":"
+		}
+		fmt.Fprint(os.Stderr, mask(fmt.Sprintf("  line %d%s:%s %s\n", rec.Line, name, target, rec.Failure().Message)))
+	}
+	if len(failed) > 0 {
+		return &exitError{failed[0].ExitCode()}
+	}
+	return nil
+}
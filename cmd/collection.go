@@ -0,0 +1,491 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/batch"
+	"github.com/dark-enstein/scour/internal/collection"
+	"github.com/dark-enstein/scour/internal/dal"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/scenario"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	// collectionNameRe matches the runs of characters replaced with dashes when a collection name is derived
+	// from the name of an imported collection.
+	collectionNameRe = regexp.MustCompile(`[^a-z0-9_.-]+`)
+)
+
+// newCollectionCmd builds the collection command, which saves requests under a name and folder, runs them,
+// and shares them with Postman.
+func newCollectionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collection",
+		Short: "Save requests in named collections and folders, run them, and import or export Postman collections",
+		Long: `Save requests in named collections and folders, run them, and import or export Postman collections.
+
+A collection is a set of requests organized in folders, kept in a file under ~/.scour/collections, or
+$SCOUR_HOME/collections, named after the collection. Requests are named by their path, as in
+users-api/accounts/create: the collection, the folders, then the name of the request. Names may hold
+spaces, but no slashes.
+
+Requests are saved as written, with their {{name}} references, and templated when they are run with
+the variables of the collection, overridden by the environment, then by --var. Collections convert to
+and from Postman Collection v2.1 JSON, so they can be shared with Postman users.`,
+	}
+	cmd.AddCommand(newCollectionSaveCmd(), newCollectionListCmd(), newCollectionTreeCmd(), newCollectionRunCmd(),
+		newCollectionRmCmd(), newCollectionImportCmd(), newCollectionExportCmd())
+	return cmd
+}
+
+// newCollectionSaveCmd builds the collection save command.
+func newCollectionSaveCmd() *cobra.Command {
+	var item collection.Item
+	var expectStatus string
+	req := &batch.Request{}
+	cmd := &cobra.Command{
+		Use:   "save [flags] <collection>/[<folder>/...]<name> <url>",
+		Short: "Save a request in a collection, creating the collection and folders if needed",
+		Long: `Save a request in a collection, creating the collection and folders if needed.
+
+The request is saved with the method, headers and data passed in, as written: {{name}} references
+are kept, and templated when the request is run. Data passed with @file is read when saving. A request
+already saved under the same path is replaced. --expect-status takes codes and classes, such as 200,204
+or 2xx, as it does for a transfer, and is checked when the request is run.`,
+		Example: `  scour collection save users-api/accounts/list '{{base_url}}/users'
+  scour collection save -X POST -H 'Content-Type: application/json' -d '{"name": "{{name}}"}' \
+        --expect-status 201 users-api/accounts/create '{{base_url}}/users'
+  scour collection save --socket /var/run/docker.sock docker/images http:/images/json`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeCollectionPaths,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			if len(FLGS.Export) > 0 {
+				return fmt.Errorf("--export can't be used to save a request")
+			}
+			data, err := requestData(FLGS.Data)
+			if err != nil {
+				return err
+			}
+			req.Method, req.Url, req.Headers = FLGS.Method, args[1], FLGS.Headers
+			if len(data) > 0 {
+				req.Body = collection.StringBody(string(data))
+			}
+			if len(expectStatus) > 0 {
+				if req.ExpectStatus, err = batch.ParseStatus(expectStatus); err != nil {
+					return fmt.Errorf("--expect-status: %w", err)
+				}
+			}
+			if len(req.Socket) > 0 {
+				req.Method = ""
+			}
+			if err := req.Validate(); err != nil {
+				return err
+			}
+			item.Request = req
+			return saveRequest(args[0], &item)
+		},
+	}
+	cmd.Flags().AddFlagSet(requestFlags(FLGS))
+	cmd.Flags().StringVar(&expectStatus, "expect-status", "", "Fail the request when it runs, unless the response status is one of these, comma-separated, e.g. 200,204 or 2xx.")
+	cmd.Flags().StringVar(&req.Socket, "socket", "", "Send <url> as the resource through this Unix domain socket, instead of over HTTP.")
+	cmd.Flags().StringVar(&item.Description, "description", "", "Describe the request.")
+	return cmd
+}
+
+// newCollectionListCmd builds the collection list command.
+func newCollectionListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [<collection>[/<folder>...]]",
+		Short: "List the collections, or the requests of a collection or folder",
+		Example: `  scour collection list
+  scour collection list users-api/accounts`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeCollectionPaths,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return listCollections()
+			}
+			return listCollectionRequests(args[0])
+		},
+	}
+}
+
+// newCollectionTreeCmd builds the collection tree command.
+func newCollectionTreeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "tree [<collection>...]",
+		Short:             "Print collections as trees of their folders and requests, every collection by default",
+		ValidArgsFunction: completeCollectionPaths,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names := args
+			if len(names) == 0 {
+				var err error
+				if names, err = dal.Collections(); err != nil {
+					return err
+				}
+			}
+			for i, name := range names {
+				c, _, err := loadCollection(name)
+				if err != nil {
+					return err
+				}
+				if i > 0 {
+					fmt.Println()
+				}
+				if err := c.Tree(os.Stdout); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// newCollectionRunCmd builds the collection run command.
+func newCollectionRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run [flags] <collection>[/<folder>...][/<name>]",
+		Short: "Send a saved request, or every request of a folder or collection in order",
+		Long: `Send a saved request, or every request of a folder or collection in order.
+
+Requests are templated with the variables of the collection, overridden by the environment, then by
+--var. They are sent one after the other, in the order they were saved, folders included, and the
+first one that fails stops the run: a failed request, or a status other than the expected one, or
+>= 400 without one. The outcome of every request is printed to stderr, and response bodies to stdout,
+or to the files passed with -o, paired with the requests in order.`,
+		Example: `  scour collection run users-api/accounts/list
+  scour collection run --env staging users-api/accounts
+  scour collection run -o /dev/null users-api`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeCollectionPaths,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			name, c, p, entries, err := collectionRequests(args[0])
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				return fmt.Errorf("%s holds no requests", args[0])
+			}
+			s := &scenario.Scenario{Name: args[0], Vars: c.Vars, Path: p}
+			for _, e := range entries {
+				step := scenario.Step{Request: *e.Request}
+				step.Name = name + collection.Sep + e.Path
+				s.Steps = append(s.Steps, step)
+			}
+			return runScenario(s)
+		},
+	}
+	cmd.Flags().AddFlagSet(bodyFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	return cmd
+}
+
+// newCollectionRmCmd builds the collection rm command.
+func newCollectionRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm <collection>[/<folder>...][/<name>]",
+		Short: "Remove a request, a folder with its requests, or a whole collection",
+		Example: `  scour collection rm users-api/accounts/create
+  scour collection rm users-api`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeCollectionPaths,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, names, err := collection.SplitPath(args[0])
+			if err != nil {
+				return err
+			}
+			if len(names) == 0 {
+				return dal.RemoveCollection(name)
+			}
+			c, p, err := loadCollection(name)
+			if err != nil {
+				return err
+			}
+			if err := c.Remove(names); err != nil {
+				return err
+			}
+			return c.Write(p)
+		},
+	}
+}
+
+// newCollectionImportCmd builds the collection import command.
+func newCollectionImportCmd() *cobra.Command {
+	var name string
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "import [flags] <postman_collection.json|->",
+		Short: "Import a Postman Collection v2.1 as a collection",
+		Long: `Import a Postman Collection v2.1 as a collection.
+
+The collection is named after the Postman collection, lowercased with spaces replaced by dashes,
+unless --name is passed. Folders and requests keep their order, with slashes in their names replaced
+by dashes, and collection variables become the variables of the collection. Bearer, basic and API key
+authentication, inherited or not, become headers, and the status assertions of test scripts,
+pm.response.to.have.status(...), the expected status. What scour can't carry over, such as form-data
+bodies or other scripts, is left out with a warning.`,
+		Example: `  scour collection import "Users API.postman_collection.json"
+  scour collection import --name users-api --force users.json`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importCollection(args[0], name, force)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "Name the collection, instead of deriving the name from the Postman collection.")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Replace a collection of the same name.")
+	return cmd
+}
+
+// newCollectionExportCmd builds the collection export command.
+func newCollectionExportCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export [flags] <collection>",
+		Short: "Export a collection as a Postman Collection v2.1",
+		Long: `Export a collection as a Postman Collection v2.1, to stdout unless -o is passed.
+
+Variables of the collection become collection variables, and the expected status of requests a test
+script asserting it. Bodies are exported raw, as JSON when they were saved as JSON. Socket requests,
+which Postman can't send, are left out with a warning.`,
+		Example:           `  scour collection export users-api -o "Users API.postman_collection.json"`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeCollectionPaths,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportCollection(args[0], output)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the Postman collection to <file> instead of stdout.")
+	return cmd
+}
+
+// loadCollection reads the collection called name, and returns it along with the path of its file.
+func loadCollection(name string) (*collection.Collection, string, error) {
+	p, err := dal.CollectionPath(name)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := collection.Read(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", fmt.Errorf("%w: %s", dal.ErrNoCollection, name)
+	}
+	return c, p, err
+}
+
+// collectionRequests returns the requests at the path p, along with the name of their collection, the
+// collection and the path of its file.
+func collectionRequests(p string) (string, *collection.Collection, string, []collection.Entry, error) {
+	name, names, err := collection.SplitPath(p)
+	if err != nil {
+		return "", nil, "", nil, err
+	}
+	c, file, err := loadCollection(name)
+	if err != nil {
+		return "", nil, "", nil, err
+	}
+	entries, err := c.Requests(names)
+	if errors.Is(err, collection.ErrNoItem) {
+		err = fmt.Errorf("%w: %s", collection.ErrNoItem, p)
+	}
+	return name, c, file, entries, err
+}
+
+// saveRequest stores the request of item at the path p, creating the collection if needed.
+func saveRequest(p string, item *collection.Item) error {
+	name, names, err := collection.SplitPath(p)
+	if err != nil {
+		return err
+	}
+	c, file, err := loadCollection(name)
+	if errors.Is(err, dal.ErrNoCollection) {
+		if file, err = dal.CollectionPath(name); err == nil {
+			c = &collection.Collection{Name: name}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	replaced, err := c.Put(names, item.Request)
+	if err != nil {
+		return err
+	}
+	if len(item.Description) > 0 {
+		c.Find(names).Description = item.Description
+	}
+	if err := c.Write(file); err != nil {
+		return err
+	}
+	verb := "saved"
+	if replaced {
+		verb = "replaced"
+	}
+	log.Printf("%s %s %s as %s\n", verb, collection.Method(item.Request), item.Request.Url, p)
+	return nil
+}
+
+// listCollections prints the name, number of requests and title of every collection.
+func listCollections() error {
+	names, err := dal.Collections()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		c, _, err := loadCollection(name)
+		if err != nil {
+			return err
+		}
+		entries, _ := c.Requests(nil)
+		title := c.Name
+		if title == name {
+			title = ""
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, requests(len(entries)), title)
+	}
+	return w.Flush()
+}
+
+// listCollectionRequests prints the method, path and url of every request at the path p.
+func listCollectionRequests(p string) error {
+	name, _, _, entries, err := collectionRequests(p)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, e := range entries {
+		method, url := collection.Method(e.Request), e.Request.Url
+		if len(e.Request.Socket) > 0 {
+			url = e.Request.Socket + " " + url
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", method, name+collection.Sep+e.Path, url)
+	}
+	return w.Flush()
+}
+
+// importCollection imports the Postman collection file p as the collection called name, or named after it.
+func importCollection(p, name string, force bool) error {
+	var b []byte
+	var err error
+	if p == transfer.Stdout {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(p)
+	}
+	if err != nil {
+		return err
+	}
+	c, warnings, err := collection.ImportPostman(b)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p, err)
+	}
+	if len(name) == 0 {
+		name = strings.Trim(collectionNameRe.ReplaceAllString(strings.ToLower(c.Name), "-"), "-.")
+		if len(name) == 0 {
+			return fmt.Errorf("%s: the collection has no usable name, please pass one with --name", p)
+		}
+	}
+	file, err := dal.CollectionPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(file); err == nil && !force {
+		return fmt.Errorf("collection %s already exists, pass --force to replace it", name)
+	}
+	if len(c.Name) == 0 {
+		c.Name = name
+	}
+	for _, w := range warnings {
+		log.Printf("Warning: %s\n", w)
+	}
+	if err := c.Write(file); err != nil {
+		return err
+	}
+	entries, _ := c.Requests(nil)
+	log.Printf("imported %s into collection %s\n", requests(len(entries)), name)
+	return nil
+}
+
+// exportCollection writes the collection called name to output as a Postman collection, or to stdout.
+func exportCollection(name, output string) error {
+	c, _, err := loadCollection(name)
+	if err != nil {
+		return err
+	}
+	b, warnings, err := c.ExportPostman()
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		log.Printf("Warning: %s\n", w)
+	}
+	if len(output) == 0 || output == transfer.Stdout {
+		_, err = os.Stdout.Write(b)
+	} else {
+		err = os.WriteFile(output, b, 0o644)
+	}
+	if err != nil {
+		log.Printf("Error writing %s: %s\n", output, err.Error())
+		return &exitError{exitcode.WriteError}
+	}
+	return nil
+}
+
+// requests counts the requests of a collection.
+func requests(n int) string {
+	if n == 1 {
+		return "1 request"
+	}
+	return fmt.Sprintf("%d requests", n)
+}
+
+// completeCollectionPaths completes the paths of collections, and of their folders and requests.
+func completeCollectionPaths(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, err := dal.Collections()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var paths []string
+	for _, name := range names {
+		if !strings.HasPrefix(toComplete, name+collection.Sep) {
+			paths = append(paths, name)
+			continue
+		}
+		c, _, err := loadCollection(name)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, itemPaths(c.Items, name)...)
+	}
+	return paths, cobra.ShellCompDirectiveNoFileComp
+}
+
+// itemPaths returns the paths of items and the items below them, prefixed with prefix.
+func itemPaths(items []*collection.Item, prefix string) []string {
+	var paths []string
+	for _, item := range items {
+		p := prefix + collection.Sep + item.Name
+		paths = append(paths, p)
+		paths = append(paths, itemPaths(item.Items, p)...)
+	}
+	return paths
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/dal"
+	"github.com/dark-enstein/scour/internal/utils"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// completionFunc completes the value of a flag or argument.
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+var (
+	// flagCompletions maps flag names to the function completing their values.
+	flagCompletions = map[string]completionFunc{
+		"request":       completeMethods,
+		"data":          completeDataFile,
+		"output-format": completeOutputFormats,
+		"export":        completeExportTargets,
+		"env":           completeEnvs,
+		"output":        completeFiles,
+		"dump-header":   completeFiles,
+		"trace":         completeFiles,
+		"trace-ascii":   completeFiles,
+	}
+)
+
+// newCompletionCmd builds the completion command, which generates shell completion scripts.
+func newCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish",
+		Short: "Generate the completion script for a shell",
+		Long: `Generate the completion script for a shell.
+
+Scour completes flags, HTTP methods, files for -o and --data @file, Unix socket paths, and urls of the
+hosts requested before. To load completions in the current shell:
+
+  bash:  source <(scour completion bash)
+  zsh:   source <(scour completion zsh)
+  fish:  scour completion fish | source
+
+To load them in every session, write the script to the completion directory of the shell instead, e.g.
+/etc/bash_completion.d/scour, a directory in $fpath named _scour, or ~/.config/fish/completions/scour.fish.`,
+		ValidArgs:             []string{"bash", "zsh", "fish"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			default:
+				return root.GenFishCompletion(os.Stdout, true)
+			}
+		},
+	}
+}
+
+// registerCompletions registers the completion functions of every flag of cmd listed in flagCompletions.
+func registerCompletions(cmd *cobra.Command) {
+	for name, fn := range flagCompletions {
+		if cmd.Flag(name) != nil {
+			_ = cmd.RegisterFlagCompletionFunc(name, fn)
+		}
+	}
+}
+
+// completeMethods completes the HTTP methods scour supports.
+func completeMethods(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	var methods []string
+	for _, m := range config.AllSupportedConn {
+		if m != config.MethodSocket {
+			methods = append(methods, m)
+		}
+	}
+	return methods, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOutputFormats completes the formats --output-format supports.
+func completeOutputFormats(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return config.AllOutputFormats, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeExportTargets completes the targets --export supports.
+func completeExportTargets(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return config.AllExportTargets, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeEnvs completes the names of the environments that have a file.
+func completeEnvs(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	names, err := dal.Envs()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFiles leaves completion of file paths to the shell.
+func completeFiles(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return nil, cobra.ShellCompDirectiveDefault
+}
+
+// completeDataFile completes the file paths of --data @file. Inline data isn't completed.
+func completeDataFile(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if !strings.HasPrefix(toComplete, "@") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	paths, directive := completePaths(toComplete[1:], func(string) bool { return true })
+	for i := range paths {
+		paths[i] = "@" + paths[i]
+	}
+	return paths, directive
+}
+
+// completeSockets completes the paths of Unix domain sockets, descending into directories.
+func completeSockets(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completePaths(toComplete, func(path string) bool {
+		ok, _ := utils.IsSocket(path)
+		return ok
+	})
+}
+
+// completeHosts completes urls from the origins of the hosts requested before.
+func completeHosts(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	hosts, err := dal.Hosts()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var urls []string
+	for _, h := range hosts {
+		if strings.HasPrefix(h, toComplete) {
+			urls = append(urls, h)
+		}
+	}
+	return urls, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeUrls completes the arguments of the default command: socket paths first in socket mode, urls
+// otherwise.
+func completeUrls(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if FLGS.UnixSocket {
+		if len(args) == 0 {
+			return completeSockets(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeHosts(cmd, args, toComplete)
+}
+
+// completePaths lists the entries of the directory toComplete points into whose name starts with the
+// rest of toComplete. Directories are always listed, with a trailing slash, while files only when keep
+// returns true for their path. Hidden entries are left out unless toComplete asks for them.
+func completePaths(toComplete string, keep func(path string) bool) ([]string, cobra.ShellCompDirective) {
+	dir, prefix := filepath.Split(toComplete)
+	read := dir
+	if len(read) == 0 {
+		read = "."
+	}
+	entries, err := os.ReadDir(read)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var paths []string
+	directive := cobra.ShellCompDirectiveNoFileComp
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		path := dir + name
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			paths = append(paths, fmt.Sprintf("%s%c", path, filepath.Separator))
+			directive |= cobra.ShellCompDirectiveNoSpace
+			continue
+		}
+		if keep(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths, directive
+}
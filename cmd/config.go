@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/exp/slices"
+	"io/fs"
+	neturl "net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	// RC holds the config file loaded for the invocation, if any
+	RC *config.RC
+	// envApplied lists the SCOUR_* environment variables applied to the flags of the invocation
+	envApplied []string
+	// explicit holds the flags set on the command line or through the environment. Config files don't override them.
+	explicit map[string]bool
+	// noConfigFlags lists the flags that can't be set from a config file or the environment.
+	noConfigFlags = []string{"config", "disable", "help"}
+)
+
+// newConfigCmd builds the config command, which inspects the options read from config files.
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the options read from the config file and the environment",
+		Long: `Inspect the options read from the config file and the environment.
+
+Scour reads default options from ~/.scourrc, or the file passed in with -K/--config. Options are
+named after the long flags, one per line, with their value after whitespace, '=' or ':'. Options
+under a [host pattern] section only apply to requests sent to matching hosts:
+
+  # ~/.scourrc
+  header = "Accept: application/json"
+  fail
+  [*.internal.corp]
+  header = "Authorization: Bearer xyz"
+
+Every flag can also be set through a SCOUR_* environment variable, e.g. SCOUR_FAIL=true or
+SCOUR_OUTPUT_FORMAT=ndjson. The command line overrides the environment, which overrides the
+config file. Values of flags that can be passed more than once, such as --header, add up instead.
+-q/--disable ignores both the config file and the environment.`,
+		Args: cobra.NoArgs,
+	}
+	show := &cobra.Command{
+		Use:   "show [flags]",
+		Short: "Print the effective options, merged from the config file, the environment and the command line",
+		Example: `  scour config show
+  scour config show -K ./ci.scourrc -H 'X-Run: 1'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Print(showConfig(cmd.Flags()))
+			return nil
+		},
+	}
+	show.Flags().AddFlagSet(requestFlags(FLGS))
+	show.Flags().AddFlagSet(bodyFlags(FLGS))
+	show.Flags().AddFlagSet(responseFlags(FLGS))
+	show.Flags().AddFlagSet(transferFlags(FLGS))
+	show.Flags().AddFlagSet(traceFlags(FLGS))
+	cmd.AddCommand(show)
+	return cmd
+}
+
+// loadConfig applies the SCOUR_* environment variables and the config file to the flags of cmd that
+// weren't set on the command line. Host sections of the config file are kept in RC, for newJob.
+func loadConfig(cmd *cobra.Command) error {
+	RC, envApplied, explicit = nil, nil, map[string]bool{}
+	flags := cmd.Flags()
+	flags.Visit(func(f *pflag.Flag) {
+		explicit[f.Name] = true
+	})
+	if FLGS.NoConfig {
+		return nil
+	}
+
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		name := config.EnvName(f.Name)
+		v, ok := os.LookupEnv(name)
+		if !ok || err != nil || f.Name == "disable" || f.Name == "help" || (explicit[f.Name] && !isSlice(f)) {
+			return
+		}
+		if e := setFlag(f, []string{v}); e != nil {
+			err = fmt.Errorf("%s: %w", name, e)
+			return
+		}
+		explicit[f.Name] = true
+		envApplied = append(envApplied, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	p := FLGS.ConfigFile
+	if len(p) == 0 {
+		if p, err = config.DefaultRCPath(); err != nil {
+			return nil
+		}
+	}
+	rc, err := config.ReadRC(p)
+	if errors.Is(err, fs.ErrNotExist) && len(FLGS.ConfigFile) == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := applyRC(rc, flags, cmd.Root().Flags()); err != nil {
+		return err
+	}
+	RC = rc
+	return nil
+}
+
+// applyRC sets the flags of the global section of rc, unless they were set explicitly. Options that are
+// valid scour flags but aren't taken by the command running are skipped.
+func applyRC(rc *config.RC, flags, all *pflag.FlagSet) error {
+	values := map[*pflag.Flag][]string{}
+	var order []*pflag.Flag
+	for _, opt := range rc.Global {
+		if slices.Contains(noConfigFlags, opt.Name) {
+			return fmt.Errorf("%s:%d: %s can't be set in a config file", rc.Path, opt.Line, opt.Name)
+		}
+		if lookupFlag(all, opt.Name) == nil && lookupFlag(flags, opt.Name) == nil {
+			return fmt.Errorf("%s:%d: unknown option %s", rc.Path, opt.Line, opt.Name)
+		}
+		f := lookupFlag(flags, opt.Name)
+		if f == nil || (explicit[f.Name] && !isSlice(f)) {
+			continue
+		}
+		value := opt.Value
+		if !opt.Set {
+			if f.Value.Type() != "bool" {
+				return fmt.Errorf("%s:%d: option %s needs a value", rc.Path, opt.Line, opt.Name)
+			}
+			value = "true"
+		}
+		if _, ok := values[f]; !ok {
+			order = append(order, f)
+		}
+		values[f] = append(values[f], value)
+	}
+	for _, f := range order {
+		if err := setFlag(f, values[f]); err != nil {
+			return fmt.Errorf("%s: %s: %w", rc.Path, f.Name, err)
+		}
+	}
+	for _, s := range rc.Hosts {
+		for _, opt := range s.Options {
+			if opt.Name == "request" && !slices.Contains(config.AllSupportedConn[1:], strings.ToUpper(opt.Value)) {
+				return fmt.Errorf("%s:%d: method %s is not supported. please pass in a supported method: %s", rc.Path, opt.Line, opt.Value, strings.Join(config.AllSupportedConn[1:], ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// applyHostConfig applies the options of the config file sections matching the host of job. Headers add
+// up, while the method is only changed when it wasn't set explicitly.
+func applyHostConfig(job *transfer.Job) {
+	if RC == nil || FLGS.Resolve() == config.MODE_SOCKET {
+		return
+	}
+	u, err := neturl.Parse(job.Url)
+	if err != nil {
+		return
+	}
+	var headers []string
+	for _, opt := range RC.ForHost(u.Hostname()) {
+		switch opt.Name {
+		case "header":
+			headers = append(headers, opt.Value)
+		case "request":
+			if !explicit["request"] && !FLGS.Head {
+				job.Method = strings.ToUpper(opt.Value)
+			}
+		}
+	}
+	if len(headers) > 0 {
+		job.Headers = append(headers, job.Headers...)
+	}
+}
+
+// lookupFlag finds a flag by its long name, or its shorthand for single letter names.
+func lookupFlag(flags *pflag.FlagSet, name string) *pflag.Flag {
+	if len(name) == 1 {
+		return flags.ShorthandLookup(name)
+	}
+	return flags.Lookup(name)
+}
+
+// isSlice reports whether f can be passed more than once.
+func isSlice(f *pflag.Flag) bool {
+	_, ok := f.Value.(pflag.SliceValue)
+	return ok
+}
+
+// setFlag sets f to values. Values of flags that can be passed more than once go before the ones already set.
+func setFlag(f *pflag.Flag, values []string) error {
+	f.Changed = true
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.Replace(append(values, sv.GetSlice()...))
+	}
+	for _, v := range values {
+		if err := f.Value.Set(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// showConfig renders the effective options of flags, and the host sections of the config file, in the
+// config file format.
+func showConfig(flags *pflag.FlagSet) string {
+	var b strings.Builder
+	if RC != nil {
+		fmt.Fprintf(&b, "# config file: %s\n", RC.Path)
+	} else {
+		b.WriteString("# config file: none\n")
+	}
+	if len(envApplied) > 0 {
+		fmt.Fprintf(&b, "# environment: %s\n", strings.Join(envApplied, ", "))
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		if slices.Contains(noConfigFlags, f.Name) || !f.Changed {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range sv.GetSlice() {
+				fmt.Fprintf(&b, "%s = %s\n", f.Name, strconv.Quote(v))
+			}
+			return
+		}
+		switch f.Value.Type() {
+		case "string":
+			fmt.Fprintf(&b, "%s = %s\n", f.Name, strconv.Quote(f.Value.String()))
+		default:
+			fmt.Fprintf(&b, "%s = %s\n", f.Name, f.Value.String())
+		}
+	})
+	if RC != nil {
+		for _, s := range RC.Hosts {
+			fmt.Fprintf(&b, "\n[%s]\n", s.Pattern)
+			for _, opt := range s.Options {
+				fmt.Fprintf(&b, "%s = %s\n", opt.Name, strconv.Quote(opt.Value))
+			}
+		}
+	}
+	return b.String()
+}
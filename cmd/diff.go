@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/jsondiff"
+	"github.com/dark-enstein/scour/internal/respdiff"
+	"github.com/dark-enstein/scour/internal/snapshot"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"log"
+)
+
+// newDiffCmd builds the diff command, which compares the responses of two endpoints, or a response with
+// a saved body.
+func newDiffCmd() *cobra.Command {
+	var ignore snapshot.Ignore
+	var ignoreHeaders []string
+	cmd := &cobra.Command{
+		Use:   "diff [flags] <url|file> <url|file>",
+		Short: "Compare the responses of two urls, or a response with a saved body",
+		Long: `Compare the responses of two urls, or a response with a saved body.
+
+Both urls are requested with the same method, headers and data. Their statuses, headers and bodies
+are compared: JSON bodies value by value, and text bodies line by line. An argument naming an
+existing file, rather than a url, is taken as a saved response body, compared with the other body
+alone. The differences are printed to stdout, one per line, as "~ path: first -> second",
+"- path: first" or "+ path: second", and scour exits with code 102 when there are any.
+
+Values that change on every response are ignored with --ignore-path, a JSONPath into JSON bodies,
+or --ignore-regex, matched against text bodies, JSON strings and header values. Headers named with
+--ignore-header are left out, and "*" leaves out every header. By default, the headers whose values
+vary from one response or server to the next are left out: Age, Content-Length, Date, ETag, Expires,
+Last-Modified, Server, Set-Cookie, Via and X-Request-Id. Passing --ignore-header replaces that list.`,
+		Example: `  scour diff https://old.example.com/users/1 https://new.example.com/users/1
+  scour diff -H 'Authorization: Bearer {{token}}' --ignore-path '$..updated_at' {{old}}/users {{new}}/users
+  scour diff https://example.com/users/1 saved/user-1.json`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeUrls,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			if len(FLGS.Export) > 0 {
+				return fmt.Errorf("--export can't be used to diff responses")
+			}
+			return runDiff(args, ignore, ignoreHeaders)
+		},
+	}
+	cmd.Flags().AddFlagSet(requestFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	cmd.Flags().AddFlagSet(ignoreFlags(&ignore))
+	cmd.Flags().StringArrayVar(&ignoreHeaders, "ignore-header", respdiff.DefaultIgnoreHeaders, "Leave this response header out of the diff, or every header with \"*\". Pass once per header.")
+	return cmd
+}
+
+// runDiff fetches or reads both sides, and prints their differences. It returns an exitError when a
+// request fails, or when the sides differ.
+func runDiff(args []string, ignore snapshot.Ignore, ignoreHeaders []string) error {
+	printBanner()
+	rules, err := ignore.Compile()
+	if err != nil {
+		return err
+	}
+	data, err := requestData(FLGS.Data)
+	if err != nil {
+		return err
+	}
+
+	// sides are read from files, or paired in order with the results of the requests sent for them
+	sides := make([]*transfer.Result, len(args))
+	var jobs []transfer.Job
+	for i, arg := range args {
+		if respdiff.IsFile(arg) {
+			if sides[i], err = respdiff.ReadSaved(arg); err != nil {
+				return err
+			}
+			continue
+		}
+		url, err := Vars.Expand(arg)
+		if err != nil {
+			return fmt.Errorf("url %s: %w", arg, err)
+		}
+		job, err := newJob(url, "", data, FLGS)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	results, err := transferJobs(jobs, true, len(jobs))
+	if err != nil {
+		return err
+	}
+	for i := range sides {
+		if sides[i] == nil {
+			sides[i], results = &results[0], results[1:]
+			if res := sides[i]; res.Err != nil {
+				log.Printf("Error requesting %s %s: %s\n", res.Method, res.Url, res.Err.Error())
+				return &exitError{exitcode.Classify(res.Err)}
+			}
+		}
+	}
+
+	a, b := sides[0], sides[1]
+	changes := respdiff.Compare(a, b, ignoreHeaders, rules)
+	if len(changes) == 0 {
+		log.Printf("no differences between %s and %s\n", a.Url, b.Url)
+		return nil
+	}
+	fmt.Print(mask(fmt.Sprintf("--- %s\n+++ %s\n%s", a.Url, b.Url, jsondiff.Format(changes))))
+	log.Printf("%s between %s and %s\n", differences(len(changes)), a.Url, b.Url)
+	return &exitError{exitcode.Mismatch}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/respdiff"
+	"github.com/dark-enstein/scour/internal/snapshot"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRunDiff checks that scour diff exits with code 102 when the responses differ, and succeeds when they
+// don't, against servers and saved bodies.
+func TestRunDiff(t *testing.T) {
+	t.Setenv("SCOUR_HOME", t.TempDir())
+	flags := *FLGS
+	*FLGS = config.Flags{Method: http.MethodGet, ParallelMax: transfer.DefaultParallelMax}
+	t.Cleanup(func() { *FLGS = flags })
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", r.URL.Path)
+		_, _ = w.Write([]byte(`{"name": "` + r.URL.Query().Get("name") + `"}`))
+	}))
+	defer srv.Close()
+	saved := filepath.Join(t.TempDir(), "ann.json")
+	assert.NoError(t, os.WriteFile(saved, []byte(`{"name":"Ann"}`), 0o600))
+
+	for _, tc := range []struct {
+		a, b string
+		code int
+	}{
+		{srv.URL + "/a?name=Ann", srv.URL + "/b?name=Ann", exitcode.OK},
+		{srv.URL + "/a?name=Ann", srv.URL + "/a?name=Anne", exitcode.Mismatch},
+		{saved, srv.URL + "/a?name=Ann", exitcode.OK},
+		{srv.URL + "/a?name=Anne", saved, exitcode.Mismatch},
+	} {
+		err := runDiff([]string{tc.a, tc.b}, snapshot.Ignore{}, respdiff.DefaultIgnoreHeaders)
+		code := exitcode.OK
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.code
+		} else {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, tc.code, code, tc.a+" "+tc.b)
+	}
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/dal"
+	"github.com/dark-enstein/scour/internal/env"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/dark-enstein/scour/internal/vars"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	// Env holds the environment requests are templated with. It has no name when none is in use
+	Env *env.Env
+	// Vars holds the variables requests are templated with: those of Env, overridden by --var
+	Vars vars.Vars
+	// masker masks the values of secret variables in output, if there are any
+	masker *strings.Replacer
+)
+
+// newEnvCmd builds the env command, which manages the environments requests are templated with.
+func newEnvCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Manage the environments requests are templated with",
+		Long: `Manage the environments requests are templated with.
+
+An environment is a set of variables, such as base_url or token, kept in a file under
+~/.scour/environments, or $SCOUR_HOME/environments, named after the environment:
+
+  # ~/.scour/environments/staging.env
+  base_url = https://staging.example.com
+  secret token = s3cr3t
+
+Variables are substituted wherever {{name}} appears in urls, headers, request data, socket paths and
+resources, and .http files. Values of secret variables are masked as **** in diagnostics, verbose
+output, traces, records and exported snippets. The environment in use is set with "scour env use",
+overridden for a single invocation with --env, and single variables with --var name=value.`,
+		Args: cobra.NoArgs,
+		// the environment in use isn't loaded, so a broken one can still be fixed or replaced
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return loadConfig(cmd)
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the environments, marking the one in use with *",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := dal.Envs()
+			if err != nil {
+				return err
+			}
+			current, err := dal.CurrentEnv()
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				mark := " "
+				if name == current {
+					mark = "*"
+				}
+				fmt.Printf("%s %s\n", mark, name)
+			}
+			return nil
+		},
+	}
+
+	var none bool
+	use := &cobra.Command{
+		Use:   "use [flags] <name>",
+		Short: "Template requests with the variables of an environment from now on",
+		Example: `  scour env use staging
+  scour env use --none`,
+		ValidArgsFunction: completeEnvArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if none == (len(args) == 1) || len(args) > 1 {
+				return fmt.Errorf("please pass either the name of an environment or --none")
+			}
+			if none {
+				return dal.UseEnv("")
+			}
+			return dal.UseEnv(args[0])
+		},
+	}
+	use.Flags().BoolVar(&none, "none", false, "Stop using any environment.")
+
+	show := &cobra.Command{
+		Use:               "show [flags] [<name>]",
+		Short:             "Print the variables of an environment, the one in use by default, with secrets masked",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeEnvArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := FLGS.Env
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return showEnv(name)
+		},
+	}
+
+	var secret bool
+	set := &cobra.Command{
+		Use:   "set [flags] <name> <variable>[=<value>]",
+		Short: "Set a variable of an environment, creating the environment if needed",
+		Long: `Set a variable of an environment, creating the environment if needed.
+
+Without a value, the value is read from the first line of stdin, which keeps secrets out of the shell history.`,
+		Example: `  scour env set staging base_url=https://staging.example.com
+  scour env set --secret staging token < token.txt`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeEnvArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setEnv(args[0], args[1], secret)
+		},
+	}
+	set.Flags().BoolVar(&secret, "secret", false, "Mark the variable as secret, masking its value in output.")
+
+	cmd.AddCommand(list, use, show, set)
+	return cmd
+}
+
+// loadEnv loads the variables requests are templated with: those of the environment passed in with --env,
+// or else of the one in use, overridden by the ones passed in with --var. Diagnostics logged from then on
+// have the values of secret variables masked.
+func loadEnv() error {
+	Env, Vars, masker = nil, nil, nil
+	log.SetOutput(os.Stderr)
+	name := FLGS.Env
+	if len(name) == 0 {
+		var err error
+		if name, err = dal.CurrentEnv(); err != nil && FLGS.Verbose {
+			log.Println("Warning: failed reading the environment in use:", err)
+		}
+	}
+	e := &env.Env{Vars: vars.Vars{}, Secrets: map[string]bool{}}
+	if len(name) > 0 {
+		var err error
+		if e, err = env.Load(name); err != nil {
+			return err
+		}
+	}
+	v := e.Vars.Merge()
+	for _, def := range FLGS.Vars {
+		name, value, err := env.ParseVar(def)
+		if err != nil {
+			return fmt.Errorf("--var: %w", err)
+		}
+		v[name] = value
+	}
+	Env, Vars, masker = e, v, e.Masker(v)
+	log.SetOutput(maskWriter{os.Stderr})
+	return nil
+}
+
+// showEnv prints the variables of the environment called name, or of the one in use when name is empty.
+func showEnv(name string) error {
+	if len(name) == 0 {
+		var err error
+		if name, err = dal.CurrentEnv(); err != nil {
+			return err
+		}
+		if len(name) == 0 {
+			return fmt.Errorf("no environment is in use. Pass in the name of one, or pick one with: scour env use <name>")
+		}
+	}
+	e, err := env.Load(name)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(e.Vars))
+	for n := range e.Vars {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	fmt.Printf("# environment: %s (%s)\n", e.Name, e.Path)
+	for _, n := range names {
+		value := e.Vars[n]
+		if e.Secrets[n] {
+			value = env.Mask
+		}
+		fmt.Println(env.Format(n, value, e.Secrets[n]))
+	}
+	return nil
+}
+
+// setEnv sets a variable of the environment called name from its name=value definition, reading the value
+// from stdin when def has none.
+func setEnv(name, def string, secret bool) error {
+	e, err := env.Load(name)
+	if errors.Is(err, dal.ErrNoEnv) {
+		p, err := dal.EnvPath(name)
+		if err != nil {
+			return err
+		}
+		e = &env.Env{Name: name, Path: p, Vars: vars.Vars{}, Secrets: map[string]bool{}}
+	} else if err != nil {
+		return err
+	}
+	if !strings.Contains(def, "=") {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading the value of %s: %w", def, err)
+		}
+		def += "=" + strings.TrimRight(line, "\r\n")
+	}
+	variable, value, err := env.ParseVar(def)
+	if err != nil {
+		return err
+	}
+	return e.Set(variable, value, secret)
+}
+
+// expandJob substitutes the variables v wherever {{name}} appears in the url, headers and data of job.
+func expandJob(job *transfer.Job, v vars.Vars) error {
+	url, err := v.Expand(job.Url)
+	if err != nil {
+		return fmt.Errorf("url %s: %w", job.Url, err)
+	}
+	job.Url = url
+	return expandRequest(job, v)
+}
+
+// expandRequest substitutes the variables v in the headers and data of job.
+func expandRequest(job *transfer.Job, v vars.Vars) (err error) {
+	headers := make([]string, len(job.Headers))
+	for i, h := range job.Headers {
+		if headers[i], err = v.Expand(h); err != nil {
+			return fmt.Errorf("header %s: %w", h, err)
+		}
+	}
+	if len(headers) > 0 {
+		job.Headers = headers
+	}
+	if strings.Contains(string(job.Data), "{{") {
+		data, err := v.Expand(string(job.Data))
+		if err != nil {
+			return fmt.Errorf("request data: %w", err)
+		}
+		job.Data = []byte(data)
+	}
+	return nil
+}
+
+// mask masks the values of secret variables in s.
+func mask(s string) string {
+	if masker == nil {
+		return s
+	}
+	return masker.Replace(s)
+}
+
+// maskWriter masks the values of secret variables in everything written through it.
+type maskWriter struct {
+	w io.Writer
+}
+
+func (m maskWriter) Write(p []byte) (int, error) {
+	if masker == nil {
+		return m.w.Write(p)
+	}
+	if _, err := io.WriteString(m.w, masker.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// completeEnvArg completes the name of an environment as the first argument.
+func completeEnvArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeEnvs(cmd, args, toComplete)
+}
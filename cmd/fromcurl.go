@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dark-enstein/scour/internal/curl"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+)
+
+// newFromCurlCmd builds the from-curl command, which runs a curl command line with scour.
+func newFromCurlCmd() *cobra.Command {
+	var printOnly bool
+	cmd := &cobra.Command{
+		Use:   "from-curl [flags] ['<curl command>'|-]",
+		Short: "Run a curl command line with scour, or print the equivalent scour invocation",
+		Long: `Run a curl command line with scour, or print the equivalent scour invocation.
+
+The curl command is read from the argument, or from stdin when none or "-" is passed in, and split
+following shell quoting rules, so commands copied from browser devtools work as is. Arguments passed
+in after "--" are used as already split. Curl options scour has no equivalent for are reported, and
+left out.`,
+		Example: `  scour from-curl 'curl -H "Accept: application/json" https://example.com/users'
+  pbpaste | scour from-curl --print
+  scour from-curl -- curl -X POST -d a=1 https://example.com/form`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			argv, err := curlArgs(args)
+			if err != nil {
+				return err
+			}
+			c, err := curl.Parse(argv)
+			if err != nil {
+				return err
+			}
+			for _, opt := range c.Unsupported {
+				log.Printf("Warning: curl option %s is not supported by scour and is left out\n", opt)
+			}
+			if printOnly {
+				fmt.Println("scour " + curl.Join(c.ScourArgs()))
+				return nil
+			}
+			c.Apply(FLGS)
+			return runTransfers(c.Urls)
+		},
+	}
+	cmd.Flags().BoolVarP(&printOnly, "print", "p", false, "Print the equivalent scour invocation instead of running it.")
+	return cmd
+}
+
+// curlArgs returns the curl command line passed in to from-curl, split into arguments.
+func curlArgs(args []string) ([]string, error) {
+	if len(args) > 1 {
+		return args, nil
+	}
+	var line string
+	if len(args) == 0 || args[0] == transfer.Stdout {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading the curl command from stdin: %w", err)
+		}
+		line = string(b)
+	} else {
+		line = args[0]
+	}
+	argv, err := curl.Split(line)
+	if err != nil {
+		return nil, fmt.Errorf("splitting the curl command: %w", err)
+	}
+	return argv, nil
+}
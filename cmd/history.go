@@ -0,0 +1,512 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/dal"
+	"github.com/dark-enstein/scour/internal/env"
+	"github.com/dark-enstein/scour/internal/envelope"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+	"io"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// RedactedHeaders are the request and response headers whose values aren't recorded in the history,
+	// unless they are templated from variables.
+	RedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"}
+	// RedactedQueryParams are the url query parameters whose values aren't recorded in the history, unless
+	// they are templated from variables. Names are matched ignoring case.
+	RedactedQueryParams = []string{"access_token", "api_key", "apikey", "auth", "client_secret", "id_token", "key",
+		"password", "passwd", "refresh_token", "secret", "sig", "signature", "token", "x-amz-signature"}
+	// DefaultHistoryLimit is the number of records listed, unless set otherwise.
+	DefaultHistoryLimit = 20
+)
+
+// newHistoryCmd builds the history command, which inspects and sends again the transfers recorded in the history.
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List, search, show and send again the transfers recorded in the history",
+		Long: `List, search, show and send again the transfers recorded in the history.
+
+Every transfer is recorded in ~/.scour/history.ndjson: the request as sent, and the status, headers,
+timings and body of the response, or the messages of a socket session. Bodies are truncated to 4 KiB.
+The values of secret variables are recorded as {{name}} placeholders, templated again on rerun, and
+the values of credential headers, such as Authorization or Cookie, as ****, like the user info of urls
+and query parameters such as access_token, api_key or password.
+
+--history-max and --history-max-age limit how many transfers are kept, and for how long: 1000 and 30
+days by default. --no-history leaves the transfers of an invocation out. Like every flag, they can
+be set in the config file.`,
+	}
+	cmd.AddCommand(newHistoryListCmd(), newHistorySearchCmd(), newHistoryShowCmd(), newHistoryRerunCmd(), newHistoryClearCmd())
+	return cmd
+}
+
+// newHistoryListCmd builds the history list command.
+func newHistoryListCmd() *cobra.Command {
+	var limit int
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "list [flags]",
+		Short: "List the most recent transfers, newest first",
+		Example: `  scour history list
+  scour history list -n 100 --json | jq 'select(.status >= 500)'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := dal.History()
+			if err != nil {
+				return err
+			}
+			return listHistory(records, limit, asJSON)
+		},
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "n", DefaultHistoryLimit, "List at most this many transfers. 0 lists every one.")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print every record as JSON, one per line.")
+	return cmd
+}
+
+// historyQuery selects records of the history.
+type historyQuery struct {
+	text   string        // Text contained in the url, headers, data, body or error, ignoring case.
+	method string        // Request method.
+	status string        // Response status, as a code or a class such as 5xx.
+	since  time.Duration // Maximum age.
+}
+
+// match reports whether r is selected by the query.
+func (q historyQuery) match(r dal.Record) bool {
+	if len(q.method) > 0 && !strings.EqualFold(q.method, r.Method) {
+		return false
+	}
+	if len(q.status) > 0 {
+		code := strconv.Itoa(r.Status)
+		want := strings.ToLower(q.status)
+		if code != want && !(strings.HasSuffix(want, "xx") && r.Status > 0 && want[0] == code[0]) {
+			return false
+		}
+	}
+	if q.since > 0 && r.Time.Before(time.Now().Add(-q.since)) {
+		return false
+	}
+	if len(q.text) == 0 {
+		return true
+	}
+	fields := append([]string{r.Url, r.Data, r.Body.Text, r.Error}, r.Headers...)
+	fields = append(fields, r.ResponseHeaders...)
+	if r.Session != nil {
+		for _, m := range r.Session.Messages {
+			fields = append(fields, m.Text)
+		}
+	}
+	text := strings.ToLower(q.text)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// newHistorySearchCmd builds the history search command.
+func newHistorySearchCmd() *cobra.Command {
+	var q historyQuery
+	var limit int
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "search [flags] [<text>]",
+		Short: "List the transfers matching text and filters, newest first",
+		Long: `List the transfers matching text and filters, newest first.
+
+Text is matched, ignoring case, against the url, the request headers and data, the response headers
+and body, the messages of socket sessions, and the error of failed transfers.`,
+		Example: `  scour history search users
+  scour history search --method POST --status 5xx --since 24h`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				q.text = args[0]
+			}
+			if len(q.status) > 0 && !statusPattern(q.status) {
+				return fmt.Errorf("--status: %q isn't a status, such as 404, or a class, such as 5xx", q.status)
+			}
+			records, err := dal.History()
+			if err != nil {
+				return err
+			}
+			var matched []dal.Record
+			for _, r := range records {
+				if q.match(r) {
+					matched = append(matched, r)
+				}
+			}
+			return listHistory(matched, limit, asJSON)
+		},
+	}
+	cmd.Flags().StringVarP(&q.method, "method", "X", "", "Only list transfers sent with this method.")
+	cmd.Flags().StringVar(&q.status, "status", "", "Only list transfers whose response has this status, or class of status, such as 5xx.")
+	cmd.Flags().DurationVar(&q.since, "since", 0, "Only list transfers made in this last duration, e.g. 2h.")
+	cmd.Flags().IntVarP(&limit, "limit", "n", DefaultHistoryLimit, "List at most this many transfers. 0 lists every one.")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print every record as JSON, one per line.")
+	return cmd
+}
+
+// statusPattern reports whether s is a status code, or a class of statuses such as 5xx.
+func statusPattern(s string) bool {
+	s = strings.ToLower(s)
+	if len(s) != 3 || s[0] < '1' || s[0] > '5' {
+		return false
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil || s[1:] == "xx"
+}
+
+// newHistoryShowCmd builds the history show command.
+func newHistoryShowCmd() *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "show [flags] <id>",
+		Short: "Print the request and response of a transfer",
+		Example: `  scour history show 42
+  scour history show --json 42 | jq -r .body.text`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := historyRecord(args[0])
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetEscapeHTML(false)
+				enc.SetIndent("", "  ")
+				return enc.Encode(r)
+			}
+			return writeRecord(os.Stdout, r)
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the record as JSON.")
+	return cmd
+}
+
+// newHistoryRerunCmd builds the history rerun command.
+func newHistoryRerunCmd() *cobra.Command {
+	var headers []string
+	cmd := &cobra.Command{
+		Use:   "rerun [flags] <id>",
+		Short: "Send the request of a transfer again",
+		Long: `Send the request of a transfer again, with its recorded method, url, headers and data.
+
+Placeholders of secret variables are templated with the environment in use. Headers whose values were
+redacted are left out, unless passed in again with -H, which also overrides recorded headers.`,
+		Example: `  scour history rerun 42
+  scour history rerun -H 'Authorization: Bearer xyz' 42`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			r, err := historyRecord(args[0])
+			if err != nil {
+				return err
+			}
+			job, err := rerunJob(r, headers)
+			if err != nil {
+				return err
+			}
+			return runJobs([]transfer.Job{job})
+		},
+	}
+	cmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "Pass in a request header, in \"Name: value\" form, overriding the recorded one. Pass once per header.")
+	cmd.Flags().AddFlagSet(bodyFlags(FLGS))
+	cmd.Flags().AddFlagSet(responseFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	cmd.Flags().AddFlagSet(expectFlags(FLGS))
+	return cmd
+}
+
+// newHistoryClearCmd builds the history clear command.
+func newHistoryClearCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Drop every transfer recorded in the history",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dal.ClearHistory()
+		},
+	}
+}
+
+// historyRecord returns the record of the history whose ID is passed in.
+func historyRecord(arg string) (*dal.Record, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("%q isn't a history ID. List them with: scour history list", arg)
+	}
+	return dal.HistoryRecord(id)
+}
+
+// rerunJob turns the request of r back into a job, templated with the variables in use. Redacted headers
+// are left out, and headers override the recorded ones of the same name.
+func rerunJob(r *dal.Record, headers []string) (transfer.Job, error) {
+	job := transfer.Job{Url: r.Url, Method: r.Method, Data: []byte(r.Data)}
+	job.Headers = restoreHeaders(r.Headers, headers, "the history")
+	if strings.Contains(job.Url, env.Mask) {
+		log.Printf("Warning: credentials of the url were redacted in the history and are sent as %s\n", env.Mask)
+	}
+	return job, expandJob(&job, Vars)
+}
+
+// restoreHeaders returns the headers recorded in store, such as the history, without the redacted ones,
+// overridden by headers of the same name, and followed by them.
+func restoreHeaders(recorded, headers []string, store string) []string {
+	override := map[string]bool{}
+	for _, h := range headers {
+		name, _, _ := strings.Cut(h, ":")
+		override[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+	}
+	var restored []string
+	for _, h := range recorded {
+		name, value, _ := strings.Cut(h, ":")
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		switch {
+		case override[name]:
+		case strings.TrimSpace(value) == env.Mask:
+			log.Printf("Warning: header %s was redacted in %s and is left out. Pass it in again with -H\n", name, store)
+		default:
+			restored = append(restored, h)
+		}
+	}
+	return append(restored, headers...)
+}
+
+// listHistory prints the last limit records, newest first, as a table or as JSON.
+func listHistory(records []dal.Record, limit int, asJSON bool) error {
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		for i := len(records) - 1; i >= 0; i-- {
+			if err := enc.Encode(records[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		status := "-"
+		switch {
+		case len(r.Error) > 0:
+			status = "error"
+		case r.Status > 0:
+			status = strconv.Itoa(r.Status)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Time.Local().Format(time.DateTime), r.Method, status, milliseconds(r.Timings.Total), r.Url)
+	}
+	return w.Flush()
+}
+
+// writeRecord prints the request and response of r, curl -v style.
+func writeRecord(w io.Writer, r *dal.Record) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#%d %s\n", r.ID, r.Time.Local().Format(time.DateTime))
+	fmt.Fprintf(&sb, "> %s %s\n", r.Method, r.Url)
+	for _, h := range r.Headers {
+		fmt.Fprintf(&sb, "> %s\n", h)
+	}
+	if len(r.Data) > 0 {
+		fmt.Fprintf(&sb, ">\n> %s\n", r.Data)
+	}
+	if len(r.StatusLine) > 0 {
+		fmt.Fprintf(&sb, "< %s\n", r.StatusLine)
+	}
+	for _, h := range r.ResponseHeaders {
+		fmt.Fprintf(&sb, "< %s\n", h)
+	}
+	if t := r.Timings; t.FirstByte > 0 {
+		fmt.Fprintf(&sb, "timings: dns %s, connect %s, tls %s, first byte %s, total %s\n", milliseconds(t.DNS), milliseconds(t.Connect), milliseconds(t.TLS), milliseconds(t.FirstByte), milliseconds(t.Total))
+	} else {
+		fmt.Fprintf(&sb, "timings: total %s\n", milliseconds(t.Total))
+	}
+	if len(r.Error) > 0 {
+		fmt.Fprintf(&sb, "error: %s (exit code %d)\n", r.Error, r.ExitCode)
+	}
+	if r.Session != nil {
+		fmt.Fprintf(&sb, "\nsession %s:\n", r.Session.ID)
+		for i, m := range r.Session.Messages {
+			dir := ">"
+			if i%2 == 1 {
+				dir = "<"
+			}
+			fmt.Fprintf(&sb, "%s %s\n", dir, describeBody(m))
+		}
+	} else if r.Body.Size > 0 {
+		fmt.Fprintf(&sb, "\n%s\n", describeBody(r.Body))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// describeBody renders a recorded body, noting when it was truncated or left out.
+func describeBody(b dal.Body) string {
+	switch {
+	case b.Binary:
+		return fmt.Sprintf("[%d bytes of binary data not recorded]", b.Size)
+	case b.Truncated:
+		return fmt.Sprintf("%s\n[truncated, %d of %d bytes recorded]", b.Text, len(b.Text), b.Size)
+	}
+	return b.Text
+}
+
+// milliseconds formats a duration in milliseconds.
+func milliseconds(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', -1, 64) + "ms"
+}
+
+// recordHistory records results in the history, unless --no-history is set, redacting secrets. Failing
+// to record is only reported in verbose mode.
+func recordHistory(results []transfer.Result) {
+	if FLGS.NoHistory || len(results) == 0 {
+		return
+	}
+	var placeholders *strings.Replacer
+	if Env != nil {
+		placeholders = Env.Placeholders(Vars)
+	}
+	records := make([]dal.Record, len(results))
+	for i := range results {
+		records[i] = newRecord(&results[i], placeholders)
+	}
+	if _, err := dal.RecordHistory(dal.Retention{Max: FLGS.HistoryMax, MaxAge: FLGS.HistoryMaxAge}, records...); err != nil && FLGS.Verbose {
+		log.Println("Warning: failed recording the history:", err)
+	}
+}
+
+// newRecord builds the history record of res. Secret values in the request are turned into placeholders,
+// and masked everywhere else; the values of RedactedHeaders are masked unless templated.
+func newRecord(res *transfer.Result, placeholders *strings.Replacer) dal.Record {
+	job := redactRequest(withPlaceholders(res.Job, placeholders))
+	r := dal.Record{
+		Time:    time.Now().Add(-res.Duration),
+		Method:  job.Method,
+		Url:     job.Url,
+		Headers: job.Headers,
+		Data:    mask(string(job.Data)),
+		Body:    recordBody(res.Body),
+		Timings: dal.Timings(envelope.NewTimings(*res)),
+	}
+	if h := res.Headers; h != nil {
+		r.Status, r.StatusLine = h.StatusCode, h.Proto+" "+h.RespCode
+		names := make([]string, 0, len(h.Header))
+		for name := range h.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, v := range h.Header[name] {
+				r.ResponseHeaders = append(r.ResponseHeaders, redactHeader(name+": "+v))
+			}
+		}
+	}
+	if res.Err != nil {
+		r.Error, r.ExitCode = mask(res.Err.Error()), exitcode.Classify(res.Err)
+	}
+	if res.Session != nil {
+		r.Session = &dal.Session{ID: res.SessionID}
+		for _, m := range res.Session {
+			r.Session.Messages = append(r.Session.Messages, recordBody(m))
+		}
+		r.Body = dal.Body{}
+	}
+	return r
+}
+
+// recordBody truncates a body for the history, masking secrets first so none are cut in half.
+func recordBody(b []byte) dal.Body {
+	if !utf8.Valid(b) {
+		return dal.NewBody(b)
+	}
+	body := dal.NewBody([]byte(mask(string(b))))
+	body.Size = len(b)
+	return body
+}
+
+// redactRequest masks secrets in the url and headers of job, with redactUrl and redactHeader, for storing it.
+func redactRequest(job transfer.Job) transfer.Job {
+	job.Url = mask(redactUrl(job.Url))
+	headers := make([]string, len(job.Headers))
+	for i, h := range job.Headers {
+		headers[i] = redactHeader(h)
+	}
+	job.Headers = headers
+	return job
+}
+
+// redactHeader masks secrets in a header, in "Name: value" form, and its whole value when it is one of
+// RedactedHeaders and isn't templated.
+func redactHeader(h string) string {
+	name, value, _ := strings.Cut(h, ":")
+	for _, r := range RedactedHeaders {
+		if strings.EqualFold(strings.TrimSpace(name), r) && !strings.Contains(value, "{{") {
+			return name + ": " + env.Mask
+		}
+	}
+	return mask(h)
+}
+
+// redactUrl masks the user info of u, and the values of RedactedQueryParams, unless templated. A user name
+// alone, as in https://token@host, is masked as a whole, as it is often a token.
+func redactUrl(u string) string {
+	rest, fragment, hasFragment := strings.Cut(u, "#")
+	rest, query, hasQuery := strings.Cut(rest, "?")
+	if scheme, hierarchy, ok := strings.Cut(rest, "://"); ok {
+		authority, path := hierarchy, ""
+		if i := strings.IndexByte(hierarchy, '/'); i >= 0 {
+			authority, path = hierarchy[:i], hierarchy[i:]
+		}
+		if at := strings.LastIndexByte(authority, '@'); at >= 0 && !strings.Contains(authority[:at], "{{") {
+			user, _, hasPassword := strings.Cut(authority[:at], ":")
+			userinfo := env.Mask
+			if hasPassword {
+				userinfo = user + ":" + env.Mask
+			}
+			authority = userinfo + authority[at:]
+		}
+		rest = scheme + "://" + authority + path
+	}
+	if hasQuery {
+		params := strings.Split(query, "&")
+		for i, param := range params {
+			name, value, _ := strings.Cut(param, "=")
+			if unescaped, err := neturl.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			if len(value) > 0 && !strings.Contains(value, "{{") && slices.ContainsFunc(RedactedQueryParams, func(p string) bool {
+				return strings.EqualFold(p, name)
+			}) {
+				params[i] = param[:strings.IndexByte(param, '=')+1] + env.Mask
+			}
+		}
+		rest += "?" + strings.Join(params, "&")
+	}
+	if hasFragment {
+		rest += "#" + fragment
+	}
+	return rest
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// newHttpCmd builds the http command, which transfers data to and from HTTP servers. It takes the same
+// flags as the default command, minus the socket ones.
+func newHttpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "http [flags] <url> [<url>...]",
+		Short: "Transfer data to and from HTTP servers",
+		Example: `  scour http -X POST -d '{"name": "scour"}' -H 'Content-Type: application/json' https://example.com/users
+  scour http -i -o out.html https://example.com`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeHosts,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTransfers(args)
+		},
+	}
+	cmd.Flags().AddFlagSet(requestFlags(FLGS))
+	cmd.Flags().AddFlagSet(bodyFlags(FLGS))
+	cmd.Flags().AddFlagSet(responseFlags(FLGS))
+	cmd.Flags().AddFlagSet(transferFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	cmd.Flags().AddFlagSet(expectFlags(FLGS))
+	return cmd
+}
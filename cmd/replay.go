@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dark-enstein/scour/internal/envelope"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+)
+
+// newReplayCmd builds the replay command, which sends the requests recorded in --output-format json or
+// ndjson output again.
+func newReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay [flags] <file|->",
+		Short: "Send the requests recorded by --output-format again",
+		Long: `Send the requests recorded by --output-format json or ndjson again.
+
+Every record in the file, or on stdin with "-", is sent with its recorded method, headers and body.
+Output files passed with -o are paired with the records in order.`,
+		Example: `  scour --output-format ndjson https://example.com/a https://example.com/b > run.ndjson
+  scour replay run.ndjson`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			jobs, err := replayJobs(args[0], FLGS.Outputs)
+			if err != nil {
+				return err
+			}
+			return runJobs(jobs)
+		},
+	}
+	cmd.Flags().AddFlagSet(bodyFlags(FLGS))
+	cmd.Flags().AddFlagSet(responseFlags(FLGS))
+	cmd.Flags().AddFlagSet(transferFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	return cmd
+}
+
+// replayJobs reads the envelopes recorded in file, and turns their requests into transfer jobs paired in
+// order with outputs.
+func replayJobs(file string, outputs []string) ([]transfer.Job, error) {
+	var r io.Reader = os.Stdin
+	if file != transfer.Stdout {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	envs, err := envelope.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+	if len(envs) == 0 {
+		return nil, fmt.Errorf("no requests recorded in %s", file)
+	}
+	if len(outputs) > len(envs) {
+		log.Printf("Warning: %d output files passed in for %d requests. Extra output files are ignored\n", len(outputs), len(envs))
+	}
+	jobs := make([]transfer.Job, len(envs))
+	for i, env := range envs {
+		jobs[i] = env.Request.Job()
+		if i < len(outputs) {
+			jobs[i].Output = outputs[i]
+		}
+	}
+	return jobs, nil
+}
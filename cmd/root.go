@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/dal"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"log"
+	"net/http"
+	"os"
+)
+
+const (
+	SOCKET_TEST = iota + 1
+	HTTP_TEST
+)
+
+var (
+	// FLGS holds the flags values for every iteration
+	FLGS = config.NewFlags()
+	// ScourASCII holds the header output of Scour. TODO: This should be refactored to using go:embed via text files
+	ScourASCII = `
+ _______  _______  _______  __   __  ______
+|       ||       ||       ||  | |  ||    _ |
+|  _____||       ||   _   ||  | |  ||   | ||
+| |_____ |       ||  | |  ||  |_|  ||   |_||_
+|_____  ||      _||  |_|  ||       ||    __  |
+ _____| ||     |_ |       ||       ||   |  | |
+|_______||_______||_______||_______||___|  |_|
+
+Debug = %v
+`
+	// flagAliases maps the names flags used to be registered under onto their current names.
+	flagAliases = map[string]string{
+		"X":      "request",
+		"Header": "header",
+	}
+)
+
+// exitError reports that a command ran, but failed with the given exit code. Its cause has already been
+// reported to the user.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
+// Execute runs scour with the command line arguments and returns the exit code of the invocation.
+func Execute() int {
+	initColor()
+	cmd, err := NewRootCmd().ExecuteC()
+	return exitCode(cmd, err)
+}
+
+// exitCode maps the error returned from running cmd onto an exit code. Errors other than exitError are
+// usage errors: they are reported here, alongside a pointer to the help of the command.
+func exitCode(cmd *cobra.Command, err error) int {
+	if err == nil {
+		return exitcode.OK
+	}
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	log.Println(err)
+	fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	return exitcode.Usage
+}
+
+// NewRootCmd builds the scour command tree. The root command carries out transfers in the curl-compatible
+// form, scour [flags] <url> [<url>...], while subcommands group the HTTP, socket and server features.
+func NewRootCmd() *cobra.Command {
+	*FLGS = config.Flags{Method: http.MethodGet, ParallelMax: transfer.DefaultParallelMax, HistoryMax: dal.DefaultHistoryMax, HistoryMaxAge: dal.DefaultHistoryMaxAge}
+	root := &cobra.Command{
+		Use:   "scour [flags] <url> [<url>...]",
+		Short: "Scour transfers data to and from HTTP servers and Unix domain sockets",
+		Long: `Scour transfers data to and from HTTP servers and Unix domain sockets.
+
+Without a subcommand, scour takes curl-compatible flags and fetches every url passed in.`,
+		Example:           config.Examples,
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completeUrls,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadConfig(cmd); err != nil {
+				return err
+			}
+			return loadEnv()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(FLGS.SocketLoc) > 0 {
+				return serve(FLGS.SocketLoc)
+			}
+			return runTransfers(args)
+		},
+	}
+	root.SetGlobalNormalizationFunc(normalizeFlag)
+	root.PersistentFlags().BoolVarP(&FLGS.Verbose, "verbose", "v", false, "Turn on/off verbose mode. Diagnostics are written to stderr.")
+	root.PersistentFlags().BoolVar(&FLGS.Banner, "banner", false, "Print the Scour banner to stderr.")
+	root.PersistentFlags().StringVarP(&FLGS.ConfigFile, "config", "K", "", "Read default options from <file> instead of ~/.scourrc.")
+	root.PersistentFlags().BoolVarP(&FLGS.NoConfig, "disable", "q", false, "Ignore the config file and SCOUR_* environment variables.")
+	root.PersistentFlags().StringVar(&FLGS.Env, "env", "", "Template requests with the variables of this environment, instead of the one set with \"scour env use\".")
+	root.PersistentFlags().StringArrayVar(&FLGS.Vars, "var", nil, "Set a variable requests are templated with, in name=value form, overriding the environment. Pass once per variable.")
+
+	root.PersistentFlags().BoolVar(&FLGS.NoHistory, "no-history", false, "Don't record the transfers of the invocation in the history.")
+	root.PersistentFlags().IntVar(&FLGS.HistoryMax, "history-max", dal.DefaultHistoryMax, "Number of transfers kept in the history, the oldest are dropped first. 0 keeps every one.")
+	root.PersistentFlags().DurationVar(&FLGS.HistoryMaxAge, "history-max-age", dal.DefaultHistoryMaxAge, "How long transfers are kept in the history, e.g. 168h. 0 keeps them forever.")
+
+	root.Flags().AddFlagSet(requestFlags(FLGS))
+	root.Flags().AddFlagSet(bodyFlags(FLGS))
+	root.Flags().AddFlagSet(responseFlags(FLGS))
+	root.Flags().AddFlagSet(transferFlags(FLGS))
+	root.Flags().AddFlagSet(traceFlags(FLGS))
+	root.Flags().AddFlagSet(expectFlags(FLGS))
+	root.Flags().BoolVarP(&FLGS.UnixSocket, "unix-socket", "u", false, "Connect through the Unix domain socket passed in as the first argument, instead of using the network.")
+	root.Flags().BoolVar(&FLGS.InteractiveMode, "it", false, "Toggles console mode for socket connection. (not stable)") // not stable
+	root.Flags().StringVarP(&FLGS.SocketLoc, "create-socket", "c", "", "Creates a socket at the specified path. (not stable)")
+	_ = root.Flags().MarkDeprecated("create-socket", "use \"scour serve <path>\" instead")
+
+	root.AddCommand(newHttpCmd(), newSocketCmd(), newServeCmd(), newReplayCmd(), newRunCmd(), newBatchCmd(), newScenarioCmd(), newCollectionCmd(), newSnapshotCmd(), newDiffCmd(), newHistoryCmd(), newFromCurlCmd(), newEnvCmd(), newConfigCmd(), newCompletionCmd())
+	for _, cmd := range append(root.Commands(), root) {
+		registerCompletions(cmd)
+	}
+	return root
+}
+
+// normalizeFlag resolves the names flags used to be registered under, so older command lines keep working.
+func normalizeFlag(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	if alias, ok := flagAliases[name]; ok {
+		name = alias
+	}
+	return pflag.NormalizedName(name)
+}
+
+// requestFlags holds the flags shaping HTTP requests.
+func requestFlags(f *config.Flags) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("request", pflag.ContinueOnError)
+	fs.StringVarP(&f.Method, "request", "X", http.MethodGet, "Set request method.")
+	fs.StringVarP(&f.Data, "data", "d", "", "Pass request data. Use @file to read it from a file, or @- from stdin.")
+	fs.StringArrayVarP(&f.Headers, "header", "H", nil, "Pass in custom request headers, in \"Name: value\" form. Pass once per header.")
+	fs.BoolVarP(&f.Head, "head", "I", false, "Send a HEAD request and print only the response status line and headers.")
+	fs.StringVar(&f.Export, "export", "", "Print every request as a runnable snippet instead of sending it: curl, go, python-requests, js-fetch or httpie.")
+	return fs
+}
+
+// bodyFlags holds the flags controlling where and how response bodies are written.
+func bodyFlags(f *config.Flags) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("body", pflag.ContinueOnError)
+	fs.StringArrayVarP(&f.Outputs, "output", "o", nil, "Write the response body to <file> instead of stdout, or to stdout with \"-\". Pass once per url; they are paired in order.")
+	fs.StringVar(&f.OutputFormat, "output-format", "", "Print a machine-readable record per transfer to stdout instead of the body: json or ndjson.")
+	fs.BoolVar(&f.RawOutput, "raw-output", false, "Print response bodies as is. By default bodies written to a terminal are pretty-printed.")
+	fs.BoolVar(&f.SortKeys, "sort-keys", false, "Sort the keys of JSON objects in pretty-printed response bodies.")
+	fs.StringVar(&f.Jq, "jq", "", "Filter JSON response bodies through a jq expression. With --raw-output, strings are printed without quotes.")
+	fs.StringVar(&f.JSONPath, "json-path", "", "Filter JSON response bodies through a JSONPath expression.")
+	fs.StringVar(&f.Charset, "charset", "", "Decode response bodies printed to stdout from this charset, instead of the one detected from Content-Type, BOM or <meta>.")
+	return fs
+}
+
+// responseFlags holds the flags acting on HTTP response status lines and headers.
+func responseFlags(f *config.Flags) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("response", pflag.ContinueOnError)
+	fs.BoolVarP(&f.Include, "include", "i", false, "Print the response status line and headers before the body.")
+	fs.StringVarP(&f.DumpHeader, "dump-header", "D", "", "Write the response status line and headers to <file>, or to stdout with \"-\".")
+	fs.BoolVarP(&f.Fail, "fail", "f", false, "Fail on HTTP responses with status >= 400 with exit code 22, without printing the body.")
+	fs.BoolVar(&f.FailWithBody, "fail-with-body", false, "Fail on HTTP responses with status >= 400 with exit code 22, still printing the body.")
+	return fs
+}
+
+// transferFlags holds the flags controlling how multiple urls are transferred.
+func transferFlags(f *config.Flags) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("transfer", pflag.ContinueOnError)
+	fs.BoolVarP(&f.Parallel, "parallel", "Z", false, "Carry out the transfers for all urls in parallel.")
+	fs.IntVar(&f.ParallelMax, "parallel-max", transfer.DefaultParallelMax, "Maximum number of transfers running at once in parallel mode.")
+	fs.BoolVarP(&f.GlobOff, "globoff", "g", false, "Turn off url globbing, so that {}[] in urls are sent as is.")
+	return fs
+}
+
+// traceFlags holds the flags turning on wire-level tracing.
+func traceFlags(f *config.Flags) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("trace", pflag.ContinueOnError)
+	fs.StringVar(&f.Trace, "trace", "", "Write a hex and ASCII dump of every byte sent and received to <file>. Use \"-\" for stdout and \"%\" for stderr.")
+	fs.StringVar(&f.TraceASCII, "trace-ascii", "", "Write an ASCII dump of every byte sent and received to <file>. Use \"-\" for stdout and \"%\" for stderr.")
+	fs.BoolVar(&f.TraceTime, "trace-time", false, "Prefix every --trace or --trace-ascii line with the time of day.")
+	return fs
+}
+
+// expectFlags holds the flags checking responses, for smoke tests.
+func expectFlags(f *config.Flags) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("expect", pflag.ContinueOnError)
+	fs.StringVar(&f.ExpectStatus, "expect-status", "", "Fail unless the response status is one of these, comma-separated, e.g. 200,204 or 2xx.")
+	fs.StringArrayVar(&f.ExpectHeaders, "expect-header", nil, "Fail unless the response has this header, in \"Name\" or \"Name: value\" form, where the value is matched as a substring. Pass once per header.")
+	fs.StringArrayVar(&f.ExpectBody, "expect-body-contains", nil, "Fail unless the response body contains this string. Pass once per string.")
+	fs.StringArrayVar(&f.ExpectJSON, "expect-json", nil, "Fail unless this jq expression is true for the JSON response body, e.g. '.status == \"ok\"'. Pass once per expression.")
+	fs.DurationVar(&f.ExpectTimeUnder, "expect-time-under", 0, "Fail unless the transfer completes in less than this duration, e.g. 500ms.")
+	fs.StringVar(&f.JUnit, "junit", "", "Write a JUnit XML report of every transfer and its checks to <file>.")
+	return fs
+}
+
+// printBanner prints the Scour banner to stderr when asked for.
+func printBanner() {
+	if FLGS.Banner {
+		fmt.Fprintf(os.Stderr, ScourASCII, FLGS.Verbose)
+	}
+}
+
+// Debug runs a transfer with the preset flag values of debugType, bypassing the command line. It is
+// meant for running scour from an IDE debugger, and returns the exit code of the invocation.
+func Debug(debugType int, args []string) int {
+	initColor()
+	root := NewRootCmd()
+	FLGS = debug(debugType, FLGS)
+	FLGS.Banner = true
+	return exitCode(root, runTransfers(args))
+}
+
+// debug sets some default flag values for Goland debugging
+func debug(debugType int, flag *config.Flags) *config.Flags {
+	switch debugType {
+	case SOCKET_TEST:
+		flag = &config.Flags{
+			Verbose:         true,
+			Method:          http.MethodGet,
+			Data:            "",
+			Headers:         nil,
+			UnixSocket:      true,
+			InteractiveMode: false,
+			ParallelMax:     transfer.DefaultParallelMax,
+		}
+	case HTTP_TEST:
+		flag = &config.Flags{
+			Verbose:         true,
+			Method:          "GET",
+			Data:            "",
+			Headers:         []string{"accept: application/json"},
+			UnixSocket:      false,
+			InteractiveMode: false,
+			ParallelMax:     transfer.DefaultParallelMax,
+		}
+	}
+	return flag
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dark-enstein/scour/internal/httpfile"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// newRunCmd builds the run command, which sends the requests of an .http file.
+func newRunCmd() *cobra.Command {
+	var list bool
+	cmd := &cobra.Command{
+		Use:   "run [flags] <file.http> [<name|index>...]",
+		Short: "Send the requests of an .http file, as used by the VS Code REST Client and JetBrains HTTP client",
+		Long: `Send the requests of an .http file, as used by the VS Code REST Client and JetBrains HTTP client.
+
+Requests are separated by lines starting with ###, whose text names the request, as does a
+"# @name" comment. Lines of the form "@name = value" define variables, substituted wherever
+{{name}} appears. A body made of a single "< ./file" line is read from the file, and "<@ ./file"
+substitutes variables in it as well. Requests are picked by name or by index, starting at 1; by
+default every request is sent, in order. Output files passed with -o are paired with them in order.`,
+		Example: `  scour run api.http
+  scour run api.http createUser 3
+  scour run --list api.http`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeHTTPFile,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			f, err := httpfile.ParseFile(args[0])
+			if err != nil {
+				return err
+			}
+			if list {
+				listRequests(f)
+				return nil
+			}
+			reqs, err := f.Select(args[1:])
+			if err != nil {
+				return err
+			}
+			jobs, err := httpFileJobs(f, reqs, FLGS.Outputs)
+			if err != nil {
+				return err
+			}
+			return runJobs(jobs)
+		},
+	}
+	cmd.Flags().BoolVarP(&list, "list", "l", false, "List the requests of the file instead of sending them.")
+	cmd.Flags().AddFlagSet(bodyFlags(FLGS))
+	cmd.Flags().AddFlagSet(responseFlags(FLGS))
+	cmd.Flags().AddFlagSet(transferFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	cmd.Flags().AddFlagSet(expectFlags(FLGS))
+	return cmd
+}
+
+// httpFileJobs resolves the requests of f into transfer jobs, paired in order with outputs.
+func httpFileJobs(f *httpfile.File, reqs []httpfile.Request, outputs []string) ([]transfer.Job, error) {
+	if len(outputs) > len(reqs) {
+		log.Printf("Warning: %d output files passed in for %d requests. Extra output files are ignored\n", len(outputs), len(reqs))
+	}
+	jobs := make([]transfer.Job, len(reqs))
+	for i, r := range reqs {
+		job, err := f.Resolve(r, Vars)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(outputs) {
+			job.Output = outputs[i]
+		}
+		applyHostConfig(&job)
+		jobs[i] = job
+	}
+	return jobs, nil
+}
+
+// listRequests prints the index, name, method and url of every request of f.
+func listRequests(f *httpfile.File) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, r := range f.Requests {
+		name := r.Name
+		if len(name) == 0 {
+			name = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", r.Index, name, r.Method, r.Url)
+	}
+	_ = w.Flush()
+}
+
+// completeHTTPFile completes .http files, then the names of the requests in the file.
+func completeHTTPFile(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return []string{"http", "rest"}, cobra.ShellCompDirectiveFilterFileExt
+	}
+	f, err := httpfile.ParseFile(args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var names []string
+	for _, r := range f.Requests {
+		if len(r.Name) > 0 {
+			names = append(names, r.Name)
+		} else {
+			names = append(names, strconv.Itoa(r.Index))
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dark-enstein/scour/internal/envelope"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/scenario"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"time"
+)
+
+// newScenarioCmd builds the scenario command, which runs a sequence of requests feeding values into each other.
+func newScenarioCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scenario [flags] <scenario.yaml>",
+		Short: "Run a sequence of requests, capturing values from responses into variables for later steps",
+		Long: `Run a sequence of requests, capturing values from responses into variables for later steps.
+
+A scenario file, in YAML or JSON, lists steps that take the fields of batch file requests (see
+"scour batch --help"), and an extract object mapping variable names onto where to find their value
+in the response: {json: <JSONPath>}, {header: <name>}, {regex: <expression>}, whose first group is
+the value if it has one, or {cookie: <name>}. A plain string is taken as a JSONPath. Extracted values
+marked secret: true are masked in output.
+
+  name: login
+  vars:
+    user: ann
+  steps:
+    - name: login
+      method: POST
+      url: "{{base_url}}/login"
+      body: {"user": "{{user}}", "password": "{{password}}"}
+      extract:
+        token: $.token
+        session: {cookie: SESSION, secret: true}
+    - name: me
+      url: "{{base_url}}/me"
+      headers: {Authorization: "Bearer {{token}}"}
+      expect_status: 200
+
+Steps are templated with the vars of the scenario, overridden by the environment, by --var, then by
+the values extracted so far. They run in order, and the first one that fails stops the scenario: a
+failed request, a status other than expect_status, or >= 400 without one, or a value that can't be
+extracted. The outcome of every step is printed to stderr, and response bodies to stdout, or to the
+files passed with -o, paired with the steps in order.`,
+		Example: `  scour scenario login.yaml
+  scour scenario --env staging --var password=hunter2 -o /dev/null -o - login.yaml`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return []string{"yaml", "yml", "json"}, cobra.ShellCompDirectiveFilterFileExt
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			s, err := scenario.ParseFile(args[0])
+			if err != nil {
+				return err
+			}
+			return runScenario(s)
+		},
+	}
+	cmd.Flags().AddFlagSet(bodyFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	return cmd
+}
+
+// runScenario runs the steps of s in order, printing the outcome of each, until one fails. It returns an
+// exitError for the failed step.
+func runScenario(s *scenario.Scenario) error {
+	printBanner()
+	filter, err := compileFilter(FLGS)
+	if err != nil {
+		return err
+	}
+	Filter = filter
+	ctx, err := newInstanceCtx()
+	if err != nil {
+		return err
+	}
+	if len(FLGS.Outputs) > len(s.Steps) {
+		log.Printf("Warning: %d output files passed in for %d steps. Extra output files are ignored\n", len(FLGS.Outputs), len(s.Steps))
+	}
+
+	v := s.Vars.Merge(Vars)
+	var results []transfer.Result
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		job := step.Job()
+		if i < len(FLGS.Outputs) {
+			job.Output = FLGS.Outputs[i]
+		}
+		res := transfer.Result{Job: job}
+		if res.Err = expandJob(&job, v); res.Err == nil {
+			applyHostConfig(&job)
+			res = transfer.Run(ctx, []transfer.Job{job}, false, 1, invokeJob)[0]
+			step.Check(&res)
+		}
+		var values []scenario.Value
+		if res.Err == nil {
+			values, res.Err = step.Values(&res)
+		}
+		for _, val := range values {
+			v[val.Name] = val.Value
+			if val.Secret && Env != nil {
+				Env.Secrets[val.Name] = true
+				masker = Env.Masker(v)
+			}
+		}
+
+		fmt.Fprint(os.Stderr, mask(fmt.Sprintf("[%d/%d] %s: %s %s -> %s (%s)\n", i+1, len(s.Steps), step.Label(i), job.Method, job.Url, res.Status(), res.Duration.Round(time.Millisecond))))
+		for _, val := range values {
+			fmt.Fprint(os.Stderr, mask(fmt.Sprintf("      %s = %s\n", val.Name, val.Value)))
+		}
+		writeResult(&res)
+		results = append(results, res)
+		if res.Err != nil {
+			break
+		}
+	}
+	recordHistory(results)
+
+	if len(FLGS.OutputFormat) > 0 {
+		envs := make([]*envelope.Envelope, len(results))
+		for i, res := range results {
+			envs[i] = envelope.New(envelope.NewRequest(res.Job), res)
+		}
+		if err := envelope.Write(maskWriter{os.Stdout}, FLGS.OutputFormat, envs); err != nil {
+			log.Printf("Error writing %s output: %s\n", FLGS.OutputFormat, err.Error())
+			return &exitError{exitcode.WriteError}
+		}
+	}
+	last := results[len(results)-1]
+	if last.Err == nil {
+		return nil
+	}
+	name := s.Name
+	if len(name) == 0 {
+		name = s.Path
+	}
+	log.Printf("scenario %s stopped at %s: %d of %d steps passed\n", name, s.Steps[len(results)-1].Label(len(results)-1), len(results)-1, len(s.Steps))
+	return &exitError{exitcode.Classify(last.Err)}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/invoke/socket"
+	"github.com/spf13/cobra"
+	"log"
+)
+
+// newServeCmd builds the serve command, which creates a Unix domain socket and serves requests on it.
+func newServeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "serve <socket-path>",
+		Short:   "Create a Unix domain socket and serve requests on it (not stable)",
+		Example: `  scour serve /tmp/scour.sock`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(args[0])
+		},
+	}
+}
+
+// serve creates the socket at path and serves on it until it is shut down.
+func serve(path string) error {
+	printBanner()
+	if err := socket.CreateSocketSubProc(path); err != nil {
+		log.Println(err.Error())
+		return &exitError{exitcode.Failure}
+	}
+	return nil
+}
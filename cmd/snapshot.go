@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dark-enstein/scour/internal/envelope"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/jsondiff"
+	"github.com/dark-enstein/scour/internal/snapshot"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// newSnapshotCmd builds the snapshot command, which records responses to files and verifies later
+// responses against them.
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Record responses to snapshot files, and verify later responses against them",
+		Long: `Record responses to snapshot files, and verify later responses against them.
+
+A snapshot file stores every request along with the status, the selected headers and the body of
+its response. Parts of responses that change from one request to the next, such as timestamps,
+UUIDs or request IDs, are ignored by JSONPath in JSON bodies, or by regular expression in text
+bodies, JSON strings and header values. They are stored as "<ignored>".`,
+	}
+	cmd.AddCommand(newSnapshotRecordCmd(), newSnapshotVerifyCmd())
+	return cmd
+}
+
+// ignoreFlags holds the flags adding ignore rules to snapshots.
+func ignoreFlags(ignore *snapshot.Ignore) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("ignore", pflag.ContinueOnError)
+	fs.StringArrayVar(&ignore.Paths, "ignore-path", nil, "Ignore the values of JSON bodies matched by this JSONPath, e.g. $.meta.request_id or $..updated_at. Pass once per path.")
+	fs.StringArrayVar(&ignore.Regexes, "ignore-regex", nil, "Ignore matches of this regular expression in text bodies, JSON strings and header values. Pass once per expression.")
+	return fs
+}
+
+// newSnapshotRecordCmd builds the snapshot record command.
+func newSnapshotRecordCmd() *cobra.Command {
+	var ignore snapshot.Ignore
+	var headers []string
+	cmd := &cobra.Command{
+		Use:   "record [flags] <snapshot.json> <url> [<url>...]",
+		Short: "Send requests and store their normalized responses in a snapshot file",
+		Long: `Send requests and store their normalized responses in a snapshot file, replacing it.
+
+The requests are sent with the method, headers and data passed in, and stored with them. Values of
+secret variables are stored as {{name}} placeholders, in requests and responses alike: requests are
+templated again when the snapshot is verified, and responses compared with the same placeholders.
+Credentials that aren't templated, such as an Authorization header or an access_token query
+parameter, are stored as ****, as in the history, so that snapshots can be committed. Pass redacted
+headers again with -H when verifying, or template them from secret variables.
+Every request must get a response for the snapshot to be written.`,
+		Example: `  scour snapshot record users.json https://api.example.com/users/1 https://api.example.com/users/2
+  scour snapshot record --ignore-path '$..updated_at' --ignore-regex '[0-9a-f-]{36}' users.json https://api.example.com/users`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			if len(FLGS.Export) > 0 {
+				return fmt.Errorf("--export can't be used to record a snapshot")
+			}
+			return recordSnapshot(args[0], args[1:], ignore, headers)
+		},
+	}
+	cmd.Flags().AddFlagSet(requestFlags(FLGS))
+	cmd.Flags().AddFlagSet(transferFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	cmd.Flags().AddFlagSet(ignoreFlags(&ignore))
+	cmd.Flags().StringArrayVar(&headers, "keep-header", snapshot.DefaultHeaders, "Store and compare this response header, on top of status and body. Pass once per header.")
+	return cmd
+}
+
+// newSnapshotVerifyCmd builds the snapshot verify command.
+func newSnapshotVerifyCmd() *cobra.Command {
+	var ignore snapshot.Ignore
+	var update bool
+	var headers []string
+	cmd := &cobra.Command{
+		Use:   "verify [flags] <snapshot.json> [<snapshot.json>...]",
+		Short: "Send the requests of snapshot files again and compare their responses",
+		Long: `Send the requests of snapshot files again and compare their responses with the stored ones.
+
+Responses are normalized with the ignore rules of the snapshot, and the ones passed in, then compared:
+status, stored headers, and the body, value by value for JSON and line by line for text. The
+differences are printed to stdout, one per line, as "~ path: stored -> received", "- path: stored"
+or "+ path: received". A pass/fail line per request and a tally are printed to stderr. When any
+response differs, scour exits with code 102, unless --update stores the new responses instead.
+Headers stored as **** are left out, unless passed in again with -H.`,
+		Example: `  scour snapshot verify users.json
+  scour snapshot verify -H 'Authorization: Bearer xyz' users.json
+  scour snapshot verify --ignore-path '$.meta' snapshots/*.json
+  scour snapshot verify --update users.json`,
+		Args: cobra.MinimumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := FLGS.ValidateAll(); err != nil {
+				return err
+			}
+			code := exitcode.OK
+			for _, name := range args {
+				c, err := verifySnapshot(name, ignore, update, headers)
+				if err != nil {
+					return err
+				}
+				if code == exitcode.OK {
+					code = c
+				}
+			}
+			if code != exitcode.OK {
+				return &exitError{code}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "Pass in a request header, in \"Name: value\" form, overriding the stored one. Pass once per header.")
+	cmd.Flags().BoolVarP(&update, "update", "u", false, "Store the responses that differ in the snapshot, along with the ignore rules passed in, instead of failing.")
+	cmd.Flags().AddFlagSet(transferFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	cmd.Flags().AddFlagSet(ignoreFlags(&ignore))
+	return cmd
+}
+
+// recordSnapshot sends the requests for urls and writes their normalized responses to the snapshot file
+// name. It returns an exitError when any request fails.
+func recordSnapshot(name string, urls []string, ignore snapshot.Ignore, headers []string) error {
+	printBanner()
+	rules, err := ignore.Compile()
+	if err != nil {
+		return err
+	}
+	if Env != nil {
+		rules.Secrets = Env.Placeholders(Vars)
+	}
+	jobs, err := buildJobs(urls, FLGS)
+	if err != nil {
+		return err
+	}
+	results, err := transferJobs(jobs, FLGS.Parallel, FLGS.ParallelMax)
+	if err != nil {
+		return err
+	}
+
+	f := &snapshot.File{Ignore: ignore, Headers: headers}
+	for i := range results {
+		res := &results[i]
+		if res.Err != nil {
+			log.Printf("Error recording %s %s: %s\n", res.Method, res.Url, res.Err.Error())
+			log.Printf("snapshot %s not written\n", name)
+			return &exitError{exitcode.Classify(res.Err)}
+		}
+		f.Entries = append(f.Entries, snapshot.Entry{
+			Request:  envelope.NewRequest(redactRequest(withPlaceholders(res.Job, rules.Secrets))),
+			Response: snapshot.NewResponse(res, headers, rules),
+		})
+		fmt.Fprint(os.Stderr, mask(fmt.Sprintf("recorded %s %s -> %s (%s)\n", res.Method, res.Url, res.Status(), res.Duration.Round(time.Millisecond))))
+	}
+	if err := f.Write(name); err != nil {
+		log.Printf("Error writing snapshot %s: %s\n", name, err.Error())
+		return &exitError{exitcode.WriteError}
+	}
+	log.Printf("snapshot %s: %d responses recorded\n", name, len(f.Entries))
+	return nil
+}
+
+// withPlaceholders turns the values of secret variables in the url, headers and data of job back into
+// placeholders, so that they aren't stored.
+func withPlaceholders(job transfer.Job, placeholders *strings.Replacer) transfer.Job {
+	if placeholders == nil {
+		return job
+	}
+	job.Url = placeholders.Replace(job.Url)
+	headers := make([]string, len(job.Headers))
+	for i, h := range job.Headers {
+		headers[i] = placeholders.Replace(h)
+	}
+	job.Headers = headers
+	job.Data = []byte(placeholders.Replace(string(job.Data)))
+	return job
+}
+
+// verifySnapshot sends the requests of the snapshot file name again, and compares their responses with the
+// stored ones. It returns the exit code of the first request failing or differing, and an error only when
+// the snapshot can't be verified at all.
+func verifySnapshot(name string, extra snapshot.Ignore, update bool, headers []string) (int, error) {
+	printBanner()
+	f, err := snapshot.Read(name)
+	if err != nil {
+		return exitcode.OK, err
+	}
+	f.Ignore.Add(extra)
+	rules, err := f.Ignore.Compile()
+	if err != nil {
+		return exitcode.OK, fmt.Errorf("snapshot %s: %w", name, err)
+	}
+	if Env != nil {
+		rules.Secrets = Env.Placeholders(Vars)
+	}
+
+	// requests failing to template are reported with the others, without being sent
+	jobs := make([]transfer.Job, 0, len(f.Entries))
+	templated := make([]error, len(f.Entries))
+	for i, e := range f.Entries {
+		job := e.Request.Job()
+		job.Headers = restoreHeaders(job.Headers, headers, "snapshot "+name)
+		if templated[i] = expandJob(&job, Vars); templated[i] == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	sent, err := transferJobs(jobs, FLGS.Parallel, FLGS.ParallelMax)
+	if err != nil {
+		return exitcode.OK, err
+	}
+
+	code := exitcode.OK
+	failed, updated := 0, 0
+	for i := range f.Entries {
+		e := &f.Entries[i]
+		res := transfer.Result{Job: e.Request.Job(), Err: templated[i]}
+		if res.Err == nil {
+			res, sent = sent[0], sent[1:]
+		}
+		var changes []jsondiff.Change
+		var got snapshot.Response
+		if res.Err == nil {
+			got = snapshot.NewResponse(&res, f.Headers, rules)
+			changes = snapshot.Compare(rules.Normalize(e.Response), got)
+		}
+
+		if len(changes) > 0 {
+			fmt.Print(mask(fmt.Sprintf("--- %s: %s %s\n%s", name, e.Request.Method, e.Request.Url, jsondiff.Format(changes))))
+		}
+		line := fmt.Sprintf("PASS %s %s (%s)\n", e.Request.Method, e.Request.Url, res.Duration.Round(time.Millisecond))
+		switch {
+		case len(changes) > 0 && update:
+			line = fmt.Sprintf("UPDATE %s %s (%s): %s\n", e.Request.Method, e.Request.Url, res.Duration.Round(time.Millisecond), differences(len(changes)))
+			e.Response = got
+			updated++
+		case res.Err != nil:
+			line = fmt.Sprintf("FAIL %s %s: %s\n", e.Request.Method, e.Request.Url, res.Err.Error())
+		case len(changes) > 0:
+			line = fmt.Sprintf("FAIL %s %s (%s): %s\n", e.Request.Method, e.Request.Url, res.Duration.Round(time.Millisecond), differences(len(changes)))
+			res.Err = fmt.Errorf("%w: %s", exitcode.ErrMismatch, differences(len(changes)))
+		}
+		fmt.Fprint(os.Stderr, mask(line))
+		if res.Err != nil {
+			failed++
+			if code == exitcode.OK {
+				code = exitcode.Classify(res.Err)
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d passed, %d failed\n", name, len(f.Entries)-failed, failed)
+
+	if update && (updated > 0 || len(extra.Paths)+len(extra.Regexes) > 0) {
+		if err := f.Write(name); err != nil {
+			log.Printf("Error writing snapshot %s: %s\n", name, err.Error())
+			return exitcode.WriteError, nil
+		}
+		log.Printf("snapshot %s: %d of %d responses updated\n", name, updated, len(f.Entries))
+	}
+	return code, nil
+}
+
+// differences counts the differences found between two responses.
+func differences(n int) string {
+	if n == 1 {
+		return "1 difference"
+	}
+	return fmt.Sprintf("%d differences", n)
+}
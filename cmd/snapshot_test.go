@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/snapshot"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRecordSnapshot_Redacted checks that credentials aren't written to snapshot files, and that verifying
+// takes them in again with -H.
+func TestRecordSnapshot_Redacted(t *testing.T) {
+	t.Setenv("SCOUR_HOME", t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer srv.Close()
+	flags := *FLGS
+	*FLGS = config.Flags{Method: http.MethodGet, ParallelMax: transfer.DefaultParallelMax,
+		Headers: []string{"Authorization: Bearer abc", "Accept: application/json"}}
+	t.Cleanup(func() { *FLGS = flags })
+
+	name := filepath.Join(t.TempDir(), "users.json")
+	assert.NoError(t, recordSnapshot(name, []string{srv.URL + "/users/1?access_token=abc"}, snapshot.Ignore{}, snapshot.DefaultHeaders))
+	b, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "abc")
+	f, err := snapshot.Read(name)
+	assert.NoError(t, err)
+	req := f.Entries[0].Request
+	assert.Equal(t, []string{"****"}, req.Headers.Values("Authorization"))
+	assert.Equal(t, []string{"application/json"}, req.Headers.Values("Accept"))
+	assert.Equal(t, srv.URL+"/users/1?access_token=****", req.Url)
+
+	// the server gets the stored url as is, and checks the header alone
+	code, err := verifySnapshot(name, snapshot.Ignore{}, false, []string{"Authorization: Bearer abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, exitcode.OK, code)
+	code, err = verifySnapshot(name, snapshot.Ignore{}, false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, exitcode.Mismatch, code)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// newSocketCmd builds the socket command, which sends requests through a Unix domain socket.
+func newSocketCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "socket [flags] <socket-path> [<resource>]",
+		Short: "Send requests through a Unix domain socket",
+		Long: `Send requests through a Unix domain socket.
+
+The resource is written to the socket, and the reply printed. With --it, scour opens a console on the
+socket where requests can be sent and received interactively.`,
+		Example: `  scour socket /var/run/docker.sock http:/images/json
+  scour socket --it /tmp/app.sock`,
+		Args: cobra.RangeArgs(1, 2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return completeSockets(cmd, args, toComplete)
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			FLGS.UnixSocket = true
+			return runTransfers(args)
+		},
+	}
+	cmd.Flags().AddFlagSet(bodyFlags(FLGS))
+	cmd.Flags().AddFlagSet(traceFlags(FLGS))
+	cmd.Flags().AddFlagSet(expectFlags(FLGS))
+	cmd.Flags().BoolVar(&FLGS.InteractiveMode, "it", false, "Toggles console mode for socket connection. (not stable)") // not stable
+	return cmd
+}
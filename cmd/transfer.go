@@ -0,0 +1,538 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/charset"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/envelope"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/expect"
+	"github.com/dark-enstein/scour/internal/export"
+	"github.com/dark-enstein/scour/internal/invoke/httpoke"
+	"github.com/dark-enstein/scour/internal/invoke/socket"
+	"github.com/dark-enstein/scour/internal/jq"
+	"github.com/dark-enstein/scour/internal/parser"
+	"github.com/dark-enstein/scour/internal/parser/httparser"
+	"github.com/dark-enstein/scour/internal/parser/socketparser"
+	"github.com/dark-enstein/scour/internal/pretty"
+	"github.com/dark-enstein/scour/internal/trace"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/dark-enstein/scour/internal/utils"
+	"github.com/fatih/color"
+	"golang.org/x/exp/slices"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	// Filter holds the compiled --jq or --json-path filter, if any
+	Filter *jq.Program
+	// Expect holds the compiled --expect-* checks, if any are set or --junit is
+	Expect *expect.Expectations
+	// ParsedUrlOutput holds the template for parsing url information in verbose mode. TODO: This should be refactored to using go:embed via text files
+	ParsedUrlOutput = `
+connecting to %s
+*   Trying %s...
+* Connected to %s (%s) port %s
+> %s /%s %s/1.1
+> Host: %s
+> Accept: */
+`
+	// InvokeOutput returns the metadata from the response. Activated in verbose mode. TODO: This should be refactored to using go:embed via text files.
+	InvokeOutput = `
+< %s/1.1 %s
+< Date: %s
+< Content-Type: %s
+< Content-Length: %s
+< Connection: %s
+< Server: %s
+< Access-Control-Allow-Origin: %s
+< Access-Control-Allow-Credentials: %v
+`
+)
+
+// runTransfers validates the flags, turns the url arguments into transfer jobs and carries them out.
+func runTransfers(args []string) error {
+	if err := FLGS.ValidateAll(); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		if FLGS.Method == config.MethodSocket {
+			return fmt.Errorf("please pass at least one argument in the format: scour socket [flags] <socket-path> <url>")
+		}
+		return fmt.Errorf("please pass at least one argument in the format: scour [flags] <url> [<url>...]")
+	}
+	jobs, err := buildJobs(args, FLGS)
+	if err != nil {
+		return err
+	}
+	if len(FLGS.Export) > 0 {
+		return exportJobs(jobs, FLGS.Export)
+	}
+	return runJobs(jobs)
+}
+
+// exportJobs prints the request of every job as a snippet for target, instead of sending it. The values of
+// secret variables are left out of the snippets, which read them from environment variables instead.
+func exportJobs(jobs []transfer.Job, target string) error {
+	var placeholders *strings.Replacer
+	var secretNames []string
+	if Env != nil {
+		placeholders = Env.Placeholders(Vars)
+		for name, secret := range Env.Secrets {
+			if secret {
+				secretNames = append(secretNames, name)
+			}
+		}
+		slices.Sort(secretNames)
+	}
+	read := map[string]bool{}
+	for i, job := range jobs {
+		if job.Method != http.MethodPost && job.Method != http.MethodPut && job.Method != http.MethodPatch {
+			// invokeJob only sends a payload with these methods
+			job.Data = nil
+		}
+		job = withPlaceholders(job, placeholders)
+		snippet, err := export.Render(target, job, secretNames...)
+		if err != nil {
+			return err
+		}
+		for _, name := range secretNames {
+			if strings.Contains(job.Url+"\n"+strings.Join(job.Headers, "\n")+"\n"+string(job.Data), "{{"+name+"}}") {
+				read[name] = true
+			}
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Print(mask(snippet))
+	}
+	for _, name := range secretNames {
+		if read[name] {
+			log.Printf("Warning: the value of the secret variable %s is left out of the export, set %s in the environment to run it\n", name, export.EnvName(name))
+		}
+	}
+	return nil
+}
+
+// runJobs carries out the transfer jobs and reports their results. It returns an exitError when any failed.
+func runJobs(jobs []transfer.Job) error {
+	printBanner()
+	filter, err := compileFilter(FLGS)
+	if err != nil {
+		return err
+	}
+	Filter = filter
+	if Expect, err = compileExpectations(FLGS); err != nil {
+		return err
+	}
+
+	results, err := transferJobs(jobs, FLGS.Parallel, FLGS.ParallelMax)
+	if err != nil {
+		return err
+	}
+	if code := report(results); code != exitcode.OK {
+		return &exitError{code}
+	}
+	return nil
+}
+
+// transferJobs carries out the jobs, at most max at once in parallel mode, and records the transfers in
+// the history. It fails only when the invocation can't start.
+func transferJobs(jobs []transfer.Job, parallel bool, max int) ([]transfer.Result, error) {
+	instanceCtx, err := newInstanceCtx()
+	if err != nil {
+		return nil, err
+	}
+	results := transfer.Run(instanceCtx, jobs, parallel, max, invokeJob)
+	recordHistory(results)
+	return results, nil
+}
+
+// newInstanceCtx creates the context transfers of the invocation are carried out in, opening the trace
+// file when asked for.
+func newInstanceCtx() (context.Context, error) {
+	instanceCtx := context.WithValue(context.Background(), httparser.KeyV, FLGS.Verbose)
+	instanceCtx = context.WithValue(instanceCtx, socket.KeyBinaryOK, slices.Contains(FLGS.Outputs, transfer.Stdout))
+
+	tracer, err := openTrace(FLGS)
+	if err != nil {
+		log.Println(err)
+		return nil, &exitError{exitcode.WriteError}
+	}
+	if tracer != nil {
+		instanceCtx = context.WithValue(instanceCtx, trace.KeyTracer, tracer)
+	}
+	return instanceCtx, nil
+}
+
+// openTrace creates the Tracer for --trace or --trace-ascii. It returns nil when neither is set.
+func openTrace(flag *config.Flags) (*trace.Tracer, error) {
+	dest, ascii := flag.Trace, false
+	if len(flag.TraceASCII) > 0 {
+		dest, ascii = flag.TraceASCII, true
+	}
+	var w io.Writer
+	switch dest {
+	case "":
+		return nil, nil
+	case transfer.Stdout:
+		w = os.Stdout
+	case "%":
+		w = os.Stderr
+	default:
+		f, err := os.Create(dest)
+		if err != nil {
+			return nil, fmt.Errorf("error opening trace file: %w", err)
+		}
+		w = f
+	}
+	return trace.New(maskWriter{w}, ascii, flag.TraceTime), nil
+}
+
+// compileFilter compiles the --jq or --json-path expression passed in. It returns nil when neither is set.
+func compileFilter(flag *config.Flags) (*jq.Program, error) {
+	src := flag.Jq
+	if len(flag.JSONPath) > 0 {
+		var err error
+		if src, err = jq.FromJSONPath(flag.JSONPath); err != nil {
+			return nil, fmt.Errorf("--json-path: %w", err)
+		}
+	}
+	if len(src) == 0 {
+		return nil, nil
+	}
+	filter, err := jq.Compile(src)
+	if err != nil {
+		return nil, fmt.Errorf("--jq %s: %w", src, err)
+	}
+	return filter, nil
+}
+
+// compileExpectations compiles the --expect-* checks passed in. It returns nil when none are, unless
+// --junit is set.
+func compileExpectations(flag *config.Flags) (*expect.Expectations, error) {
+	if !flag.Expecting() {
+		return nil, nil
+	}
+	return expect.New(flag.ExpectStatus, flag.ExpectHeaders, flag.ExpectBody, flag.ExpectJSON, flag.ExpectTimeUnder)
+}
+
+// writeExpectations writes the pass/fail report of the expectations to stderr, and to the --junit file if set.
+func writeExpectations(reports []expect.Report) int {
+	if Expect.Len() > 0 {
+		_ = expect.WriteText(maskWriter{os.Stderr}, reports)
+	}
+	if len(FLGS.JUnit) == 0 {
+		return exitcode.OK
+	}
+	f, err := os.Create(FLGS.JUnit)
+	if err == nil {
+		err = expect.WriteJUnit(maskWriter{f}, reports)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		log.Printf("Error writing JUnit report to %s: %s\n", FLGS.JUnit, err.Error())
+		return exitcode.WriteError
+	}
+	return exitcode.OK
+}
+
+// buildJobs pairs every url argument with its output file, expanding url globs into one job per url.
+// In socket mode the socket path and resource arguments make up a single job. Variables are substituted
+// in the urls before globs are expanded.
+func buildJobs(args []string, flag *config.Flags) ([]transfer.Job, error) {
+	data, err := requestData(flag.Data)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	if flag.Resolve() == config.MODE_SOCKET {
+		if len(args) > 2 {
+			return nil, fmt.Errorf("too many arguments passed in. Socket mode expects: scour socket [flags] <socket-path> <url>")
+		}
+		urls = []string{strings.Join(args, socketparser.SOCKET_ARG_DELIM)}
+	} else {
+		urls = args
+	}
+	if len(flag.Outputs) > len(urls) {
+		log.Printf("Warning: %d output files passed in for %d urls. Extra output files are ignored\n", len(flag.Outputs), len(urls))
+	}
+
+	var jobs []transfer.Job
+	for i := range urls {
+		var output string
+		if i < len(flag.Outputs) {
+			output = flag.Outputs[i]
+		}
+		url, err := Vars.Expand(urls[i])
+		if err != nil {
+			return nil, fmt.Errorf("url %s: %w", urls[i], err)
+		}
+		if flag.GlobOff || flag.Resolve() == config.MODE_SOCKET {
+			job, err := newJob(url, output, data, flag)
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, job)
+			continue
+		}
+		expanded, err := httparser.ExpandGlob(url)
+		if err != nil {
+			return nil, fmt.Errorf("url %s: %w. Use --globoff to pass it in as is", urls[i], err)
+		}
+		for _, g := range expanded {
+			job, err := newJob(g.Url, httparser.GlobOutput(output, g.Matches), data, flag)
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// newJob creates the job for a url, sent with the method and headers set in flag and in the config file
+// sections matching its host, with variables substituted in the headers and data.
+func newJob(url, output string, data []byte, flag *config.Flags) (transfer.Job, error) {
+	job := transfer.Job{Url: url, Output: output, Method: flag.Method, Headers: flag.Headers, Data: data}
+	applyHostConfig(&job)
+	return job, expandRequest(&job, Vars)
+}
+
+// requestData resolves the payload passed in with --data. Like curl, @file reads the payload from a file,
+// or from stdin with @-, dropping carriage returns and newlines.
+func requestData(data string) ([]byte, error) {
+	if !strings.HasPrefix(data, "@") {
+		return []byte(data), nil
+	}
+	var b []byte
+	var err error
+	if name := data[1:]; name == transfer.Stdout {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading request data: %w", err)
+	}
+	return bytes.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, b), nil
+}
+
+// invokeJob carries out a single transfer using the request method of the job
+func invokeJob(ctx context.Context, job transfer.Job) (res transfer.Result) {
+	url, err := parseUrl(ctx, job.Url, job.Method)
+	if err != nil {
+		log.Println(err)
+		res.Err = err
+		return
+	}
+
+	ctx = context.WithValue(ctx, httpoke.KeyOptions, &httpoke.Options{Headers: job.Headers})
+	switch job.Method {
+	case http.MethodGet:
+		res.Headers, res.Body, res.Err = httpoke.Get(ctx, url)
+	case http.MethodHead:
+		res.Headers, res.Body, res.Err = httpoke.Head(ctx, url)
+	case http.MethodPost:
+		res.Headers, res.Body, res.Err = httpoke.Post(ctx, url, job.Data)
+	case http.MethodDelete:
+		res.Headers, res.Body, res.Err = httpoke.Delete(ctx, url)
+	case http.MethodPut:
+		res.Headers, res.Body, res.Err = httpoke.Put(ctx, url, job.Data)
+	case http.MethodPatch:
+		res.Headers, res.Body, res.Err = httpoke.Patch(ctx, url, job.Data)
+	case config.MethodSocket:
+		sess := &socket.Session{}
+		res.Body, res.Err = socket.UnixSock(context.WithValue(ctx, socket.KeySession, sess), url, FLGS.InteractiveMode)
+		res.SessionID, res.Session = sess.ID, sess.Messages
+	}
+
+	if headers := res.Headers; headers != nil && headers.StatusCode >= 400 && (FLGS.Fail || FLGS.FailWithBody) {
+		res.Err = fmt.Errorf("%w: %s", exitcode.ErrHTTPStatus, headers.RespCode)
+		log.Printf("The requested url %s returned error: %s\n", job.Url, headers.RespCode)
+		if !FLGS.FailWithBody {
+			res.Body = nil
+		}
+	}
+
+	if FLGS.Verbose {
+		res.Verbose += fmt.Sprintf(ParsedUrlOutput, url.Host(), url.Host(), url.Host(), url.Host(), url.Port(), strings.ToUpper(url.Path()), url.Path(), url.Protocol().MustUpper(), url.Host()) + "\n"
+		if headers := res.Headers; headers != nil {
+			res.Verbose += fmt.Sprintf(InvokeOutput, headers.Protocol, headers.RespCode, headers.Date, headers.ContentType, headers.ContentLength, headers.Connection, headers.Server, headers.AccessControlAllowOrigin, headers.AccessControlAllowCredentials) + "\n"
+		}
+	}
+	return
+}
+
+// report writes every result to its destination, prints a per-url summary when more than one url
+// was requested, and returns the combined exit code of the invocation.
+func report(results []transfer.Result) int {
+	var envs []*envelope.Envelope
+	var dump io.Writer
+	if len(FLGS.DumpHeader) > 0 {
+		if FLGS.DumpHeader == transfer.Stdout {
+			dump = os.Stdout
+		} else {
+			f, err := os.Create(FLGS.DumpHeader)
+			if err != nil {
+				log.Printf("Error writing headers to %s: %s\n", FLGS.DumpHeader, err.Error())
+				return exitcode.WriteError
+			}
+			defer f.Close()
+			dump = f
+		}
+	}
+	var reports []expect.Report
+	for i := range results {
+		res := &results[i]
+		if Expect != nil {
+			reports = append(reports, Expect.Run(res))
+		}
+		if dump != nil && res.Headers != nil {
+			if _, err := dump.Write(res.Headers.Dump()); err != nil && res.Err == nil {
+				res.Err = fmt.Errorf("%w: %w", exitcode.ErrWrite, err)
+			}
+		}
+		writeResult(res)
+		if len(FLGS.OutputFormat) > 0 {
+			envs = append(envs, envelope.New(envelope.NewRequest(res.Job), *res))
+		}
+	}
+	if len(FLGS.OutputFormat) > 0 {
+		if err := envelope.Write(maskWriter{os.Stdout}, FLGS.OutputFormat, envs); err != nil {
+			log.Printf("Error writing %s output: %s\n", FLGS.OutputFormat, err.Error())
+			return exitcode.WriteError
+		}
+	}
+	if Expect != nil {
+		if code := writeExpectations(reports); code != exitcode.OK {
+			return code
+		}
+	}
+	if len(results) > 1 {
+		fmt.Fprint(os.Stderr, mask(transfer.Summary(results)))
+	}
+	return transfer.ExitCode(results)
+}
+
+// writeResult writes the body of a result to its output file, or to stdout unless an output format is set.
+// When a filter is set, the filtered body is written instead; output format records keep the full body.
+// With --include, the status line and headers are written ahead of the body.
+// Text bodies printed to a terminal, or to stdout with --charset, are decoded to UTF-8; files stay byte-exact.
+// Binary bodies are refused on a terminal, unless stdout was asked for explicitly with --output -. They are
+// then shown as a hexdump, or written as is with --raw-output.
+func writeResult(res *transfer.Result) {
+	fmt.Fprint(os.Stderr, mask(res.Verbose))
+	var head []byte
+	if FLGS.Include && res.Headers != nil {
+		head = res.Headers.Dump()
+	}
+	if res.Err != nil && len(res.Body) == 0 && len(head) == 0 {
+		return
+	}
+	contentType, body := "", res.Body
+	if res.Headers != nil {
+		contentType = res.Headers.ContentType
+	}
+	if Filter != nil && res.Method != http.MethodHead && (!res.ToStdout() || len(FLGS.OutputFormat) == 0) {
+		filtered, err := filterBody(res.Body)
+		if err != nil {
+			log.Printf("Error filtering response from %s: %s\n", res.Url, err.Error())
+			if res.Err == nil {
+				res.Err = err
+			}
+			return
+		}
+		contentType, body = "application/json", filtered
+	}
+	if res.ToStdout() {
+		if len(FLGS.OutputFormat) > 0 {
+			return
+		}
+		_, _ = os.Stdout.Write(head)
+		if Filter == nil && (len(FLGS.Charset) > 0 || utils.IsTerminal(os.Stdout)) {
+			decoded, err := charset.ToUTF8(contentType, body, FLGS.Charset)
+			if err != nil {
+				log.Printf("Warning: printing response from %s undecoded: %s\n", res.Url, err.Error())
+			}
+			body = decoded
+		}
+		if utils.IsTerminal(os.Stdout) && res.Output != transfer.Stdout && pretty.Binary(contentType, body) {
+			log.Printf("Not printing %d bytes of binary response from %s. %s\n", len(body), res.Url, pretty.BinaryWarning)
+			if res.Err == nil {
+				res.Err = fmt.Errorf("%w: binary output refused on a terminal", exitcode.ErrWrite)
+			}
+			return
+		}
+		_, _ = os.Stdout.Write(display(contentType, body))
+		return
+	}
+	if err := os.WriteFile(res.Output, append(head, body...), 0644); err != nil {
+		log.Printf("Error writing response to %s: %s\n", res.Output, err.Error())
+		if res.Err == nil {
+			res.Err = fmt.Errorf("%w: %w", exitcode.ErrWrite, err)
+		}
+	}
+}
+
+// filterBody runs a response body through Filter, returning its outputs one per line.
+func filterBody(body []byte) ([]byte, error) {
+	vals, err := Filter.RunJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	return jq.Encode(vals, FLGS.RawOutput)
+}
+
+// display returns a body as it should be printed to stdout. On a terminal it is pretty-printed
+// according to its content type, unless --raw-output is set.
+func display(contentType string, body []byte) []byte {
+	if FLGS.RawOutput || !utils.IsTerminal(os.Stdout) {
+		return body
+	}
+	return pretty.Body(contentType, body, pretty.Options{
+		Color:    len(os.Getenv("NO_COLOR")) == 0 && os.Getenv("TERM") != "dumb",
+		SortKeys: FLGS.SortKeys,
+	})
+}
+
+// initColor sends colored diagnostics to stderr, alongside every other diagnostic, so stdout only ever
+// carries response output. Color is turned off when stderr isn't a terminal or NO_COLOR is set.
+func initColor() {
+	color.Output = os.Stderr
+	color.NoColor = len(os.Getenv("NO_COLOR")) > 0 || os.Getenv("TERM") == "dumb" || !utils.IsTerminal(os.Stderr)
+}
+
+// parseUrl parses the right url from the request, according to its method
+func parseUrl(ctx context.Context, urlString string, method string) (url parser.Url, err error) {
+	if len(urlString) < 1 {
+		return nil, fmt.Errorf("%w: url string empty", exitcode.ErrUrlMalformed)
+	}
+	if method == config.MethodSocket {
+		url = socketparser.NewSocket(ctx, urlString)
+	} else {
+		httpurl, err := httparser.NewUrl(ctx, urlString)
+		if httpurl == nil {
+			return nil, fmt.Errorf("%w: %s: %s", exitcode.ErrUrlMalformed, urlString, err)
+		}
+		url = httpurl
+	}
+
+	if url.Err() != nil {
+		return nil, fmt.Errorf("%w: %s: %s", exitcode.ErrUrlMalformed, urlString, url.Err())
+	}
+	return url, nil
+}
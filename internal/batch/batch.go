@@ -0,0 +1,292 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/envelope"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/expect"
+	"github.com/dark-enstein/scour/internal/parser/socketparser"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"golang.org/x/exp/slices"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// DefaultConcurrency is the number of requests of a batch in flight at once, unless set otherwise.
+	DefaultConcurrency = 4
+	// ErrStatus is the error of a request whose response status isn't the expected one.
+	ErrStatus = errors.New("unexpected status")
+)
+
+// Request describes a single request of a batch file, one JSON object per line.
+type Request struct {
+	Name    string          `json:"name,omitempty"`    // Name shown in the summary, optional.
+	Method  string          `json:"method,omitempty"`  // Request method, GET when left out.
+	Url     string          `json:"url"`               // Url, or the resource sent through Socket.
+	Headers Headers         `json:"headers,omitempty"` // Headers, as an object or a list of "Name: value".
+	Body    json.RawMessage `json:"body,omitempty"`    // Body, as a string sent as is or any other JSON value.
+	// ExpectStatus fails the request when the response status isn't one of these. Empty accepts any status.
+	ExpectStatus Status `json:"expect_status,omitempty"`
+	// Socket sends Url as the resource through this Unix domain socket, instead of over HTTP.
+	Socket string `json:"socket,omitempty"`
+}
+
+// Headers holds request headers in "Name: value" form. In JSON they are either a list in that form, or
+// an object mapping names to a value or a list of values.
+type Headers []string
+
+// UnmarshalJSON decodes headers from a list or an object. Object keys are sorted, for a stable order.
+func (h *Headers) UnmarshalJSON(b []byte) error {
+	var list []string
+	if err := json.Unmarshal(b, &list); err == nil {
+		*h = list
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return fmt.Errorf("headers must be a list of \"Name: value\" or an object")
+	}
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var values []string
+		if err := json.Unmarshal(obj[name], &values); err != nil {
+			var value string
+			if err := json.Unmarshal(obj[name], &value); err != nil {
+				return fmt.Errorf("header %s must be a string or a list of strings", name)
+			}
+			values = []string{value}
+		}
+		for _, v := range values {
+			*h = append(*h, name+": "+v)
+		}
+	}
+	return nil
+}
+
+// Status lists the statuses a response is expected to have, as codes or classes such as 2xx, with the
+// grammar of --expect-status. In JSON it is a code, such as 201, or a string listing codes and classes,
+// comma-separated, such as "2xx" or "200,204".
+type Status []string
+
+// ParseStatus parses a comma-separated list of codes and classes of statuses.
+func ParseStatus(s string) (Status, error) {
+	return expect.ParseStatus(s)
+}
+
+// UnmarshalJSON decodes a status from a code or a string.
+func (s *Status) UnmarshalJSON(b []byte) error {
+	var code int
+	if err := json.Unmarshal(b, &code); err == nil {
+		b, _ = json.Marshal(strconv.Itoa(code))
+	}
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return fmt.Errorf("expect_status must be a status, such as 200, or a string such as \"200,204\" or \"2xx\"")
+	}
+	status, err := ParseStatus(str)
+	if err != nil {
+		return fmt.Errorf("expect_status: %w", err)
+	}
+	*s = status
+	return nil
+}
+
+// MarshalJSON encodes a single code as a number, as it was written, and anything else as a string.
+func (s Status) MarshalJSON() ([]byte, error) {
+	if code, err := strconv.Atoi(s.String()); err == nil {
+		return json.Marshal(code)
+	}
+	return json.Marshal(s.String())
+}
+
+// String returns the statuses, comma-separated.
+func (s Status) String() string {
+	return strings.Join(s, ",")
+}
+
+// Code returns the status code expected, when it is a single one, or zero.
+func (s Status) Code() int {
+	if len(s) != 1 {
+		return 0
+	}
+	code, _ := strconv.Atoi(s[0])
+	return code
+}
+
+// Match reports whether code is one of the statuses. Empty statuses match any code.
+func (s Status) Match(code int) bool {
+	return len(s) == 0 || expect.MatchStatus(s, code)
+}
+
+// Line is a line of a batch file: its number, starting at 1, and the request it describes. Blank lines
+// have no request, and malformed ones an error.
+type Line struct {
+	Number  int
+	Request *Request
+	Err     error
+}
+
+// Read reads every line of a batch file.
+func Read(r io.Reader) ([]Line, error) {
+	var lines []Line
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := Line{Number: n}
+		if b := bytes.TrimSpace(scanner.Bytes()); len(b) > 0 {
+			line.Request, line.Err = parse(b)
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parse decodes and validates the request of a line.
+func parse(b []byte) (*Request, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var req Request
+	if err := dec.Decode(&req); err != nil {
+		return nil, fmt.Errorf("malformed request: %w", err)
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// Validate checks the fields of a decoded request, defaulting its method.
+func (r *Request) Validate() error {
+	if len(r.Url) == 0 {
+		return fmt.Errorf("malformed request: url is missing")
+	}
+	r.Method = strings.ToUpper(r.Method)
+	switch {
+	case len(r.Method) > 0:
+	case len(r.Body) > 0:
+		// like curl, requests with a body are POSTed unless told otherwise
+		r.Method = http.MethodPost
+	default:
+		r.Method = http.MethodGet
+	}
+	if len(r.Socket) > 0 {
+		if len(r.ExpectStatus) > 0 || len(r.Headers) > 0 || len(r.Body) > 0 {
+			return fmt.Errorf("malformed request: socket requests take no headers, body or expect_status")
+		}
+		return nil
+	}
+	if !slices.Contains(config.AllSupportedConn[1:], r.Method) {
+		return fmt.Errorf("method %s is not supported. please use one of: %s", r.Method, strings.Join(config.AllSupportedConn[1:], ", "))
+	}
+	return nil
+}
+
+// Job turns the request into a transfer job.
+func (r *Request) Job() transfer.Job {
+	if len(r.Socket) > 0 {
+		return transfer.Job{Url: r.Socket + socketparser.SOCKET_ARG_DELIM + r.Url, Method: config.MethodSocket}
+	}
+	job := transfer.Job{Url: r.Url, Method: r.Method, Headers: r.Headers}
+	if len(r.Body) > 0 {
+		var s string
+		if err := json.Unmarshal(r.Body, &s); err == nil {
+			job.Data = []byte(s)
+		} else {
+			var compact bytes.Buffer
+			_ = json.Compact(&compact, r.Body)
+			job.Data = compact.Bytes()
+			if !hasHeader(job.Headers, "Content-Type") {
+				job.Headers = append(slices.Clip(job.Headers), "Content-Type: application/json")
+			}
+		}
+	}
+	return job
+}
+
+// hasHeader reports whether headers hold one named name.
+func hasHeader(headers []string, name string) bool {
+	for _, h := range headers {
+		if k, _, _ := strings.Cut(h, ":"); strings.EqualFold(strings.TrimSpace(k), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Record is the result of a line of a batch file, written on the same line of the results file. It holds
+// the envelope of the transfer, alongside whether it met expectations. Blank input lines have no record,
+// and malformed ones only an error.
+type Record struct {
+	Line         int    `json:"line"`
+	Name         string `json:"name,omitempty"`
+	OK           bool   `json:"ok"`
+	ExpectStatus Status `json:"expect_status,omitempty"`
+	*envelope.Envelope
+	// invalid describes why the line couldn't be parsed. The Envelope is nil then.
+	invalid *envelope.Error
+}
+
+// NewRecord builds the record of a line. res is the result of its transfer, nil when it didn't run.
+func NewRecord(line Line, res *transfer.Result) *Record {
+	rec := &Record{Line: line.Number}
+	if line.Err != nil {
+		rec.invalid = &envelope.Error{Message: line.Err.Error(), ExitCode: exitcode.Usage}
+		return rec
+	}
+	rec.Name, rec.ExpectStatus = line.Request.Name, line.Request.ExpectStatus
+	if res.Err == nil && res.Headers != nil && !rec.ExpectStatus.Match(res.Headers.StatusCode) {
+		res.Err = fmt.Errorf("%w: expected %s, got %d", ErrStatus, rec.ExpectStatus, res.Headers.StatusCode)
+	}
+	rec.Envelope = envelope.New(envelope.NewRequest(res.Job), *res)
+	if res.Err != nil && errors.Is(res.Err, ErrStatus) {
+		rec.Envelope.Error.ExitCode = exitcode.HTTPError
+	}
+	rec.OK = res.Err == nil
+	return rec
+}
+
+// Failure returns why the line failed, or nil when it didn't.
+func (r *Record) Failure() *envelope.Error {
+	if r.invalid != nil {
+		return r.invalid
+	}
+	if r.OK || r.Envelope == nil {
+		return nil
+	}
+	return r.Error
+}
+
+// ExitCode returns the exit code of a failed record, or exitcode.OK.
+func (r *Record) ExitCode() int {
+	if f := r.Failure(); f != nil {
+		return f.ExitCode
+	}
+	return exitcode.OK
+}
+
+// MarshalJSON encodes the record, with the fields of its envelope inline.
+func (r *Record) MarshalJSON() ([]byte, error) {
+	if r.invalid != nil {
+		return json.Marshal(struct {
+			Line  int             `json:"line"`
+			OK    bool            `json:"ok"`
+			Error *envelope.Error `json:"error"`
+		}{r.Line, false, r.invalid})
+	}
+	// plain drops the methods of Record, so that encoding it doesn't recurse
+	type plain Record
+	return json.Marshal((*plain)(r))
+}
@@ -0,0 +1,106 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var (
+	// testBatch is a batch file holding every kind of line.
+	testBatch = `{"url": "https://example.com/a", "name": "a"}
+
+{"method": "put", "url": "https://example.com/b", "headers": {"X-B": ["2", "3"], "X-A": "1"}, "body": {"k": [1, 2]}}
+{"url": "https://example.com/c", "headers": ["X-C: 4"], "body": "raw", "expect_status": 201}
+{"url": "http:/ping", "socket": "/tmp/app.sock"}
+not json
+{"url": "https://example.com/d", "extra": 1}
+{"url": "https://example.com/e", "method": "OPTIONS"}
+{"url": "http:/ping", "socket": "/tmp/app.sock", "expect_status": 200}
+`
+)
+
+// TestRead checks parsing of requests, and that every line is accounted for.
+func TestRead(t *testing.T) {
+	lines, err := Read(strings.NewReader(testBatch))
+	assert.NoError(t, err)
+	if !assert.Len(t, lines, 9) {
+		return
+	}
+	assert.Equal(t, transfer.Job{Url: "https://example.com/a", Method: "GET"}, lines[0].Request.Job())
+	assert.Nil(t, lines[1].Request)
+	assert.NoError(t, lines[1].Err)
+	assert.Equal(t, transfer.Job{Url: "https://example.com/b", Method: "PUT", Data: []byte(`{"k":[1,2]}`),
+		Headers: []string{"X-A: 1", "X-B: 2", "X-B: 3", "Content-Type: application/json"}}, lines[2].Request.Job())
+	assert.Equal(t, transfer.Job{Url: "https://example.com/c", Method: "POST", Data: []byte("raw"),
+		Headers: []string{"X-C: 4"}}, lines[3].Request.Job())
+	assert.Equal(t, transfer.Job{Url: "/tmp/app.sock http:/ping", Method: "SOCKET"}, lines[4].Request.Job())
+	for _, line := range lines[5:] {
+		assert.Error(t, line.Err, line.Number)
+		assert.Nil(t, line.Request, line.Number)
+	}
+}
+
+// TestNewRecord checks the outcome of expectations, and the encoding of records.
+func TestNewRecord(t *testing.T) {
+	lines, err := Read(strings.NewReader(testBatch))
+	assert.NoError(t, err)
+
+	res := &transfer.Result{Job: lines[3].Request.Job(), Headers: &invoke.RespHeaders{StatusCode: 200, RespCode: "200 OK"}}
+	rec := NewRecord(lines[3], res)
+	assert.False(t, rec.OK)
+	assert.True(t, errors.Is(res.Err, ErrStatus))
+	assert.Equal(t, exitcode.HTTPError, rec.ExitCode())
+
+	res = &transfer.Result{Job: lines[3].Request.Job(), Headers: &invoke.RespHeaders{StatusCode: 201, RespCode: "201 Created"}}
+	rec = NewRecord(lines[3], res)
+	assert.True(t, rec.OK)
+	assert.Equal(t, exitcode.OK, rec.ExitCode())
+	b, err := json.Marshal(rec)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `{"line":4,"ok":true,"expect_status":201,"url":"https://example.com/c"`)
+
+	rec = NewRecord(lines[5], nil)
+	assert.Equal(t, exitcode.Usage, rec.ExitCode())
+	b, err = json.Marshal(rec)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `{"line":6,"ok":false,"error":{"message":"malformed request`)
+}
+
+// TestStatus checks the decoding of expected statuses, as codes and lists of codes and classes.
+func TestStatus(t *testing.T) {
+	for in, expected := range map[string]Status{
+		`201`:        {"201"},
+		`"201"`:      {"201"},
+		`"2xx"`:      {"2xx"},
+		`"200, 3XX"`: {"200", "3xx"},
+		`"200,204"`:  {"200", "204"},
+	} {
+		var s Status
+		assert.NoError(t, json.Unmarshal([]byte(in), &s), in)
+		assert.Equal(t, expected, s, in)
+	}
+	for _, in := range []string{`600`, `"2x"`, `"ok"`, `true`, `[200]`} {
+		var s Status
+		assert.Error(t, json.Unmarshal([]byte(in), &s), in)
+	}
+
+	b, err := json.Marshal(Request{Url: "x", ExpectStatus: Status{"201"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"url":"x","expect_status":201}`, string(b))
+	b, err = json.Marshal(Request{Url: "x", ExpectStatus: Status{"2xx", "404"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"url":"x","expect_status":"2xx,404"}`, string(b))
+
+	assert.True(t, Status{"2xx", "404"}.Match(204))
+	assert.True(t, Status{"2xx", "404"}.Match(404))
+	assert.False(t, Status{"2xx", "404"}.Match(500))
+	assert.True(t, Status(nil).Match(500))
+	assert.Equal(t, 201, Status{"201"}.Code())
+	assert.Zero(t, Status{"2xx"}.Code())
+}
@@ -0,0 +1,90 @@
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"mime"
+	"regexp"
+	"strings"
+)
+
+var (
+	// UTF8 is the canonical name of the charset bodies are decoded into.
+	UTF8 = "utf-8"
+	// MetaScanLen is how many leading bytes of an HTML body are scanned for a <meta> charset declaration.
+	MetaScanLen = 1024
+	// metaRe matches <meta charset="x"> as well as <meta http-equiv="Content-Type" content="text/html; charset=x">.
+	metaRe = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+	// boms maps the byte order marks scour recognises to the charset they announce.
+	boms = []struct {
+		bom     []byte
+		charset string
+	}{
+		{[]byte{0xEF, 0xBB, 0xBF}, UTF8},
+		{[]byte{0xFE, 0xFF}, "utf-16be"},
+		{[]byte{0xFF, 0xFE}, "utf-16le"},
+	}
+)
+
+// Lookup returns the encoding registered under a charset label, such as latin1, shift_jis or windows-1252.
+func Lookup(name string) (encoding.Encoding, error) {
+	enc, err := htmlindex.Get(strings.TrimSpace(name))
+	if err != nil {
+		return nil, fmt.Errorf("charset \"%s\" is not supported", name)
+	}
+	return enc, nil
+}
+
+// Detect works out the charset of a body. A byte order mark wins, then the charset parameter of the
+// content type, then a <meta> declaration in HTML bodies. It returns an empty string when none is found.
+func Detect(contentType string, body []byte) string {
+	for _, b := range boms {
+		if bytes.HasPrefix(body, b.bom) {
+			return b.charset
+		}
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if cs := params["charset"]; len(cs) > 0 {
+		return strings.ToLower(cs)
+	}
+	if len(mediaType) == 0 || strings.Contains(mediaType, "html") {
+		head := body
+		if len(head) > MetaScanLen {
+			head = head[:MetaScanLen]
+		}
+		if m := metaRe.FindSubmatch(head); m != nil {
+			return strings.ToLower(string(m[1]))
+		}
+	}
+	return ""
+}
+
+// ToUTF8 decodes a body into UTF-8. The charset is detected from the content type and the body itself
+// unless override is set. Bodies already in UTF-8, or in no detectable charset, are returned as is,
+// minus any UTF-8 byte order mark.
+func ToUTF8(contentType string, body []byte, override string) ([]byte, error) {
+	name := override
+	if len(name) == 0 {
+		name = Detect(contentType, body)
+	}
+	if len(name) == 0 {
+		return body, nil
+	}
+	enc, err := Lookup(name)
+	if err != nil {
+		return body, err
+	}
+	if enc == unicode.UTF8 {
+		return bytes.TrimPrefix(body, boms[0].bom), nil
+	}
+	// a byte order mark in the body takes precedence over a declared UTF-16 variant
+	out, _, err := transform.Bytes(unicode.BOMOverride(enc.NewDecoder()), body)
+	if err != nil {
+		return body, fmt.Errorf("decoding body from %s: %w", name, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package charset
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var (
+	// testDetect maps a content type and body to the charset they are expected to be detected as.
+	testDetect = []struct {
+		contentType string
+		body        string
+		charset     string
+	}{
+		{"text/plain; charset=ISO-8859-1", "caf\xe9", "iso-8859-1"},
+		{"text/html", `<html><head><meta charset="Shift_JIS"></head></html>`, "shift_jis"},
+		{"text/html", `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">`, "windows-1252"},
+		{"", `<meta charset=euc-kr>`, "euc-kr"},
+		{"text/html; charset=utf-8", `<meta charset="latin1">`, "utf-8"},
+		{"text/plain", "\xef\xbb\xbfhello", "utf-8"},
+		{"text/plain; charset=latin1", "\xff\xfeh\x00i\x00", "utf-16le"},
+		{"application/json", `{"a": "<meta charset=latin1>"}`, ""},
+		{"text/plain", "hello", ""},
+	}
+	// testToUTF8 maps a content type, body and override to the expected UTF-8 output.
+	testToUTF8 = []struct {
+		contentType string
+		body        string
+		override    string
+		expected    string
+	}{
+		{"text/plain; charset=iso-8859-1", "caf\xe9", "", "café"},
+		{"text/plain; charset=windows-1252", "\x93quoted\x94 \x80", "", "“quoted” €"},
+		{"text/plain; charset=shift_jis", "\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd", "", "こんにちは"},
+		{"text/plain", "\xef\xbb\xbfhello", "", "hello"},
+		{"text/plain", "\xfe\xff\x00h\x00i", "", "hi"},
+		{"text/plain; charset=utf-8", "caf\xe9", "latin1", "café"},
+		{"text/plain", "plain", "", "plain"},
+	}
+)
+
+// TestDetect checks that byte order marks win over the content type, which wins over <meta> tags.
+func TestDetect(t *testing.T) {
+	for _, tc := range testDetect {
+		assert.Equal(t, tc.charset, Detect(tc.contentType, []byte(tc.body)), tc.contentType+" "+tc.body)
+	}
+}
+
+// TestToUTF8 checks decoding of detected and overridden charsets.
+func TestToUTF8(t *testing.T) {
+	for _, tc := range testToUTF8 {
+		out, err := ToUTF8(tc.contentType, []byte(tc.body), tc.override)
+		assert.NoError(t, err, tc.contentType)
+		assert.Equal(t, tc.expected, string(out), tc.contentType)
+	}
+	out, err := ToUTF8("text/plain; charset=klingon", []byte("body"), "")
+	assert.Error(t, err)
+	assert.Equal(t, "body", string(out))
+	_, err = Lookup("nonsense-8")
+	assert.Error(t, err)
+}
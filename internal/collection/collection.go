@@ -0,0 +1,251 @@
+package collection
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/batch"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/vars"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	// Sep separates the collection, folders and request of a path, as in users-api/accounts/create.
+	Sep = "/"
+	// ErrNoItem is returned for paths that don't name a request or folder of a collection.
+	ErrNoItem = errors.New("no such request or folder")
+)
+
+// Collection is a named set of requests, organized in folders. Requests are kept as written, and templated
+// with the variables of the collection, the environment and --var when they are sent.
+type Collection struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Vars        vars.Vars `json:"vars,omitempty"` // Variables every request is templated with, unless overridden.
+	Items       []*Item   `json:"items"`
+}
+
+// Item is a request of a collection, or a folder holding more items. Exactly one of Request and Items is set,
+// though folders may be empty.
+type Item struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Request     *batch.Request `json:"request,omitempty"`
+	Items       []*Item        `json:"items,omitempty"`
+}
+
+// Entry is a request of a collection, and its path below the collection.
+type Entry struct {
+	Path    string
+	Request *batch.Request
+}
+
+// IsFolder reports whether the item is a folder rather than a request.
+func (i *Item) IsFolder() bool {
+	return i.Request == nil
+}
+
+// SplitPath splits a path such as users-api/accounts/create into the name of the collection, and the names
+// of the folders and request below it.
+func SplitPath(p string) (string, []string, error) {
+	names := strings.Split(strings.Trim(p, Sep), Sep)
+	for _, name := range names {
+		if len(strings.TrimSpace(name)) == 0 {
+			return "", nil, fmt.Errorf("invalid path %q: use <collection>%s<folder>%s...%s<name>", p, Sep, Sep, Sep)
+		}
+	}
+	return names[0], names[1:], nil
+}
+
+// StringBody encodes s as the body of a request, sent as is.
+func StringBody(s string) json.RawMessage {
+	return quote(s)
+}
+
+// Read reads the collection file at p. Its requests are validated, with their methods defaulted.
+func Read(p string) (*Collection, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	c := &Collection{}
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("collection %s: %w", p, err)
+	}
+	for _, e := range c.entries(c.Items, "") {
+		if err := e.Request.Validate(); err != nil {
+			return nil, fmt.Errorf("collection %s: %s: %w", p, e.Path, err)
+		}
+		// bodies come back indented as written, compacted they rewrite as they were stored
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, e.Request.Body); err == nil {
+			e.Request.Body = buf.Bytes()
+		}
+	}
+	return c, nil
+}
+
+// Write writes the collection file at p, indented so that it diffs well.
+func (c *Collection) Write(p string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return os.WriteFile(p, buf.Bytes(), 0o600)
+}
+
+// Find returns the item at the path of names below the collection, or nil when there's none.
+func (c *Collection) Find(names []string) *Item {
+	items := c.Items
+	var found *Item
+	for _, name := range names {
+		if found = find(items, name); found == nil {
+			return nil
+		}
+		items = found.Items
+	}
+	return found
+}
+
+// Put stores req at the path of names below the collection, creating the folders on the way, and replacing
+// a request already there. It returns whether a request was replaced.
+func (c *Collection) Put(names []string, req *batch.Request) (bool, error) {
+	if len(names) == 0 {
+		return false, fmt.Errorf("a request needs a name below the collection")
+	}
+	items := &c.Items
+	for i, name := range names[:len(names)-1] {
+		folder := find(*items, name)
+		switch {
+		case folder == nil:
+			folder = &Item{Name: name}
+			*items = append(*items, folder)
+		case !folder.IsFolder():
+			return false, fmt.Errorf("%s is a request, not a folder", strings.Join(names[:i+1], Sep))
+		}
+		items = &folder.Items
+	}
+	name := names[len(names)-1]
+	if item := find(*items, name); item != nil {
+		if item.IsFolder() {
+			return false, fmt.Errorf("%s is a folder, not a request", strings.Join(names, Sep))
+		}
+		item.Request = req
+		return true, nil
+	}
+	*items = append(*items, &Item{Name: name, Request: req})
+	return false, nil
+}
+
+// Remove removes the request or folder at the path of names below the collection.
+func (c *Collection) Remove(names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("a path below the collection is needed")
+	}
+	items := &c.Items
+	if len(names) > 1 {
+		parent := c.Find(names[:len(names)-1])
+		if parent == nil || !parent.IsFolder() {
+			return fmt.Errorf("%w: %s", ErrNoItem, strings.Join(names, Sep))
+		}
+		items = &parent.Items
+	}
+	for i, item := range *items {
+		if item.Name == names[len(names)-1] {
+			*items = append((*items)[:i], (*items)[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNoItem, strings.Join(names, Sep))
+}
+
+// Requests returns the requests at the path of names below the collection, in order: the request itself, or
+// every request of the folder and its subfolders. No names returns every request of the collection.
+func (c *Collection) Requests(names []string) ([]Entry, error) {
+	if len(names) == 0 {
+		return c.entries(c.Items, ""), nil
+	}
+	item := c.Find(names)
+	if item == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoItem, strings.Join(names, Sep))
+	}
+	return c.entries([]*Item{item}, strings.Join(names[:len(names)-1], Sep)), nil
+}
+
+// entries walks items depth-first, returning their requests with paths below prefix.
+func (c *Collection) entries(items []*Item, prefix string) []Entry {
+	var entries []Entry
+	for _, item := range items {
+		p := item.Name
+		if len(prefix) > 0 {
+			p = prefix + Sep + item.Name
+		}
+		if item.IsFolder() {
+			entries = append(entries, c.entries(item.Items, p)...)
+			continue
+		}
+		entries = append(entries, Entry{Path: p, Request: item.Request})
+	}
+	return entries
+}
+
+// Tree writes the collection to w as a tree of its folders and requests, in order:
+//
+//	users-api
+//	├── accounts/
+//	│   └── POST create
+//	└── GET health
+func (c *Collection) Tree(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, c.Name); err != nil {
+		return err
+	}
+	return tree(w, c.Items, "")
+}
+
+// tree writes items to w, indented by prefix.
+func tree(w io.Writer, items []*Item, prefix string) error {
+	for i, item := range items {
+		branch, indent := "├── ", "│   "
+		if i == len(items)-1 {
+			branch, indent = "└── ", "    "
+		}
+		label := item.Name + Sep
+		if !item.IsFolder() {
+			label = Method(item.Request) + " " + item.Name
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", prefix, branch, label); err != nil {
+			return err
+		}
+		if err := tree(w, item.Items, prefix+indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method returns the method of req, or SOCKET for requests sent through a socket.
+func Method(req *batch.Request) string {
+	if len(req.Socket) > 0 {
+		return config.MethodSocket
+	}
+	return req.Method
+}
+
+// find returns the item of items called name, or nil when there's none.
+func find(items []*Item, name string) *Item {
+	for _, item := range items {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
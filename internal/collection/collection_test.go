@@ -0,0 +1,228 @@
+package collection
+
+import (
+	"encoding/json"
+	"github.com/dark-enstein/scour/internal/batch"
+	"github.com/dark-enstein/scour/internal/vars"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testCollection builds a collection with a folder, a subfolder and a request at the top.
+func testCollection(t *testing.T) *Collection {
+	c := &Collection{Name: "users-api"}
+	for p, req := range map[string]*batch.Request{
+		"accounts/list":         {Method: "GET", Url: "{{base_url}}/users"},
+		"accounts/admin/delete": {Method: "DELETE", Url: "{{base_url}}/users/1"},
+		"health":                {Method: "GET", Url: "{{base_url}}/health"},
+	} {
+		_, names, err := SplitPath("users-api/" + p)
+		assert.NoError(t, err)
+		_, err = c.Put(names, req)
+		assert.NoError(t, err)
+	}
+	// map order is random, so the items are put in a known order
+	c.Items = []*Item{c.Find([]string{"accounts"}), c.Find([]string{"health"})}
+	accounts := c.Find([]string{"accounts"})
+	accounts.Items = []*Item{c.Find([]string{"accounts", "list"}), c.Find([]string{"accounts", "admin"})}
+	return c
+}
+
+// TestSplitPath checks splitting paths into a collection and the names below it.
+func TestSplitPath(t *testing.T) {
+	name, names, err := SplitPath("users-api/accounts/create user")
+	assert.NoError(t, err)
+	assert.Equal(t, "users-api", name)
+	assert.Equal(t, []string{"accounts", "create user"}, names)
+
+	name, names, err = SplitPath("users-api/")
+	assert.NoError(t, err)
+	assert.Equal(t, "users-api", name)
+	assert.Empty(t, names)
+
+	_, _, err = SplitPath("users-api//create")
+	assert.Error(t, err)
+	_, _, err = SplitPath("")
+	assert.Error(t, err)
+}
+
+// TestPut checks that requests are stored in folders created on the way, and replaced in place.
+func TestPut(t *testing.T) {
+	c := testCollection(t)
+	replaced, err := c.Put([]string{"accounts", "list"}, &batch.Request{Method: "GET", Url: "{{base_url}}/v2/users"})
+	assert.NoError(t, err)
+	assert.True(t, replaced)
+	assert.Equal(t, "{{base_url}}/v2/users", c.Find([]string{"accounts", "list"}).Request.Url)
+	assert.Equal(t, "list", c.Find([]string{"accounts"}).Items[0].Name)
+
+	_, err = c.Put([]string{"health", "deep"}, &batch.Request{Url: "x"})
+	assert.ErrorContains(t, err, "health is a request")
+	_, err = c.Put([]string{"accounts", "admin"}, &batch.Request{Url: "x"})
+	assert.ErrorContains(t, err, "accounts/admin is a folder")
+	_, err = c.Put(nil, &batch.Request{Url: "x"})
+	assert.Error(t, err)
+}
+
+// TestRequests checks that requests are listed depth-first, in order, for a request, a folder or everything.
+func TestRequests(t *testing.T) {
+	c := testCollection(t)
+	paths := func(entries []Entry) []string {
+		var p []string
+		for _, e := range entries {
+			p = append(p, e.Path)
+		}
+		return p
+	}
+	entries, err := c.Requests(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"accounts/list", "accounts/admin/delete", "health"}, paths(entries))
+
+	entries, err = c.Requests([]string{"accounts"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"accounts/list", "accounts/admin/delete"}, paths(entries))
+
+	entries, err = c.Requests([]string{"accounts", "admin", "delete"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"accounts/admin/delete"}, paths(entries))
+	assert.Equal(t, "DELETE", entries[0].Request.Method)
+
+	_, err = c.Requests([]string{"accounts", "nope"})
+	assert.ErrorIs(t, err, ErrNoItem)
+}
+
+// TestRemove checks removing requests and folders.
+func TestRemove(t *testing.T) {
+	c := testCollection(t)
+	assert.NoError(t, c.Remove([]string{"accounts", "admin"}))
+	assert.Nil(t, c.Find([]string{"accounts", "admin", "delete"}))
+	assert.NoError(t, c.Remove([]string{"health"}))
+	assert.ErrorIs(t, c.Remove([]string{"health"}), ErrNoItem)
+	assert.ErrorIs(t, c.Remove([]string{"health", "x"}), ErrNoItem)
+	entries, err := c.Requests(nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestTree checks the rendering of collections as trees.
+func TestTree(t *testing.T) {
+	var sb strings.Builder
+	assert.NoError(t, testCollection(t).Tree(&sb))
+	assert.Equal(t, `users-api
+├── accounts/
+│   ├── GET list
+│   └── admin/
+│       └── DELETE delete
+└── GET health
+`, sb.String())
+}
+
+// TestReadWrite checks that collections read back as written, and that invalid requests are refused.
+func TestReadWrite(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "users-api.json")
+	c := testCollection(t)
+	c.Vars = vars.Vars{"base_url": "https://api.example.com"}
+	_, err := c.Put([]string{"accounts", "create"}, &batch.Request{Method: "POST", Url: "{{base_url}}/users", Body: json.RawMessage(`{"name":"Ann"}`), ExpectStatus: batch.Status{"201"}})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Write(p))
+	got, err := Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, c, got)
+
+	assert.NoError(t, os.WriteFile(p, []byte(`{"name": "x", "items": [{"name": "a", "request": {"method": "TRACE", "url": "x"}}]}`), 0o600))
+	_, err = Read(p)
+	assert.ErrorContains(t, err, "a: method TRACE is not supported")
+	assert.NoError(t, os.WriteFile(p, []byte(`{"name": "x", "folders": []}`), 0o600))
+	_, err = Read(p)
+	assert.Error(t, err)
+}
+
+// TestImportPostman checks the conversion of a Postman collection, and the warnings about what is left out.
+func TestImportPostman(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "users.postman_collection.json"))
+	assert.NoError(t, err)
+	c, warnings, err := ImportPostman(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "Users API", c.Name)
+	assert.Equal(t, "Accounts and health checks", c.Description)
+	assert.Equal(t, vars.Vars{"base_url": "https://api.example.com", "page_size": "20"}, c.Vars)
+	assert.Equal(t, []string{
+		"accounts/avatar: formdata bodies are not supported, the body is left out",
+		"health: method OPTIONS is not supported, left out",
+		"health: prerequest script is not run",
+	}, warnings)
+
+	entries, err := c.Requests(nil)
+	assert.NoError(t, err)
+	got := map[string]*batch.Request{}
+	var paths []string
+	for _, e := range entries {
+		got[e.Path] = e.Request
+		paths = append(paths, e.Path)
+	}
+	assert.Equal(t, []string{"accounts/list users", "accounts/create-update user", "accounts/login", "accounts/avatar", "health"}, paths)
+	assert.Equal(t, &batch.Request{
+		Method:       "GET",
+		Url:          "{{base_url}}/users?limit={{page_size}}",
+		Headers:      batch.Headers{"Accept: application/json", "Authorization: Bearer {{token}}"},
+		ExpectStatus: batch.Status{"200"},
+	}, got["accounts/list users"])
+	assert.Equal(t, &batch.Request{
+		Method:  "POST",
+		Url:     "{{base_url}}/users",
+		Headers: batch.Headers{"Authorization: Bearer {{token}}"},
+		Body:    json.RawMessage(`{"name":"Ann"}`),
+	}, got["accounts/create-update user"])
+	assert.Equal(t, &batch.Request{
+		Method:  "POST",
+		Url:     "https://api.example.com/login",
+		Headers: batch.Headers{"Content-Type: application/x-www-form-urlencoded"},
+		Body:    json.RawMessage(`"user=ann+smith&password={{password}}"`),
+	}, got["accounts/login"])
+	assert.Equal(t, "user=ann+smith&password={{password}}", string(got["accounts/login"].Job().Data))
+	assert.Nil(t, got["accounts/avatar"].Body)
+	assert.Equal(t, &batch.Request{
+		Method:  "GET",
+		Url:     "{{base_url}}/health",
+		Headers: batch.Headers{"Authorization: Bearer {{token}}"},
+	}, got["health"])
+
+	_, _, err = ImportPostman([]byte(`{"info": {"name": "old", "schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}}`))
+	assert.ErrorContains(t, err, "not a Postman Collection v2.1")
+	_, _, err = ImportPostman([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+// TestExportPostman checks that exported collections import back as they were, minus socket requests.
+func TestExportPostman(t *testing.T) {
+	c := testCollection(t)
+	c.Description = "Accounts"
+	c.Vars = vars.Vars{"base_url": "https://api.example.com"}
+	_, err := c.Put([]string{"accounts", "create"}, &batch.Request{Method: "POST", Url: "{{base_url}}/users", Headers: batch.Headers{"Accept: application/json"}, Body: json.RawMessage(`{"name":"Ann"}`), ExpectStatus: batch.Status{"201"}})
+	assert.NoError(t, err)
+	_, err = c.Put([]string{"accounts", "rename"}, &batch.Request{Method: "PATCH", Url: "{{base_url}}/users/1", Headers: batch.Headers{"Content-Type: text/plain"}, Body: json.RawMessage(`"Anne"`)})
+	assert.NoError(t, err)
+	_, err = c.Put([]string{"accounts", "delete"}, &batch.Request{Method: "DELETE", Url: "{{base_url}}/users/1", ExpectStatus: batch.Status{"2xx", "404"}})
+	assert.NoError(t, err)
+	_, err = c.Put([]string{"docker"}, &batch.Request{Method: "GET", Url: "http:/images/json", Socket: "/var/run/docker.sock"})
+	assert.NoError(t, err)
+
+	b, warnings, err := c.ExportPostman()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docker: socket requests can't be sent by Postman, left out"}, warnings)
+	assert.Contains(t, string(b), `"schema": "`+PostmanSchema+`"`)
+	assert.Contains(t, string(b), `pm.response.to.have.status(201);`)
+	assert.Contains(t, string(b), `pm.expect(String(pm.response.code)).to.match(/^(2..|404)$/);`)
+
+	imported, warnings, err := ImportPostman(b)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.NoError(t, c.Remove([]string{"docker"}))
+	// bodies come back indented
+	create := imported.Find([]string{"accounts", "create"}).Request
+	assert.JSONEq(t, `{"name":"Ann"}`, string(create.Body))
+	create.Body = json.RawMessage(`{"name":"Ann"}`)
+	assert.Equal(t, c, imported)
+}
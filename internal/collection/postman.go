@@ -0,0 +1,625 @@
+package collection
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/batch"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// PostmanSchema is the schema of the Postman collections exported, and the version of those imported.
+	PostmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+	// rawContentTypes maps the languages of Postman raw bodies onto the Content-Type Postman sends them with.
+	rawContentTypes = map[string]string{
+		"text":       "text/plain",
+		"json":       "application/json",
+		"xml":        "application/xml",
+		"html":       "text/html",
+		"javascript": "application/javascript",
+	}
+	// statusTestRe matches the status assertions of Postman test scripts, which are imported as expect_status:
+	// of a single code, or of codes and classes as exported for lists of statuses.
+	statusTestRe = regexp.MustCompile(`pm\.response\.to\.have\.status\(\s*(\d{3})\s*\)|pm\.expect\(String\(pm\.response\.code\)\)\.to\.match\(/\^\(([0-9.|]+)\)\$/\)`)
+	// testWrapperRe matches the lines of test scripts wrapping status assertions, or carrying no statement.
+	testWrapperRe = regexp.MustCompile(`^\s*(pm\.test\(.*function\s*\(\)\s*\{|\}\);?|//.*)?\s*$`)
+	// refRe matches a {{name}} reference, which is kept as is when form bodies are encoded.
+	refRe = regexp.MustCompile(`{{[^{}]*}}`)
+)
+
+// postmanCollection is a Postman Collection v2.1 document, limited to the parts scour converts.
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []*postmanItem    `json:"item"`
+	Auth     *postmanAuth      `json:"auth,omitempty"`
+	Event    []postmanEvent    `json:"event,omitempty"`
+	Variable []postmanVariable `json:"variable,omitempty"`
+}
+
+type postmanInfo struct {
+	PostmanID   string          `json:"_postman_id,omitempty"`
+	Name        string          `json:"name"`
+	Description json.RawMessage `json:"description,omitempty"` // A string, or an object with content.
+	Schema      string          `json:"schema"`
+}
+
+// postmanItem is a request, when Request is set, or a folder otherwise.
+type postmanItem struct {
+	Name        string          `json:"name"`
+	Description json.RawMessage `json:"description,omitempty"`
+	Item        []*postmanItem  `json:"item,omitempty"`
+	Request     json.RawMessage `json:"request,omitempty"` // A postmanRequest, or a url.
+	Auth        *postmanAuth    `json:"auth,omitempty"`
+	Event       []postmanEvent  `json:"event,omitempty"`
+}
+
+type postmanRequest struct {
+	Method      string          `json:"method,omitempty"`
+	Header      []postmanKV     `json:"header,omitempty"`
+	Body        *postmanBody    `json:"body,omitempty"`
+	URL         json.RawMessage `json:"url,omitempty"` // A string, or a postmanURL.
+	Auth        *postmanAuth    `json:"auth,omitempty"`
+	Description json.RawMessage `json:"description,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string          `json:"raw"`
+	Protocol string          `json:"protocol"`
+	Host     json.RawMessage `json:"host"` // A string, or a list of labels.
+	Port     string          `json:"port"`
+	Path     json.RawMessage `json:"path"` // A string, or a list of segments.
+	Query    []postmanKV     `json:"query"`
+}
+
+type postmanKV struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Type     string `json:"type,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string          `json:"mode"`
+	Raw        string          `json:"raw,omitempty"`
+	URLEncoded []postmanKV     `json:"urlencoded,omitempty"`
+	FormData   []postmanKV     `json:"formdata,omitempty"`
+	GraphQL    *postmanGraphQL `json:"graphql,omitempty"`
+	Options    *postmanOptions `json:"options,omitempty"`
+	Disabled   bool            `json:"disabled,omitempty"`
+}
+
+type postmanGraphQL struct {
+	Query     string `json:"query"`
+	Variables string `json:"variables"`
+}
+
+type postmanOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+// postmanAuth holds the attributes of each type of authentication, as a list of key/value objects, or an
+// object in older exports.
+type postmanAuth struct {
+	Type   string          `json:"type"`
+	Bearer json.RawMessage `json:"bearer,omitempty"`
+	Basic  json.RawMessage `json:"basic,omitempty"`
+	APIKey json.RawMessage `json:"apikey,omitempty"`
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Type string          `json:"type,omitempty"`
+		Exec json.RawMessage `json:"exec"` // A string, or a list of lines.
+	} `json:"script"`
+}
+
+type postmanVariable struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Disabled bool        `json:"disabled,omitempty"`
+}
+
+// ImportPostman converts a Postman Collection v2.1 document into a collection. Requests and folders keep
+// their order; slashes in their names are replaced with dashes. Collection variables become the variables of
+// the collection, and status assertions of test scripts expect_status. What scour can't carry over, such as
+// form-data bodies, other scripts or unsupported methods, is left out and described in the warnings returned.
+func ImportPostman(b []byte) (*Collection, []string, error) {
+	var doc postmanCollection
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, nil, fmt.Errorf("malformed Postman collection: %w", err)
+	}
+	if !strings.Contains(doc.Info.Schema, "/v2.1.") && !strings.Contains(doc.Info.Schema, "/v2.0.") {
+		return nil, nil, fmt.Errorf("not a Postman Collection v2.1: schema %q", doc.Info.Schema)
+	}
+	im := &importer{}
+	c := &Collection{Name: doc.Info.Name, Description: text(doc.Info.Description)}
+	for _, v := range doc.Variable {
+		if v.Disabled || len(v.Key) == 0 {
+			continue
+		}
+		if c.Vars == nil {
+			c.Vars = map[string]string{}
+		}
+		c.Vars[v.Key] = ""
+		if v.Value != nil {
+			c.Vars[v.Key] = fmt.Sprint(v.Value)
+		}
+	}
+	if statements(doc.Event) {
+		im.warn("", "collection scripts are not run")
+	}
+	c.Items = im.items(doc.Item, "", doc.Auth)
+	return c, im.warnings, nil
+}
+
+// importer converts Postman items, collecting warnings about what it leaves out.
+type importer struct {
+	warnings []string
+}
+
+// warn records a warning about the item at path p.
+func (im *importer) warn(p, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if len(p) > 0 {
+		msg = p + ": " + msg
+	}
+	im.warnings = append(im.warnings, msg)
+}
+
+// items converts the Postman items below the path prefix, authenticated with auth unless they set their own.
+func (im *importer) items(pitems []*postmanItem, prefix string, auth *postmanAuth) []*Item {
+	var items []*Item
+	for i, pi := range pitems {
+		base := strings.TrimSpace(strings.ReplaceAll(pi.Name, Sep, "-"))
+		if len(base) == 0 {
+			base = "item " + strconv.Itoa(i+1)
+		}
+		// names are unique within a folder, so that paths name a single item
+		name := base
+		for n := 2; find(items, name) != nil; n++ {
+			name = fmt.Sprintf("%s (%d)", base, n)
+		}
+		p := name
+		if len(prefix) > 0 {
+			p = prefix + Sep + name
+		}
+		item := &Item{Name: name, Description: text(pi.Description)}
+		if len(pi.Request) == 0 {
+			inherited := auth
+			if pi.Auth != nil {
+				inherited = pi.Auth
+			}
+			if statements(pi.Event) {
+				im.warn(p, "folder scripts are not run")
+			}
+			item.Items = im.items(pi.Item, p, inherited)
+			items = append(items, item)
+			continue
+		}
+		req, ok := im.request(pi, p, auth)
+		if !ok {
+			continue
+		}
+		item.Request = req
+		items = append(items, item)
+	}
+	return items
+}
+
+// request converts the request of the Postman item at path p. It returns false when the request can't be
+// converted, after warning about it.
+func (im *importer) request(pi *postmanItem, p string, auth *postmanAuth) (*batch.Request, bool) {
+	var pr postmanRequest
+	if bytes.HasPrefix(pi.Request, []byte(`"`)) {
+		// a request may be a url alone
+		pr.URL = pi.Request
+	} else if err := json.Unmarshal(pi.Request, &pr); err != nil {
+		im.warn(p, "malformed request, left out: %s", err.Error())
+		return nil, false
+	}
+	req := &batch.Request{Method: strings.ToUpper(pr.Method), Url: postmanURLString(pr.URL)}
+	if len(req.Method) == 0 {
+		req.Method = http.MethodGet
+	}
+	if !slices.Contains(config.AllSupportedConn[1:], req.Method) {
+		im.warn(p, "method %s is not supported, left out", req.Method)
+		return nil, false
+	}
+	if len(req.Url) == 0 {
+		im.warn(p, "request has no url, left out")
+		return nil, false
+	}
+	for _, h := range pr.Header {
+		if !h.Disabled && len(h.Key) > 0 {
+			req.Headers = append(req.Headers, h.Key+": "+h.Value)
+		}
+	}
+	if pr.Auth != nil {
+		auth = pr.Auth
+	}
+	im.auth(req, auth, p)
+	im.body(req, pr.Body, p)
+
+	for _, e := range pi.Event {
+		lines := execLines(e.Script.Exec)
+		switch e.Listen {
+		case "test":
+			for _, line := range lines {
+				if m := statusTestRe.FindStringSubmatch(line); m != nil && len(req.ExpectStatus) == 0 {
+					status := m[1]
+					if len(status) == 0 {
+						status = strings.ReplaceAll(strings.ReplaceAll(m[2], "|", ","), ".", "x")
+					}
+					expected, err := batch.ParseStatus(status)
+					if err != nil {
+						im.warn(p, "test script is not run, and its status assertion can't be kept: %s", err.Error())
+						break
+					}
+					req.ExpectStatus = expected
+				} else if m == nil && !testWrapperRe.MatchString(line) {
+					im.warn(p, "test script is not run, only its status assertion is kept as expect_status")
+					break
+				}
+			}
+		default:
+			if statements([]postmanEvent{e}) {
+				im.warn(p, "%s script is not run", e.Listen)
+			}
+		}
+	}
+	if err := req.Validate(); err != nil {
+		im.warn(p, "%s, left out", err.Error())
+		return nil, false
+	}
+	return req, true
+}
+
+// auth adds the header, or query parameter, authenticating req with auth.
+func (im *importer) auth(req *batch.Request, auth *postmanAuth, p string) {
+	if auth == nil || auth.Type == "noauth" || hasHeader(req.Headers, "Authorization") {
+		return
+	}
+	switch auth.Type {
+	case "bearer":
+		req.Headers = append(req.Headers, "Authorization: Bearer "+attrs(auth.Bearer)["token"])
+	case "basic":
+		a := attrs(auth.Basic)
+		credentials := a["username"] + ":" + a["password"]
+		if refRe.MatchString(credentials) {
+			im.warn(p, "basic auth templated with variables is not supported, set the Authorization header instead")
+			return
+		}
+		req.Headers = append(req.Headers, "Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case "apikey":
+		a := attrs(auth.APIKey)
+		if a["in"] == "query" {
+			sep := "?"
+			if strings.Contains(req.Url, "?") {
+				sep = "&"
+			}
+			req.Url += sep + escapeForm(a["key"]) + "=" + escapeForm(a["value"])
+			return
+		}
+		req.Headers = append(req.Headers, a["key"]+": "+a["value"])
+	default:
+		im.warn(p, "%s auth is not supported", auth.Type)
+	}
+}
+
+// body sets the body of req from a Postman body, and its Content-Type unless req already has one.
+func (im *importer) body(req *batch.Request, body *postmanBody, p string) {
+	if body == nil || body.Disabled {
+		return
+	}
+	var data, contentType string
+	switch body.Mode {
+	case "raw":
+		// without a language, the body is sent as is, without a Content-Type
+		var language string
+		if body.Options != nil {
+			language = body.Options.Raw.Language
+		}
+		if trimmed := strings.TrimSpace(body.Raw); language == "json" && isJSONDocument(trimmed) {
+			// JSON documents are kept as JSON, and sent as application/json
+			var buf bytes.Buffer
+			_ = json.Compact(&buf, []byte(trimmed))
+			req.Body = buf.Bytes()
+			return
+		}
+		data, contentType = body.Raw, rawContentTypes[language]
+	case "urlencoded":
+		var pairs []string
+		for _, kv := range body.URLEncoded {
+			if !kv.Disabled {
+				pairs = append(pairs, escapeForm(kv.Key)+"="+escapeForm(kv.Value))
+			}
+		}
+		data, contentType = strings.Join(pairs, "&"), "application/x-www-form-urlencoded"
+	case "graphql":
+		if body.GraphQL == nil {
+			return
+		}
+		q := quote(body.GraphQL.Query)
+		variables := strings.TrimSpace(body.GraphQL.Variables)
+		if len(variables) == 0 {
+			variables = "{}"
+		}
+		data = `{"query":` + string(q) + `,"variables":` + variables + `}`
+		if json.Valid([]byte(data)) {
+			req.Body = json.RawMessage(data)
+			return
+		}
+		contentType = "application/json"
+	case "":
+		return
+	default:
+		im.warn(p, "%s bodies are not supported, the body is left out", body.Mode)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+	req.Body = quote(data)
+	if len(contentType) > 0 && !hasHeader(req.Headers, "Content-Type") {
+		req.Headers = append(req.Headers, "Content-Type: "+contentType)
+	}
+}
+
+// ExportPostman converts the collection into a Postman Collection v2.1 document. Bodies are exported raw,
+// and expect_status as a test script asserting the status. Socket requests, which Postman can't send, are
+// left out and described in the warnings returned.
+func (c *Collection) ExportPostman() ([]byte, []string, error) {
+	doc := postmanCollection{
+		Info: postmanInfo{PostmanID: uuid.NewString(), Name: c.Name, Description: description(c.Description), Schema: PostmanSchema},
+		Item: []*postmanItem{},
+	}
+	names := make([]string, 0, len(c.Vars))
+	for name := range c.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.Variable = append(doc.Variable, postmanVariable{Key: name, Value: c.Vars[name]})
+	}
+	var warnings []string
+	doc.Item = exportItems(c.Items, "", &warnings)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(doc); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), warnings, nil
+}
+
+// exportItems converts items below the path prefix into Postman items, appending warnings about the ones
+// left out.
+func exportItems(items []*Item, prefix string, warnings *[]string) []*postmanItem {
+	pitems := []*postmanItem{}
+	for _, item := range items {
+		p := item.Name
+		if len(prefix) > 0 {
+			p = prefix + Sep + item.Name
+		}
+		pi := &postmanItem{Name: item.Name, Description: description(item.Description)}
+		if item.IsFolder() {
+			pi.Item = exportItems(item.Items, p, warnings)
+			pitems = append(pitems, pi)
+			continue
+		}
+		req := item.Request
+		if len(req.Socket) > 0 {
+			*warnings = append(*warnings, p+": socket requests can't be sent by Postman, left out")
+			continue
+		}
+		pr := postmanRequest{Method: req.Method, Header: []postmanKV{}}
+		pr.URL = quote(req.Url)
+		for _, h := range req.Headers {
+			name, value, _ := strings.Cut(h, ":")
+			pr.Header = append(pr.Header, postmanKV{Key: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+		}
+		if len(req.Body) > 0 {
+			pr.Body = &postmanBody{Mode: "raw"}
+			if err := json.Unmarshal(req.Body, &pr.Body.Raw); err != nil {
+				// JSON values other than strings are sent as application/json
+				var indented bytes.Buffer
+				_ = json.Indent(&indented, req.Body, "", "    ")
+				pr.Body.Raw = indented.String()
+				pr.Body.Options = &postmanOptions{}
+				pr.Body.Options.Raw.Language = "json"
+			}
+		}
+		pi.Request, _ = json.Marshal(pr)
+		if len(req.ExpectStatus) > 0 {
+			e := postmanEvent{Listen: "test"}
+			e.Script.Type = "text/javascript"
+			assertion := fmt.Sprintf("pm.response.to.have.status(%d);", req.ExpectStatus.Code())
+			if req.ExpectStatus.Code() == 0 {
+				// lists and classes of statuses are matched as a pattern, 2xx becoming 2..
+				pattern := strings.ReplaceAll(strings.Join(req.ExpectStatus, "|"), "x", ".")
+				assertion = "pm.expect(String(pm.response.code)).to.match(/^(" + pattern + ")$/);"
+			}
+			e.Script.Exec, _ = json.Marshal([]string{
+				fmt.Sprintf("pm.test(\"Status code is %s\", function () {", strings.Join(req.ExpectStatus, " or ")),
+				"    " + assertion,
+				"});",
+			})
+			pi.Event = []postmanEvent{e}
+		}
+		pitems = append(pitems, pi)
+	}
+	return pitems
+}
+
+// postmanURLString returns the url of a Postman request, given as a string or an object.
+func postmanURLString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var u postmanURL
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return ""
+	}
+	if len(u.Raw) > 0 {
+		return u.Raw
+	}
+	host := strings.Join(strs(u.Host), ".")
+	if len(host) == 0 {
+		return ""
+	}
+	s = host
+	if len(u.Protocol) > 0 {
+		s = u.Protocol + "://" + host
+	}
+	if len(u.Port) > 0 {
+		s += ":" + u.Port
+	}
+	if path := strings.Join(strs(u.Path), "/"); len(path) > 0 {
+		s += "/" + strings.TrimPrefix(path, "/")
+	}
+	var query []string
+	for _, kv := range u.Query {
+		if !kv.Disabled {
+			query = append(query, kv.Key+"="+kv.Value)
+		}
+	}
+	if len(query) > 0 {
+		s += "?" + strings.Join(query, "&")
+	}
+	return s
+}
+
+// strs decodes a string, or a list of strings.
+func strs(raw json.RawMessage) []string {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil && len(s) > 0 {
+		return []string{s}
+	}
+	return nil
+}
+
+// attrs decodes the attributes of a type of authentication, from a list of key/value objects or an object.
+func attrs(raw json.RawMessage) map[string]string {
+	m := map[string]string{}
+	var list []struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, kv := range list {
+			if kv.Value != nil {
+				m[kv.Key] = fmt.Sprint(kv.Value)
+			}
+		}
+		return m
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		for k, v := range obj {
+			if v != nil {
+				m[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	return m
+}
+
+// execLines returns the lines of a script, given as a string or a list of lines.
+func execLines(raw json.RawMessage) []string {
+	var lines []string
+	for _, s := range strs(raw) {
+		lines = append(lines, strings.Split(s, "\n")...)
+	}
+	return lines
+}
+
+// statements reports whether any of events has a script with more than blank lines and comments.
+func statements(events []postmanEvent) bool {
+	for _, e := range events {
+		for _, line := range execLines(e.Script.Exec) {
+			if line = strings.TrimSpace(line); len(line) > 0 && !strings.HasPrefix(line, "//") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// text returns the text of a Postman description, given as a string or an object with content.
+func text(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var d struct {
+		Content string `json:"content"`
+	}
+	_ = json.Unmarshal(raw, &d)
+	return d.Content
+}
+
+// quote encodes s as a JSON string, without escaping HTML characters such as '&'.
+func quote(s string) json.RawMessage {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return bytes.TrimSpace(buf.Bytes())
+}
+
+// description encodes a Postman description, left out when empty.
+func description(s string) json.RawMessage {
+	if len(s) == 0 {
+		return nil
+	}
+	return quote(s)
+}
+
+// isJSONDocument reports whether s is a JSON object or array.
+func isJSONDocument(s string) bool {
+	return (strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")) && json.Valid([]byte(s))
+}
+
+// escapeForm escapes s for a form-encoded body or query, leaving {{name}} references as they are so that
+// they are still templated.
+func escapeForm(s string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range refRe.FindAllStringIndex(s, -1) {
+		sb.WriteString(url.QueryEscape(s[last:loc[0]]))
+		sb.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(url.QueryEscape(s[last:]))
+	return sb.String()
+}
+
+// hasHeader reports whether headers hold one named name.
+func hasHeader(headers []string, name string) bool {
+	for _, h := range headers {
+		if k, _, _ := strings.Cut(h, ":"); strings.EqualFold(strings.TrimSpace(k), name) {
+			return true
+		}
+	}
+	return false
+}
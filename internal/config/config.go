@@ -2,9 +2,12 @@ package config
 
 import (
 	"fmt"
+	"github.com/dark-enstein/scour/internal/charset"
 	"github.com/fatih/color"
 	"golang.org/x/exp/slices"
 	"net/http"
+	"strings"
+	"time"
 )
 
 const (
@@ -13,28 +16,29 @@ const (
 )
 
 var (
-	HTTPVer          = "1.1"
-	HTTPSVer         = "1.1"
-	HTTP             = "http"
-	HTTPS            = "https"
-	MethodSocket     = "SOCKET"
-	MethodAbsSocket  = "ABSSOCKET"
-	AllSupportedConn = []string{MethodSocket, http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodPatch}
-	Help             = `
-    Usage:
-	scour [flags] <url>
-
-	Flags:	
-	--verbose or -v: Enable verbose mode.
-	-X: Specify the request method (GET, POST, etc.).
-	-d: Pass request data.
-	-H: Custom request headers.
-	--unix-socket or -aus: Use an Unix domain socket.
-	--abstract-unix-socket or -aus: Use an abstract Unix domain socket.
-
-	Example:
-    scour -v -X GET https://example.com
-`
+	HTTPVer              = "1.1"
+	HTTPSVer             = "1.1"
+	HTTP                 = "http"
+	HTTPS                = "https"
+	MethodSocket         = "SOCKET"
+	MethodAbsSocket      = "ABSSOCKET"
+	AllSupportedConn     = []string{MethodSocket, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodPatch}
+	FormatJSON           = "json"
+	FormatNDJSON         = "ndjson"
+	AllOutputFormats     = []string{FormatJSON, FormatNDJSON}
+	ExportCurl           = "curl"
+	ExportGo             = "go"
+	ExportPythonRequests = "python-requests"
+	ExportJSFetch        = "js-fetch"
+	ExportHTTPie         = "httpie"
+	AllExportTargets     = []string{ExportCurl, ExportGo, ExportPythonRequests, ExportJSFetch, ExportHTTPie}
+	// Examples are shown in the help of the default command.
+	Examples = `  scour -v -X GET https://example.com
+  scour -Z -o a.json -o b.json https://example.com/a https://example.com/b
+  scour -o "page_#1.json" "https://example.com/items?page=[1-50]"
+  scour --jq '.users[] | {name, email}' https://example.com/users
+  scour -I https://example.com
+  scour socket /var/run/docker.sock http:/images/json`
 )
 
 // Flags struct holds the flag values passed in via the commandline to Scour.
@@ -45,14 +49,80 @@ type Flags struct {
 	Method string
 	// Data denotes the payload to be sent to the server parsed via command line
 	Data string
-	// Headers denotes the header information to be sent to the server
-	Headers string
+	// Headers denotes the header information to be sent to the server, each in "Name: value" form
+	Headers []string
 	// UnixSocket flag sets scour into unixsocket mode
 	UnixSocket bool
 	// InteractiveMode opens scour console where requests can be sent and received interactively
 	InteractiveMode bool
 	// SocketLoc saves the path to the socket to be created
 	SocketLoc string
+	// Outputs holds the files response bodies are written to, paired in order with the urls passed in
+	Outputs []string
+	// Parallel runs the transfers for every url passed in concurrently instead of one after the other
+	Parallel bool
+	// ParallelMax caps the number of transfers running at once in parallel mode
+	ParallelMax int
+	// GlobOff turns off expansion of {sets} and [ranges] in urls
+	GlobOff bool
+	// Fail treats HTTP responses with status >= 400 as failed transfers, and suppresses their body
+	Fail bool
+	// FailWithBody treats HTTP responses with status >= 400 as failed transfers, but still outputs their body
+	FailWithBody bool
+	// OutputFormat switches stdout to a machine-readable record per transfer. Empty means plain output
+	OutputFormat string
+	// Banner prints the Scour ASCII banner to stderr before any transfer
+	Banner bool
+	// RawOutput turns off pretty-printing of response bodies written to a terminal
+	RawOutput bool
+	// SortKeys sorts the keys of JSON objects when pretty-printing response bodies
+	SortKeys bool
+	// Jq filters JSON response bodies through a jq expression before they are output
+	Jq string
+	// JSONPath filters JSON response bodies through a JSONPath expression before they are output
+	JSONPath string
+	// Charset overrides the detected charset of response bodies decoded to UTF-8 for stdout
+	Charset string
+	// Include prefixes the response body with the status line and headers
+	Include bool
+	// Head sends a HEAD request and outputs only the status line and headers
+	Head bool
+	// DumpHeader holds the file the status line and headers of every response are written to
+	DumpHeader string
+	// Trace holds the file a hex dump of every byte sent and received is written to
+	Trace string
+	// TraceASCII holds the file an ASCII dump of every byte sent and received is written to
+	TraceASCII string
+	// TraceTime prefixes every trace line with a timestamp
+	TraceTime bool
+	// ConfigFile holds the config file read instead of ~/.scourrc
+	ConfigFile string
+	// NoConfig ignores the config file and the SCOUR_* environment variables
+	NoConfig bool
+	// Export renders every request as a code snippet for this target instead of sending it
+	Export string
+	// Env names the environment requests are templated with, instead of the one set with scour env use
+	Env string
+	// Vars holds variables requests are templated with, in name=value form. They override the environment
+	Vars []string
+	// ExpectStatus lists the statuses responses must have, comma-separated, as codes or classes such as 2xx
+	ExpectStatus string
+	// ExpectHeaders lists headers responses must have, as "Name" or "Name: value"
+	ExpectHeaders []string
+	// ExpectBody lists strings response bodies must contain
+	ExpectBody []string
+	// ExpectJSON lists jq expressions JSON response bodies must make true
+	ExpectJSON []string
+	// ExpectTimeUnder is the time transfers must complete in. Zero doesn't check it
+	ExpectTimeUnder time.Duration
+	// JUnit holds the file a JUnit XML report of the transfers and their expectations is written to
+	JUnit string
+	// NoHistory doesn't record the transfers of the invocation in the history
+	NoHistory bool
+	// HistoryMax is the number of transfers kept in the history. Zero keeps every one
+	HistoryMax int
+	// HistoryMaxAge is how long transfers are kept in the history. Zero keeps them forever
+	HistoryMaxAge time.Duration
 }
 
 // NewFlags is a consuructor function for Flags
@@ -62,17 +132,80 @@ func NewFlags() *Flags {
 
 // ValidateAll implements validation for Flags values
 func (f *Flags) ValidateAll() error {
-	// TODO: ensure flags who shouldn't be used together aren't used together
 	if !slices.Contains(AllSupportedConn, f.Method) {
-		return fmt.Errorf("connection type \"%s\" passed is not supported. please pass in a supported type: GET, DELETE, PUT, POST. Use --unix-socket or --abstract-unix-socket flags for socket connection", f.Method)
+		return fmt.Errorf("connection type \"%s\" passed is not supported. please pass in a supported type: %s. Use the --unix-socket flag or the socket command for socket connection", f.Method, strings.Join(AllSupportedConn[1:], ", "))
+	}
+	for _, ex := range f.exclusive() {
+		if ex.set {
+			return fmt.Errorf("%s and %s can't be used together", ex.a, ex.b)
+		}
+	}
+	if f.Head {
+		f.Method, f.Include = http.MethodHead, true
+	}
+	if len(f.OutputFormat) > 0 && !slices.Contains(AllOutputFormats, f.OutputFormat) {
+		return fmt.Errorf("output format \"%s\" passed is not supported. please pass in a supported format: %s", f.OutputFormat, strings.Join(AllOutputFormats, ", "))
+	}
+	if len(f.Export) > 0 && !slices.Contains(AllExportTargets, f.Export) {
+		return fmt.Errorf("export target \"%s\" is not supported. please pass in a supported target: %s", f.Export, strings.Join(AllExportTargets, ", "))
+	}
+	if f.TraceTime && len(f.Trace) == 0 && len(f.TraceASCII) == 0 {
+		return fmt.Errorf("--trace-time needs --trace or --trace-ascii")
+	}
+	if len(f.Charset) > 0 {
+		if _, err := charset.Lookup(f.Charset); err != nil {
+			return err
+		}
+	}
+	if f.ParallelMax < 1 {
+		return fmt.Errorf("--parallel-max must be at least 1, got %d", f.ParallelMax)
+	}
+	if f.HistoryMax < 0 || f.HistoryMaxAge < 0 {
+		return fmt.Errorf("--history-max and --history-max-age can't be negative")
 	}
 	if f.UnixSocket {
-		color.Green("Socket mode enabled")
+		if f.Verbose {
+			color.Green("Socket mode enabled")
+		}
 		f.Method = MethodSocket
 	}
 	return nil
 }
 
+// exclusion is a pair of flags that can't be used together.
+type exclusion struct {
+	a, b string // Names of the flags, as shown to the user.
+	set  bool   // Whether both flags are set.
+}
+
+// exclusive lists every pair of flags that can't be used together.
+func (f *Flags) exclusive() []exclusion {
+	return []exclusion{
+		{"--fail", "--fail-with-body", f.Fail && f.FailWithBody},
+		{"--jq", "--json-path", len(f.Jq) > 0 && len(f.JSONPath) > 0},
+		{"--trace", "--trace-ascii", len(f.Trace) > 0 && len(f.TraceASCII) > 0},
+		{"--head", "--data", f.Head && len(f.Data) > 0},
+		{"--head", "--request " + f.Method, f.Head && f.Method != http.MethodGet && f.Method != http.MethodHead},
+		{"--head", "--unix-socket", f.Head && f.UnixSocket},
+		{"--unix-socket", "--request " + f.Method, f.UnixSocket && f.Method != http.MethodGet && f.Method != MethodSocket},
+		{"--unix-socket", "--parallel", f.UnixSocket && f.Parallel},
+		{"--it", "--parallel", f.InteractiveMode && f.Parallel},
+		{"--it", "--output-format", f.InteractiveMode && len(f.OutputFormat) > 0},
+		{"--include", "--output-format", f.Include && !f.Head && len(f.OutputFormat) > 0},
+		{"--dump-header -", "--output-format", f.DumpHeader == "-" && len(f.OutputFormat) > 0},
+		{"--export", "--unix-socket", len(f.Export) > 0 && f.UnixSocket},
+		{"--export", "--output-format", len(f.Export) > 0 && len(f.OutputFormat) > 0},
+		{"--export", "--expect-* or --junit", len(f.Export) > 0 && f.Expecting()},
+		{"--it", "--expect-* or --junit", f.InteractiveMode && f.Expecting()},
+	}
+}
+
+// Expecting reports whether responses are checked against --expect-* flags, or reported with --junit.
+func (f *Flags) Expecting() bool {
+	return len(f.ExpectStatus) > 0 || len(f.ExpectHeaders) > 0 || len(f.ExpectBody) > 0 || len(f.ExpectJSON) > 0 ||
+		f.ExpectTimeUnder != 0 || len(f.JUnit) > 0
+}
+
 // Resolve resolves the mode of the current request
 func (f *Flags) Resolve() int {
 	if f.UnixSocket {
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+var (
+	// testExclusive holds combinations of flags ValidateAll rejects.
+	testExclusive = []Flags{
+		{Method: http.MethodGet, ParallelMax: 1, Fail: true, FailWithBody: true},
+		{Method: http.MethodGet, ParallelMax: 1, Jq: ".", JSONPath: "$"},
+		{Method: http.MethodGet, ParallelMax: 1, Trace: "-", TraceASCII: "-"},
+		{Method: http.MethodGet, ParallelMax: 1, Head: true, Data: "x"},
+		{Method: http.MethodPost, ParallelMax: 1, Head: true},
+		{Method: http.MethodGet, ParallelMax: 1, Head: true, UnixSocket: true},
+		{Method: http.MethodPost, ParallelMax: 1, UnixSocket: true},
+		{Method: http.MethodGet, ParallelMax: 1, UnixSocket: true, Parallel: true},
+		{Method: http.MethodGet, ParallelMax: 1, InteractiveMode: true, OutputFormat: FormatJSON},
+		{Method: http.MethodGet, ParallelMax: 1, Include: true, OutputFormat: FormatNDJSON},
+		{Method: http.MethodGet, ParallelMax: 1, DumpHeader: "-", OutputFormat: FormatJSON},
+	}
+)
+
+// TestValidateAll_Exclusive checks that flags which can't be used together are rejected.
+func TestValidateAll_Exclusive(t *testing.T) {
+	for _, f := range testExclusive {
+		assert.Error(t, f.ValidateAll(), "%+v", f)
+	}
+}
+
+// TestValidateAll checks the flag values ValidateAll derives.
+func TestValidateAll(t *testing.T) {
+	f := Flags{Method: http.MethodGet, ParallelMax: 1, Head: true, OutputFormat: FormatJSON}
+	assert.NoError(t, f.ValidateAll())
+	assert.Equal(t, http.MethodHead, f.Method)
+	assert.True(t, f.Include)
+
+	f = Flags{Method: http.MethodGet, ParallelMax: 1, UnixSocket: true}
+	assert.NoError(t, f.ValidateAll())
+	assert.Equal(t, MethodSocket, f.Method)
+
+	f = Flags{Method: "BREW", ParallelMax: 1}
+	assert.Error(t, f.ValidateAll())
+	f = Flags{Method: http.MethodGet, ParallelMax: 0}
+	assert.Error(t, f.ValidateAll())
+	f = Flags{Method: http.MethodGet, ParallelMax: 1, TraceTime: true}
+	assert.Error(t, f.ValidateAll())
+	f = Flags{Method: http.MethodGet, ParallelMax: 1, HistoryMax: -1}
+	assert.Error(t, f.ValidateAll())
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/exp/slices"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// RCName is the name of the config file read from the home directory of the user.
+	RCName = ".scourrc"
+	// EnvPrefix prefixes the environment variables overriding flags, e.g. SCOUR_VERBOSE for --verbose.
+	EnvPrefix = "SCOUR_"
+	// HostOptions lists the options that can be set in a host section, as they apply per request.
+	HostOptions = []string{"header", "request"}
+)
+
+// Option is a single option set in a config file: the long name of a flag, and its value.
+type Option struct {
+	Name  string
+	Value string
+	// Set reports whether a value was given. Boolean options may be listed without one.
+	Set  bool
+	Line int
+}
+
+// HostSection holds the options that only apply to requests sent to hosts matching Pattern, e.g. *.internal.corp.
+type HostSection struct {
+	Pattern string
+	Options []Option
+}
+
+// RC holds the options read from a config file. Options before the first [host] section apply to every request.
+type RC struct {
+	Path   string
+	Global []Option
+	Hosts  []HostSection
+}
+
+// DefaultRCPath returns the path of the config file read when none is passed in: ~/.scourrc.
+func DefaultRCPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locating %s: %w", RCName, err)
+	}
+	return filepath.Join(home, RCName), nil
+}
+
+// EnvName returns the name of the environment variable overriding a flag.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// ReadRC reads the config file at p.
+func ReadRC(p string) (*RC, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRC(f, p)
+}
+
+// ParseRC parses a config file in the style of curl's: one option per line, named after the long flag
+// with or without its leading dashes, followed by its value after whitespace, '=' or ':'. Values may be
+// double-quoted, with \", \\, \t, \n and \r escapes. Lines starting with # are comments, and a [pattern]
+// line starts a section whose options only apply to requests sent to hosts matching the pattern.
+func ParseRC(r io.Reader, name string) (*RC, error) {
+	rc := &RC{Path: name}
+	var section *HostSection
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			pattern := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"))
+			if !strings.HasSuffix(line, "]") || len(pattern) == 0 {
+				return nil, fmt.Errorf("%s:%d: malformed host section %s", name, n, line)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%s:%d: malformed host pattern %s: %w", name, n, pattern, err)
+			}
+			rc.Hosts = append(rc.Hosts, HostSection{Pattern: pattern})
+			section = &rc.Hosts[len(rc.Hosts)-1]
+			continue
+		}
+		opt, err := parseOption(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+		opt.Line = n
+		if section == nil {
+			rc.Global = append(rc.Global, opt)
+			continue
+		}
+		if !slices.Contains(HostOptions, opt.Name) {
+			return nil, fmt.Errorf("%s:%d: option %s can't be set for a host. Host sections support: %s", name, n, opt.Name, strings.Join(HostOptions, ", "))
+		}
+		section.Options = append(section.Options, opt)
+	}
+	return rc, scanner.Err()
+}
+
+// ForHost returns the options of every section matching host, in the order they appear in the file.
+func (rc *RC) ForHost(host string) []Option {
+	var opts []Option
+	for _, s := range rc.Hosts {
+		if ok, _ := path.Match(s.Pattern, host); ok {
+			opts = append(opts, s.Options...)
+		}
+	}
+	return opts
+}
+
+// parseOption parses a single option line.
+func parseOption(line string) (Option, error) {
+	line = strings.TrimLeft(line, "-")
+	end := strings.IndexAny(line, " \t=:")
+	if end < 0 {
+		return Option{Name: line}, nil
+	}
+	opt := Option{Name: line[:end]}
+	rest := strings.TrimSpace(line[end:])
+	if len(rest) > 0 && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimSpace(rest[1:])
+	}
+	if len(rest) == 0 {
+		return opt, nil
+	}
+	opt.Set = true
+	if rest[0] != '"' {
+		opt.Value = rest
+		return opt, nil
+	}
+	var b strings.Builder
+	for i := 1; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == '"':
+			if tail := strings.TrimSpace(rest[i+1:]); len(tail) > 0 && !strings.HasPrefix(tail, "#") {
+				return opt, fmt.Errorf("unexpected %s after the value of %s", tail, opt.Name)
+			}
+			opt.Value = b.String()
+			return opt, nil
+		case c == '\\' && i+1 < len(rest):
+			i++
+			switch rest[i] {
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(rest[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return opt, fmt.Errorf("unterminated quote in the value of %s", opt.Name)
+}
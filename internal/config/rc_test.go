@@ -0,0 +1,71 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var (
+	// testRC is a config file using every supported syntax.
+	testRC = `# defaults
+header = "Accept: application/json"
+--fail
+output-format: ndjson
+verbose false
+data = "tab\there \"quoted\""
+
+[*.internal.corp]
+header: X-Corp: 1
+request POST
+[api.example.com]
+header = "Authorization: Bearer xyz" # token
+`
+	// testBadRC maps malformed config files to the error they are expected to fail with.
+	testBadRC = map[string]string{
+		"[*.corp\nheader = a":   "malformed host section",
+		"[]":                    "malformed host section",
+		"[a[]\n":                "malformed host pattern",
+		"data = \"unterminated": "unterminated quote",
+		"data = \"a\" b":        "unexpected b",
+		"[x]\nfail":             "can't be set for a host",
+	}
+)
+
+// TestParseRC checks parsing of options and host sections.
+func TestParseRC(t *testing.T) {
+	rc, err := ParseRC(strings.NewReader(testRC), "test.rc")
+	assert.NoError(t, err)
+	assert.Equal(t, []Option{
+		{Name: "header", Value: "Accept: application/json", Set: true, Line: 2},
+		{Name: "fail", Line: 3},
+		{Name: "output-format", Value: "ndjson", Set: true, Line: 4},
+		{Name: "verbose", Value: "false", Set: true, Line: 5},
+		{Name: "data", Value: "tab\there \"quoted\"", Set: true, Line: 6},
+	}, rc.Global)
+	assert.Len(t, rc.Hosts, 2)
+
+	assert.Equal(t, []Option{
+		{Name: "header", Value: "X-Corp: 1", Set: true, Line: 9},
+		{Name: "request", Value: "POST", Set: true, Line: 10},
+	}, rc.ForHost("git.internal.corp"))
+	assert.Equal(t, "Authorization: Bearer xyz", rc.ForHost("api.example.com")[0].Value)
+	assert.Empty(t, rc.ForHost("example.com"))
+}
+
+// TestParseRC_Errors checks that malformed config files are rejected, pointing at the offending line.
+func TestParseRC_Errors(t *testing.T) {
+	for src, msg := range testBadRC {
+		_, err := ParseRC(strings.NewReader(src), "bad.rc")
+		if assert.Error(t, err, src) {
+			assert.Contains(t, err.Error(), msg, src)
+			assert.Contains(t, err.Error(), "bad.rc:", src)
+		}
+	}
+}
+
+// TestEnvName checks the environment variable names flags map to.
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "SCOUR_OUTPUT_FORMAT", EnvName("output-format"))
+	assert.Equal(t, "SCOUR_VERBOSE", EnvName("verbose"))
+}
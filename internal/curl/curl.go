@@ -0,0 +1,344 @@
+package curl
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"golang.org/x/exp/slices"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	// FormContentType is the content type curl sends --data with, unless a Content-Type header is passed in.
+	FormContentType = "application/x-www-form-urlencoded"
+	// JSONContentType is the content type and accepted type curl sends --json with.
+	JSONContentType = "application/json"
+	// options maps the curl options scour supports onto the config.Flags they set.
+	options = map[string]option{}
+	// ignored lists curl options without effect in scour, as it already behaves that way: it follows
+	// redirects, decompresses responses, and doesn't show a progress meter.
+	ignored = []string{"-s", "--silent", "-S", "--show-error", "-L", "--location", "--compressed", "-#",
+		"--progress-bar", "--no-progress-meter", "-N", "--no-buffer", "--http1.1", "-q", "--disable"}
+	// unsupportedWithArg lists curl options scour has no equivalent for that take an argument, so that
+	// the argument isn't mistaken for a url.
+	unsupportedWithArg = []string{"-x", "--proxy", "-U", "--proxy-user", "--noproxy", "--preproxy", "--socks5",
+		"--socks5-hostname", "--cacert", "--capath", "-E", "--cert", "--key", "--cert-type", "--key-type",
+		"--pinnedpubkey", "--ciphers", "--tls-max", "-m", "--max-time", "--connect-timeout", "--retry",
+		"--retry-delay", "--retry-max-time", "--max-redirs", "--resolve", "--connect-to", "--limit-rate",
+		"-Y", "--speed-limit", "-y", "--speed-time", "-c", "--cookie-jar", "--unix-socket",
+		"--abstract-unix-socket", "-F", "--form", "--form-string", "-T", "--upload-file", "-r", "--range",
+		"-K", "--config", "-w", "--write-out", "--interface", "--dns-servers", "--request-target",
+		"--aws-sigv4", "--netrc-file", "--local-port", "-C", "--continue-at", "-z", "--time-cond"}
+)
+
+// option is a curl option scour supports.
+type option struct {
+	arg   bool                             // Whether the option takes an argument.
+	apply func(c *Command, v string) error // Sets the option on the command.
+}
+
+// Command is a curl command line mapped onto scour.
+type Command struct {
+	// Flags holds the scour flags equivalent to the curl options.
+	Flags config.Flags
+	// Urls holds the urls requested.
+	Urls []string
+	// Unsupported lists the curl options scour has no equivalent for. They are left out of Flags.
+	Unsupported []string
+	// data holds every --data value, joined with & once parsing is done.
+	data []string
+	// get sends the data in the query string instead of the body, like curl's -G.
+	get bool
+}
+
+func init() {
+	register(options, false, func(c *Command, _ string) error { c.Flags.Verbose = true; return nil }, "-v", "--verbose")
+	register(options, true, func(c *Command, v string) error { c.Flags.Method = strings.ToUpper(v); return nil }, "-X", "--request")
+	register(options, true, func(c *Command, v string) error { c.Flags.Headers = append(c.Flags.Headers, v); return nil }, "-H", "--header")
+	register(options, true, func(c *Command, v string) error { c.data = append(c.data, v); return nil }, "-d", "--data", "--data-ascii", "--data-binary")
+	register(options, true, func(c *Command, v string) error {
+		if strings.HasPrefix(v, "@") {
+			return c.unsupported("--data-raw " + v)
+		}
+		c.data = append(c.data, v)
+		return nil
+	}, "--data-raw")
+	register(options, true, func(c *Command, v string) error {
+		if name, content, ok := strings.Cut(v, "="); ok && !strings.HasPrefix(content, "@") {
+			c.data = append(c.data, name+"="+url.QueryEscape(content))
+			return nil
+		}
+		if strings.ContainsAny(v, "=@") {
+			return c.unsupported("--data-urlencode " + v)
+		}
+		c.data = append(c.data, url.QueryEscape(v))
+		return nil
+	}, "--data-urlencode")
+	register(options, true, func(c *Command, v string) error {
+		c.data = append(c.data, v)
+		c.Flags.Headers = append(c.Flags.Headers, "Content-Type: "+JSONContentType, "Accept: "+JSONContentType)
+		return nil
+	}, "--json")
+	register(options, false, func(c *Command, _ string) error { c.get = true; return nil }, "-G", "--get")
+	register(options, true, func(c *Command, v string) error { c.Urls = append(c.Urls, v); return nil }, "--url")
+	register(options, true, func(c *Command, v string) error {
+		if !strings.Contains(v, ":") {
+			return c.unsupported("--user " + v + " (without a password)")
+		}
+		c.Flags.Headers = append(c.Flags.Headers, "Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte(v)))
+		return nil
+	}, "-u", "--user")
+	register(options, true, func(c *Command, v string) error {
+		c.Flags.Headers = append(c.Flags.Headers, "Authorization: Bearer "+v)
+		return nil
+	}, "--oauth2-bearer")
+	register(options, true, func(c *Command, v string) error {
+		c.Flags.Headers = append(c.Flags.Headers, "User-Agent: "+v)
+		return nil
+	}, "-A", "--user-agent")
+	register(options, true, func(c *Command, v string) error {
+		c.Flags.Headers = append(c.Flags.Headers, "Referer: "+strings.TrimSuffix(v, ";auto"))
+		return nil
+	}, "-e", "--referer")
+	register(options, true, func(c *Command, v string) error {
+		if !strings.Contains(v, "=") {
+			return c.unsupported("--cookie " + v + " (a cookie file)")
+		}
+		c.Flags.Headers = append(c.Flags.Headers, "Cookie: "+v)
+		return nil
+	}, "-b", "--cookie")
+	register(options, true, func(c *Command, v string) error { c.Flags.Outputs = append(c.Flags.Outputs, v); return nil }, "-o", "--output")
+	register(options, false, func(c *Command, _ string) error { c.Flags.Include = true; return nil }, "-i", "--include")
+	register(options, false, func(c *Command, _ string) error { c.Flags.Head = true; return nil }, "-I", "--head")
+	register(options, true, func(c *Command, v string) error { c.Flags.DumpHeader = v; return nil }, "-D", "--dump-header")
+	register(options, false, func(c *Command, _ string) error { c.Flags.Fail = true; return nil }, "-f", "--fail")
+	register(options, false, func(c *Command, _ string) error { c.Flags.FailWithBody = true; return nil }, "--fail-with-body")
+	register(options, false, func(c *Command, _ string) error { c.Flags.Parallel = true; return nil }, "-Z", "--parallel")
+	register(options, true, func(c *Command, v string) (err error) {
+		c.Flags.ParallelMax, err = strconv.Atoi(v)
+		return err
+	}, "--parallel-max")
+	register(options, false, func(c *Command, _ string) error { c.Flags.GlobOff = true; return nil }, "-g", "--globoff")
+	register(options, true, func(c *Command, v string) error { c.Flags.Trace = v; return nil }, "--trace")
+	register(options, true, func(c *Command, v string) error { c.Flags.TraceASCII = v; return nil }, "--trace-ascii")
+	register(options, false, func(c *Command, _ string) error { c.Flags.TraceTime = true; return nil }, "--trace-time")
+	for _, name := range ignored {
+		register(options, false, func(*Command, string) error { return nil }, name)
+	}
+	for _, name := range unsupportedWithArg {
+		name := name
+		register(options, true, func(c *Command, v string) error { return c.unsupported(name + " " + v) }, name)
+	}
+}
+
+// register adds the option with every name in names to opts.
+func register(opts map[string]option, arg bool, apply func(c *Command, v string) error, names ...string) {
+	for _, name := range names {
+		opts[name] = option{arg: arg, apply: apply}
+	}
+}
+
+// unsupported records an option scour has no equivalent for.
+func (c *Command) unsupported(opt string) error {
+	c.Unsupported = append(c.Unsupported, opt)
+	return nil
+}
+
+// Parse maps a curl command line, as split by Split, onto scour. A leading curl program name is skipped.
+// Options scour has no equivalent for are listed in Unsupported rather than failing the parse.
+func Parse(args []string) (*Command, error) {
+	if len(args) > 0 && (args[0] == "curl" || strings.HasSuffix(args[0], "/curl") || args[0] == "curl.exe") {
+		args = args[1:]
+	}
+	c := &Command{Flags: config.Flags{ParallelMax: transfer.DefaultParallelMax}}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			c.Urls = append(c.Urls, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(a, "--"):
+			opt, ok := options[a]
+			if !ok {
+				c.unsupported(a)
+				continue
+			}
+			var v string
+			if opt.arg {
+				if i+1 == len(args) {
+					return nil, fmt.Errorf("curl option %s needs an argument", a)
+				}
+				i++
+				v = args[i]
+			}
+			if err := opt.apply(c, v); err != nil {
+				return nil, fmt.Errorf("curl option %s: %w", a, err)
+			}
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			// short options can be combined, as in -sSL, and take their argument attached, as in -XPOST
+			for j := 1; j < len(a); j++ {
+				name := "-" + a[j:j+1]
+				opt, ok := options[name]
+				if !ok {
+					c.unsupported(name)
+					continue
+				}
+				var v string
+				if opt.arg {
+					if v = a[j+1:]; len(v) == 0 {
+						if i+1 == len(args) {
+							return nil, fmt.Errorf("curl option %s needs an argument", name)
+						}
+						i++
+						v = args[i]
+					}
+					j = len(a)
+				}
+				if err := opt.apply(c, v); err != nil {
+					return nil, fmt.Errorf("curl option %s: %w", name, err)
+				}
+			}
+		default:
+			c.Urls = append(c.Urls, a)
+		}
+	}
+	if len(c.Urls) == 0 {
+		return nil, fmt.Errorf("no url found in the curl command")
+	}
+	c.resolveData()
+	return c, nil
+}
+
+// resolveData works out the payload and method the way curl does: --data values are joined with &, and
+// sent as a form in a POST request, unless -G moves them to the query string of every url.
+func (c *Command) resolveData() {
+	data := strings.Join(c.data, "&")
+	switch {
+	case c.get:
+		if len(c.Flags.Method) == 0 {
+			c.Flags.Method = http.MethodGet
+		}
+		if len(data) == 0 {
+			break
+		}
+		for i, u := range c.Urls {
+			sep := "?"
+			if strings.Contains(u, "?") {
+				sep = "&"
+			}
+			c.Urls[i] = u + sep + data
+		}
+	case len(c.data) > 0:
+		c.Flags.Data = data
+		if len(c.Flags.Method) == 0 {
+			c.Flags.Method = http.MethodPost
+		}
+		if !c.hasHeader("Content-Type") {
+			c.Flags.Headers = append(c.Flags.Headers, "Content-Type: "+FormContentType)
+		}
+	}
+	if len(c.Flags.Method) == 0 {
+		c.Flags.Method = http.MethodGet
+	}
+}
+
+// hasHeader reports whether a header with name was passed in.
+func (c *Command) hasHeader(name string) bool {
+	for _, h := range c.Flags.Headers {
+		if k, _, _ := strings.Cut(h, ":"); strings.EqualFold(strings.TrimSpace(k), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the scour flags equivalent to the curl options onto f, which holds the defaults of the
+// invocation: flags the command doesn't set keep their value, and headers and outputs add up. The method
+// and data always come from the command, as they define the request.
+func (c *Command) Apply(f *config.Flags) {
+	cf := c.Flags
+	f.Method, f.Data = cf.Method, cf.Data
+	f.Headers = append(slices.Clip(f.Headers), cf.Headers...)
+	f.Outputs = append(slices.Clip(f.Outputs), cf.Outputs...)
+	for _, b := range []struct {
+		dst *bool
+		src bool
+	}{
+		{&f.Verbose, cf.Verbose}, {&f.Include, cf.Include}, {&f.Head, cf.Head}, {&f.Fail, cf.Fail},
+		{&f.FailWithBody, cf.FailWithBody}, {&f.Parallel, cf.Parallel}, {&f.GlobOff, cf.GlobOff}, {&f.TraceTime, cf.TraceTime},
+	} {
+		*b.dst = *b.dst || b.src
+	}
+	for _, s := range []struct {
+		dst *string
+		src string
+	}{
+		{&f.DumpHeader, cf.DumpHeader}, {&f.Trace, cf.Trace}, {&f.TraceASCII, cf.TraceASCII},
+	} {
+		if len(s.src) > 0 {
+			*s.dst = s.src
+		}
+	}
+	if cf.ParallelMax != transfer.DefaultParallelMax {
+		f.ParallelMax = cf.ParallelMax
+	}
+}
+
+// ScourArgs returns the arguments of the scour invocation equivalent to the command, minus the program name.
+func (c *Command) ScourArgs() []string {
+	f := c.Flags
+	var args []string
+	if f.Verbose {
+		args = append(args, "-v")
+	}
+	if f.Method != http.MethodGet && !f.Head {
+		args = append(args, "-X", f.Method)
+	}
+	for _, h := range f.Headers {
+		args = append(args, "-H", h)
+	}
+	if len(f.Data) > 0 {
+		args = append(args, "-d", f.Data)
+	}
+	if f.Head {
+		args = append(args, "-I")
+	}
+	if f.Include {
+		args = append(args, "-i")
+	}
+	if len(f.DumpHeader) > 0 {
+		args = append(args, "-D", f.DumpHeader)
+	}
+	for _, o := range f.Outputs {
+		args = append(args, "-o", o)
+	}
+	if f.Fail {
+		args = append(args, "-f")
+	}
+	if f.FailWithBody {
+		args = append(args, "--fail-with-body")
+	}
+	if f.Parallel {
+		args = append(args, "-Z")
+	}
+	if f.ParallelMax != transfer.DefaultParallelMax {
+		args = append(args, "--parallel-max", strconv.Itoa(f.ParallelMax))
+	}
+	if f.GlobOff {
+		args = append(args, "-g")
+	}
+	if len(f.Trace) > 0 {
+		args = append(args, "--trace", f.Trace)
+	}
+	if len(f.TraceASCII) > 0 {
+		args = append(args, "--trace-ascii", f.TraceASCII)
+	}
+	if f.TraceTime {
+		args = append(args, "--trace-time")
+	}
+	return append(args, c.Urls...)
+}
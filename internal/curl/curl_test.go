@@ -0,0 +1,88 @@
+package curl
+
+import (
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var (
+	// testParse maps curl command lines to the scour arguments they are expected to map onto.
+	testParse = map[string][]string{
+		`curl https://example.com`:                           {"https://example.com"},
+		`curl -sSL -XPUT -H 'A: b' https://example.com -d x`: {"-X", "PUT", "-H", "A: b", "-H", "Content-Type: " + FormContentType, "-d", "x", "https://example.com"},
+		`curl 'https://example.com/api' -H 'content-type: application/json' --data-raw '{"a":1}' --compressed`: {
+			"-X", "POST", "-H", "content-type: application/json", "-d", `{"a":1}`, "https://example.com/api"},
+		`curl -d a=1 -d b=2 https://example.com`:                  {"-X", "POST", "-H", "Content-Type: " + FormContentType, "-d", "a=1&b=2", "https://example.com"},
+		`curl -G --data-urlencode 'q=a b' https://example.com/s`:  {"https://example.com/s?q=a+b"},
+		`curl --json '{"a":1}' https://example.com`:               {"-X", "POST", "-H", "Content-Type: application/json", "-H", "Accept: application/json", "-d", `{"a":1}`, "https://example.com"},
+		`curl -u user:pass -A scour -b 'a=1' https://example.com`: {"-H", "Authorization: Basic dXNlcjpwYXNz", "-H", "User-Agent: scour", "-H", "Cookie: a=1", "https://example.com"},
+		`curl -fiI -o out.html -D - --url https://example.com`:    {"-I", "-i", "-D", "-", "-o", "out.html", "-f", "https://example.com"},
+		`curl -Z --parallel-max 4 -g 'https://example.com/[1-2]'`: {"-Z", "--parallel-max", "4", "-g", "https://example.com/[1-2]"},
+	}
+)
+
+// TestParse checks the mapping of curl options onto scour flags.
+func TestParse(t *testing.T) {
+	for line, expected := range testParse {
+		args, err := Split(line)
+		assert.NoError(t, err)
+		c, err := Parse(args)
+		if assert.NoError(t, err, line) {
+			assert.Equal(t, expected, c.ScourArgs(), line)
+			assert.Empty(t, c.Unsupported, line)
+		}
+	}
+}
+
+// TestParse_Unsupported checks that options without a scour equivalent are reported, along with their argument.
+func TestParse_Unsupported(t *testing.T) {
+	args, _ := Split(`curl -k --proxy http://proxy:3128 -x http://proxy:3128 --data-raw @file --http2 -u user https://example.com`)
+	c, err := Parse(args)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com"}, c.Urls)
+	assert.Equal(t, []string{"-k", "--proxy http://proxy:3128", "-x http://proxy:3128", "--data-raw @file", "--http2", "--user user (without a password)"}, c.Unsupported)
+
+	_, err = Parse([]string{"curl", "-v"})
+	assert.Error(t, err)
+	_, err = Parse([]string{"curl", "https://example.com", "-H"})
+	assert.Error(t, err)
+	_, err = Parse([]string{"curl", "--parallel-max", "many", "https://example.com"})
+	assert.Error(t, err)
+}
+
+// TestApply checks that the options of a curl command are set onto the flags of the invocation, and that the
+// flags it doesn't set, such as config file defaults, --env or the history flags, are kept.
+func TestApply(t *testing.T) {
+	f := &config.Flags{
+		Method:        http.MethodGet,
+		ParallelMax:   transfer.DefaultParallelMax,
+		Headers:       []string{"X-Team: a"},
+		Fail:          true,
+		Verbose:       true,
+		Env:           "staging",
+		NoHistory:     true,
+		HistoryMax:    5,
+		HistoryMaxAge: time.Hour,
+	}
+	args, _ := Split(`curl -XPUT -H 'A: b' -d x -o out.html --parallel-max 4 https://example.com`)
+	c, err := Parse(args)
+	assert.NoError(t, err)
+	c.Apply(f)
+	assert.Equal(t, &config.Flags{
+		Method:        http.MethodPut,
+		Data:          "x",
+		ParallelMax:   4,
+		Headers:       []string{"X-Team: a", "A: b", "Content-Type: " + FormContentType},
+		Outputs:       []string{"out.html"},
+		Fail:          true,
+		Verbose:       true,
+		Env:           "staging",
+		NoHistory:     true,
+		HistoryMax:    5,
+		HistoryMaxAge: time.Hour,
+	}, f)
+}
@@ -0,0 +1,161 @@
+package curl
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnterminated is returned for command lines ending inside a quote.
+	ErrUnterminated = errors.New("unterminated quote")
+)
+
+// Split tokenizes a POSIX shell command line into its arguments. It handles single quotes, double quotes,
+// ANSI-C $'...' quotes as emitted by browser devtools, backslash escapes and line continuations, and
+// newlines as argument separators. It doesn't expand variables or globs.
+func Split(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			inArg = true
+			if i+1 < len(line) {
+				i++
+				if line[i] == '\n' {
+					// line continuation
+					inArg = cur.Len() > 0
+					continue
+				}
+				cur.WriteByte(line[i])
+			}
+		case c == '\'':
+			inArg = true
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, ErrUnterminated
+			}
+			cur.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inArg = true
+			n, err := doubleQuoted(line[i+1:], &cur)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+		case c == '$' && i+1 < len(line) && line[i+1] == '\'':
+			inArg = true
+			n, err := ansiQuoted(line[i+2:], &cur)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+		default:
+			inArg = true
+			cur.WriteByte(c)
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// doubleQuoted writes the contents of a double-quoted string, starting after its opening quote, to b.
+// It returns the index of the closing quote.
+func doubleQuoted(s string, b *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return i, nil
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0:
+			i++
+			if s[i] != '\n' {
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return 0, ErrUnterminated
+}
+
+// ansiQuoted writes the contents of an ANSI-C $'...' string, starting after its opening quote, to b.
+// It returns the index of the closing quote.
+func ansiQuoted(s string, b *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\'' {
+			return i, nil
+		}
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch e := s[i]; e {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'x', 'u', 'U':
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+			end := i + 1
+			for end < len(s) && end < i+1+digits && isHex(s[end]) {
+				end++
+			}
+			v, err := strconv.ParseUint(s[i+1:end], 16, 32)
+			if err != nil {
+				b.WriteByte('\\')
+				b.WriteByte(e)
+				continue
+			}
+			if e == 'x' {
+				b.WriteByte(byte(v))
+			} else {
+				b.WriteRune(rune(v))
+			}
+			i = end - 1
+		default:
+			// \\, \' and \" stand for themselves, like any other escaped character
+			b.WriteByte(e)
+		}
+	}
+	return 0, ErrUnterminated
+}
+
+// isHex reports whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// Quote quotes s for a POSIX shell, leaving it as is when it holds no special characters.
+func Quote(s string) string {
+	if len(s) > 0 && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Join quotes every argument for a POSIX shell, and joins them into a command line.
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = Quote(a)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,51 @@
+package curl
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var (
+	// testSplit maps shell command lines to the arguments they are expected to split into.
+	testSplit = map[string][]string{
+		`curl https://example.com`:                      {"curl", "https://example.com"},
+		`curl -H 'Accept: */*'  -H "X-A: \"b\" \$HOME"`: {"curl", "-H", "Accept: */*", "-H", `X-A: "b" $HOME`},
+		"curl 'https://example.com' \\\n  -H 'A: b'":    {"curl", "https://example.com", "-H", "A: b"},
+		`curl --data-raw $'{"a":"it\'s\\n\x41é"}'`:      {"curl", "--data-raw", "{\"a\":\"it's\\nAé\"}"},
+		`curl -d a\ b -d ''`:                            {"curl", "-d", "a b", "-d", ""},
+		`curl "con"'cat'enated`:                         {"curl", "concatenated"},
+		`curl $'a\tb\nc'`:                               {"curl", "a\tb\nc"},
+		"curl\n-v":                                      {"curl", "-v"},
+	}
+	// testQuote maps arguments to their expected shell quoting.
+	testQuote = map[string]string{
+		"https://example.com/a?b=c": "'https://example.com/a?b=c'",
+		"plain-arg_1.0":             "plain-arg_1.0",
+		"it's":                      `'it'\''s'`,
+		"":                          "''",
+		"Accept: */*":               "'Accept: */*'",
+	}
+)
+
+// TestSplit checks tokenizing of quoted, escaped and continued command lines.
+func TestSplit(t *testing.T) {
+	for line, expected := range testSplit {
+		args, err := Split(line)
+		assert.NoError(t, err, line)
+		assert.Equal(t, expected, args, line)
+	}
+	for _, line := range []string{`curl 'open`, `curl "open`, `curl $'open`} {
+		_, err := Split(line)
+		assert.ErrorIs(t, err, ErrUnterminated, line)
+	}
+}
+
+// TestQuote checks that quoted arguments split back into themselves.
+func TestQuote(t *testing.T) {
+	for arg, expected := range testQuote {
+		assert.Equal(t, expected, Quote(arg), arg)
+		args, err := Split(Quote(arg))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{arg}, args, arg)
+	}
+}
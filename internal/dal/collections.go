@@ -0,0 +1,73 @@
+package dal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// CollectionsDir is the name of the directory collections are kept in, one <name>.json file per collection.
+	CollectionsDir = "collections"
+	// CollectionExt is the extension of collection files.
+	CollectionExt = ".json"
+	// ErrNoCollection is returned for collections without a file.
+	ErrNoCollection = errors.New("collection not found")
+)
+
+// CollectionPath returns the path of the file of the collection called name, creating the collections
+// directory if needed. The file itself may not exist. Collection names follow the rules of environment names.
+func CollectionPath(name string) (string, error) {
+	if !envNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid collection name %q: use letters, digits, '_', '.' and '-'", name)
+	}
+	dir, err := path(CollectionsDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating the collections directory: %w", err)
+	}
+	return filepath.Join(dir, name+CollectionExt), nil
+}
+
+// Collections returns the names of the collections that have a file, sorted.
+func Collections() ([]string, error) {
+	dir, err := path(CollectionsDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name := strings.TrimSuffix(e.Name(), CollectionExt); !e.IsDir() && name != e.Name() && envNameRe.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RemoveCollection deletes the file of the collection called name.
+func RemoveCollection(name string) error {
+	p, err := CollectionPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNoCollection, name)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
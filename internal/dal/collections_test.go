@@ -0,0 +1,35 @@
+package dal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// TestCollections checks listing and removing collections.
+func TestCollections(t *testing.T) {
+	t.Setenv(EnvHome, t.TempDir())
+	names, err := Collections()
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	for _, name := range []string{"users-api", "billing"} {
+		p, err := CollectionPath(name)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(p, []byte("{}"), 0o600))
+	}
+	names, err = Collections()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"billing", "users-api"}, names)
+
+	assert.NoError(t, RemoveCollection("billing"))
+	assert.ErrorIs(t, RemoveCollection("billing"), ErrNoCollection)
+	names, err = Collections()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users-api"}, names)
+
+	_, err = CollectionPath("../etc")
+	assert.Error(t, err)
+	_, err = CollectionPath("a/b")
+	assert.Error(t, err)
+}
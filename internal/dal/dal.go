@@ -0,0 +1,38 @@
+package dal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// EnvHome is the environment variable overriding the directory scour keeps its data in.
+	EnvHome = "SCOUR_HOME"
+	// DirName is the name of the directory scour keeps its data in, under the home directory of the user.
+	DirName = ".scour"
+)
+
+// Dir returns the directory scour keeps its data in: $SCOUR_HOME if set, ~/.scour otherwise.
+func Dir() (string, error) {
+	if dir := os.Getenv(EnvHome); len(dir) > 0 {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locating the scour data directory: %w", err)
+	}
+	return filepath.Join(home, DirName), nil
+}
+
+// path returns the path of a file in the scour data directory, creating the directory if needed.
+func path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating the scour data directory: %w", err)
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,99 @@
+package dal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// EnvsDir is the name of the directory environment files are kept in, one <name>.env file per environment.
+	EnvsDir = "environments"
+	// EnvExt is the extension of environment files.
+	EnvExt = ".env"
+	// CurrentEnvFile is the name of the file holding the name of the environment in use.
+	CurrentEnvFile = "environment"
+	// envNameRe matches valid environment names, which are also file names.
+	envNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	// ErrNoEnv is returned for environments without a file.
+	ErrNoEnv = errors.New("environment not found")
+)
+
+// EnvPath returns the path of the file of the environment called name, creating the environments
+// directory if needed. The file itself may not exist.
+func EnvPath(name string) (string, error) {
+	if !envNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid environment name %q: use letters, digits, '_', '.' and '-'", name)
+	}
+	dir, err := path(EnvsDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating the environments directory: %w", err)
+	}
+	return filepath.Join(dir, name+EnvExt), nil
+}
+
+// Envs returns the names of the environments that have a file, sorted.
+func Envs() ([]string, error) {
+	dir, err := path(EnvsDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name := strings.TrimSuffix(e.Name(), EnvExt); !e.IsDir() && name != e.Name() && envNameRe.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CurrentEnv returns the name of the environment set with UseEnv. It returns "" when none is in use.
+func CurrentEnv() (string, error) {
+	p, err := path(CurrentEnvFile)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(b)), err
+}
+
+// UseEnv makes name the environment in use. An empty name stops using any. The environment must have a file.
+func UseEnv(name string) error {
+	p, err := path(CurrentEnvFile)
+	if err != nil {
+		return err
+	}
+	if len(name) == 0 {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	envPath, err := EnvPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(envPath); err != nil {
+		return fmt.Errorf("%w: %s. Create %s first", ErrNoEnv, name, envPath)
+	}
+	return os.WriteFile(p, []byte(name+"\n"), 0o600)
+}
@@ -0,0 +1,40 @@
+package dal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// TestUseEnv checks listing environments, and switching between them.
+func TestUseEnv(t *testing.T) {
+	t.Setenv(EnvHome, t.TempDir())
+	names, err := Envs()
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+	current, err := CurrentEnv()
+	assert.NoError(t, err)
+	assert.Empty(t, current)
+
+	assert.ErrorIs(t, UseEnv("staging"), ErrNoEnv)
+	for _, name := range []string{"staging", "dev"} {
+		p, err := EnvPath(name)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(p, nil, 0o600))
+	}
+	names, err = Envs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev", "staging"}, names)
+
+	assert.NoError(t, UseEnv("staging"))
+	current, err = CurrentEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", current)
+	assert.NoError(t, UseEnv(""))
+	current, err = CurrentEnv()
+	assert.NoError(t, err)
+	assert.Empty(t, current)
+
+	_, err = EnvPath("../etc")
+	assert.Error(t, err)
+}
@@ -0,0 +1,250 @@
+package dal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// HistoryFile is the name of the file requests are recorded in, one JSON record per line, oldest first.
+	HistoryFile = "history.ndjson"
+	// DefaultHistoryMax is the number of records kept, unless set otherwise.
+	DefaultHistoryMax = 1000
+	// DefaultHistoryMaxAge is how long records are kept, unless set otherwise.
+	DefaultHistoryMaxAge = 30 * 24 * time.Hour
+	// MaxHistoryBody caps the bytes of a body, or of a socket message, kept in a record.
+	MaxHistoryBody = 4096
+	// ErrNoRecord is returned for history records that don't exist, or were pruned.
+	ErrNoRecord = errors.New("history record not found")
+)
+
+// Record is the summary of a transfer kept in the history: the request as sent, and the response received.
+// Bodies and socket messages are truncated to MaxHistoryBody, and binary bodies left out.
+type Record struct {
+	ID     int       `json:"id"`
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Url    string    `json:"url"`
+	// Headers holds the request headers, in "Name: value" form.
+	Headers []string `json:"headers,omitempty"`
+	Data    string   `json:"data,omitempty"`
+
+	Status          int      `json:"status,omitempty"`
+	StatusLine      string   `json:"status_line,omitempty"` // e.g. "200 OK".
+	ResponseHeaders []string `json:"response_headers,omitempty"`
+	Body            Body     `json:"body"`
+	Timings         Timings  `json:"timings"`
+	Error           string   `json:"error,omitempty"`
+	ExitCode        int      `json:"exit_code,omitempty"`
+	Session         *Session `json:"session,omitempty"` // Messages of socket transfers.
+}
+
+// Body is a truncated response body, or socket message.
+type Body struct {
+	Text      string `json:"text,omitempty"`
+	Size      int    `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Binary    bool   `json:"binary,omitempty"` // Binary bodies aren't kept.
+}
+
+// Timings holds where the time of a transfer was spent, in milliseconds.
+type Timings struct {
+	DNS       float64 `json:"dns_ms,omitempty"`
+	Connect   float64 `json:"connect_ms,omitempty"`
+	TLS       float64 `json:"tls_ms,omitempty"`
+	FirstByte float64 `json:"first_byte_ms,omitempty"`
+	Total     float64 `json:"total_ms"`
+}
+
+// Session is a socket console session: its ID, and the messages sent and received in turn.
+type Session struct {
+	ID       string `json:"id,omitempty"`
+	Messages []Body `json:"messages"`
+}
+
+// Retention limits the records kept in the history. Zero values don't limit it.
+type Retention struct {
+	Max    int           // Number of records kept, the oldest are dropped first.
+	MaxAge time.Duration // Age past which records are dropped.
+}
+
+// NewBody truncates b to MaxHistoryBody, at a rune boundary. Binary bodies are left out.
+func NewBody(b []byte) Body {
+	body := Body{Size: len(b)}
+	if !utf8.Valid(b) {
+		body.Binary = true
+		return body
+	}
+	if len(b) > MaxHistoryBody {
+		n := MaxHistoryBody
+		for n > 0 && !utf8.RuneStart(b[n]) {
+			n--
+		}
+		b, body.Truncated = b[:n], true
+	}
+	body.Text = string(b)
+	return body
+}
+
+// History returns every record of the history, oldest first. It returns no error when none have been
+// recorded yet. Lines that can't be parsed, such as one cut short by a crash, are skipped.
+func History() ([]Record, error) {
+	p, err := path(HistoryFile)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lock(p)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return readHistory(p)
+}
+
+// readHistory reads the history file at p. The caller holds its lock.
+func readHistory(p string) ([]Record, error) {
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(line, &r); err == nil {
+			records = append(records, r)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// HistoryRecord returns the record of the history with the given ID.
+func HistoryRecord(id int) (*Record, error) {
+	records, err := History()
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].ID == id {
+			return &records[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrNoRecord, id)
+}
+
+// RecordHistory appends records to the history, numbering them after the last one, then drops the records
+// keep doesn't retain. It returns the records with their IDs set. Concurrent invocations record one at a time.
+func RecordHistory(keep Retention, records ...Record) ([]Record, error) {
+	p, err := path(HistoryFile)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lock(p)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	old, err := readHistory(p)
+	if err != nil {
+		return nil, err
+	}
+	next := 1
+	if len(old) > 0 {
+		next = old[len(old)-1].ID + 1
+	}
+	for i := range records {
+		records[i].ID = next + i
+	}
+	all := append(old, records...)
+	kept := all
+	if keep.MaxAge > 0 {
+		cutoff := time.Now().Add(-keep.MaxAge)
+		kept = nil
+		for _, r := range all {
+			if r.Time.After(cutoff) {
+				kept = append(kept, r)
+			}
+		}
+	}
+	if keep.Max > 0 && len(kept) > keep.Max {
+		kept = kept[len(kept)-keep.Max:]
+	}
+	if len(kept) == len(all) {
+		return records, appendHistory(p, records)
+	}
+	b, err := encodeRecords(kept)
+	if err != nil {
+		return nil, err
+	}
+	return records, replaceFile(p, b)
+}
+
+// ClearHistory drops every record of the history.
+func ClearHistory() error {
+	p, err := path(HistoryFile)
+	if err != nil {
+		return err
+	}
+	unlock, err := lock(p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// appendHistory appends records to the history file at p. The caller holds its lock.
+func appendHistory(p string, records []Record) error {
+	b, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// a line cut short by a crash is ended, so that the records appended start on a line of their own
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			b = append([]byte("\n"), b...)
+		}
+	}
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// encodeRecords encodes records, one JSON record per line.
+func encodeRecords(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
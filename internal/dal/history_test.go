@@ -0,0 +1,116 @@
+package dal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRecordHistory checks that records are numbered in order and pruned by count and by age.
+func TestRecordHistory(t *testing.T) {
+	t.Setenv(EnvHome, t.TempDir())
+	records, err := History()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	now := time.Now()
+	added, err := RecordHistory(Retention{}, Record{Time: now.Add(-48 * time.Hour), Method: "GET", Url: "http://a"}, Record{Time: now, Method: "POST", Url: "http://b"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, []int{added[0].ID, added[1].ID})
+
+	_, err = RecordHistory(Retention{Max: 2, MaxAge: 24 * time.Hour}, Record{Time: now, Method: "GET", Url: "http://c"}, Record{Time: now, Method: "GET", Url: "http://d"})
+	assert.NoError(t, err)
+	records, err = History()
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, []int{3, 4}, []int{records[0].ID, records[1].ID})
+	assert.Equal(t, "http://c", records[0].Url)
+
+	r, err := HistoryRecord(4)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://d", r.Url)
+	_, err = HistoryRecord(1)
+	assert.ErrorIs(t, err, ErrNoRecord)
+
+	assert.NoError(t, ClearHistory())
+	added, err = RecordHistory(Retention{}, Record{Time: now})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added[0].ID)
+}
+
+// TestNewBody checks that bodies are truncated at rune boundaries, and binary ones left out.
+func TestNewBody(t *testing.T) {
+	defer func(max int) { MaxHistoryBody = max }(MaxHistoryBody)
+	MaxHistoryBody = 4
+	assert.Equal(t, Body{Text: "abc", Size: 3}, NewBody([]byte("abc")))
+	assert.Equal(t, Body{Text: "abc", Size: 5, Truncated: true}, NewBody([]byte("abcé")))
+	assert.Equal(t, Body{Size: 2, Binary: true}, NewBody([]byte{0xff, 0xfe}))
+	assert.Equal(t, Body{Text: strings.Repeat("x", 4), Size: 10, Truncated: true}, NewBody([]byte(strings.Repeat("x", 10))))
+}
+
+// TestRecordHistoryConcurrently checks that invocations recording at the same time keep every record.
+func TestRecordHistoryConcurrently(t *testing.T) {
+	t.Setenv(EnvHome, t.TempDir())
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := RecordHistory(Retention{Max: 15}, Record{Time: time.Now(), Url: strings.Repeat("x", i*100)})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	records, err := History()
+	assert.NoError(t, err)
+	assert.Len(t, records, 15)
+	for i, r := range records {
+		assert.Equal(t, 6+i, r.ID)
+	}
+	entries, err := os.ReadDir(os.Getenv(EnvHome))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary and lock files are cleaned up")
+}
+
+// TestHistoryCutShort checks that a record cut short by a crash is skipped, and doesn't corrupt the next one.
+func TestHistoryCutShort(t *testing.T) {
+	t.Setenv(EnvHome, t.TempDir())
+	_, err := RecordHistory(Retention{}, Record{Time: time.Now(), Url: "http://a"})
+	assert.NoError(t, err)
+	p, err := path(HistoryFile)
+	assert.NoError(t, err)
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0o600)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"id": 2, "url": "http://b`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	added, err := RecordHistory(Retention{}, Record{Time: time.Now(), Url: "http://c"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, added[0].ID)
+	records, err := History()
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "http://c", records[1].Url)
+}
+
+// TestLock checks that a held lock times out, and that a stale one is taken over.
+func TestLock(t *testing.T) {
+	defer func(timeout, stale time.Duration) { LockTimeout, StaleLock = timeout, stale }(LockTimeout, StaleLock)
+	LockTimeout, StaleLock = 50*time.Millisecond, time.Hour
+	p := filepath.Join(t.TempDir(), "file")
+	unlock, err := lock(p)
+	assert.NoError(t, err)
+	_, err = lock(p)
+	assert.ErrorContains(t, err, "locked by another scour invocation")
+
+	StaleLock = 0
+	unlock2, err := lock(p)
+	assert.NoError(t, err)
+	unlock2()
+	unlock()
+}
@@ -0,0 +1,36 @@
+package dal
+
+import (
+	"net/url"
+)
+
+var (
+	// MaxHosts caps the number of hosts returned. The least recently requested are dropped first.
+	MaxHosts = 200
+)
+
+// Hosts returns the origins of the HTTP servers that responded to the transfers of the history, such as
+// https://example.com, most recently requested first. It returns no error when none have been recorded yet.
+func Hosts() ([]string, error) {
+	records, err := History()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var hosts []string
+	for i := len(records) - 1; i >= 0 && len(hosts) < MaxHosts; i-- {
+		r := records[i]
+		if r.Status == 0 {
+			continue
+		}
+		u, err := url.Parse(r.Url)
+		if err != nil || len(u.Host) == 0 {
+			continue
+		}
+		if origin := u.Scheme + "://" + u.Host; !seen[origin] {
+			seen[origin] = true
+			hosts = append(hosts, origin)
+		}
+	}
+	return hosts, nil
+}
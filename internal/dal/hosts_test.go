@@ -0,0 +1,35 @@
+package dal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestHosts checks that hosts are derived from the history most recent first, without duplicates or
+// transfers that got no response, up to MaxHosts.
+func TestHosts(t *testing.T) {
+	t.Setenv(EnvHome, t.TempDir())
+	hosts, err := Hosts()
+	assert.NoError(t, err)
+	assert.Empty(t, hosts)
+
+	now := time.Now()
+	_, err = RecordHistory(Retention{},
+		Record{Time: now, Url: "https://a.example.com/users", Status: 200},
+		Record{Time: now, Url: "http://b.example.com:8080/", Status: 404},
+		Record{Time: now, Url: "https://refused.example.com/", Error: "connection refused"},
+		Record{Time: now, Url: "https://c.example.com/x", Status: 200},
+		Record{Time: now, Url: "https://a.example.com/", Status: 200},
+	)
+	assert.NoError(t, err)
+	hosts, err = Hosts()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://a.example.com", "https://c.example.com", "http://b.example.com:8080"}, hosts)
+
+	defer func(max int) { MaxHosts = max }(MaxHosts)
+	MaxHosts = 2
+	hosts, err = Hosts()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://a.example.com", "https://c.example.com"}, hosts)
+}
@@ -0,0 +1,65 @@
+package dal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// LockTimeout is how long an invocation waits for another one to release a file before giving up.
+	LockTimeout = 5 * time.Second
+	// StaleLock is the age past which a lock file is taken as left behind by an invocation that died.
+	StaleLock = 30 * time.Second
+	// lockPoll is how often a held lock file is checked again.
+	lockPoll = 10 * time.Millisecond
+)
+
+// lock takes the lock of the file at p, held by creating p.lock, so that concurrent invocations update the
+// file one at a time. It returns the function releasing the lock.
+func lock(p string) (func(), error) {
+	lockPath := p + ".lock"
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > StaleLock {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another scour invocation. Remove %s if none is running", p, lockPath)
+		}
+		time.Sleep(lockPoll)
+	}
+}
+
+// replaceFile replaces the file at p with b, writing a temporary file of a unique name in the same directory
+// first, so that readers never see a partial file.
+func replaceFile(p string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, p)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
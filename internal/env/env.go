@@ -0,0 +1,209 @@
+package env
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/dal"
+	"github.com/dark-enstein/scour/internal/vars"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Mask replaces the values of secret variables in output.
+	Mask = "****"
+	// SecretPrefix marks a variable as secret in an environment file.
+	SecretPrefix = "secret "
+	// nameRe matches valid variable names.
+	nameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+// Env is a named set of variables requests are templated with, such as base_url or token. The values
+// of secret variables are masked in output.
+type Env struct {
+	Name    string
+	Path    string
+	Vars    vars.Vars
+	Secrets map[string]bool
+}
+
+// Load reads the environment called name from its file in the scour data directory.
+func Load(name string) (*Env, error) {
+	p, err := dal.EnvPath(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s. Create %s first", dal.ErrNoEnv, name, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	e, err := Parse(f, p)
+	if err != nil {
+		return nil, err
+	}
+	e.Name = name
+	return e, nil
+}
+
+// Parse parses an environment file: one "name = value" variable per line, or "secret name = value" for a
+// secret. Values may be double-quoted, with Go escapes, and may reference other variables as {{name}}.
+// Lines starting with # are comments.
+func Parse(r io.Reader, p string) (*Env, error) {
+	e := &Env{Path: p, Vars: vars.Vars{}, Secrets: map[string]bool{}}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		name, value, secret, ok, err := parseLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", p, n, err)
+		}
+		if !ok {
+			continue
+		}
+		e.Vars[name] = value
+		e.Secrets[name] = secret
+	}
+	return e, scanner.Err()
+}
+
+// parseLine parses a line of an environment file. ok is false for blank lines and comments.
+func parseLine(line string) (name, value string, secret, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return "", "", false, false, nil
+	}
+	if rest, cut := strings.CutPrefix(line, SecretPrefix); cut {
+		line, secret = rest, true
+	}
+	name, value, err = ParseVar(line)
+	return name, value, secret, err == nil, err
+}
+
+// ParseVar parses a "name=value" variable definition, as passed in with --var. Whitespace around the
+// name and value is dropped, and double-quoted values are unquoted.
+func ParseVar(s string) (name, value string, err error) {
+	name, value, found := strings.Cut(s, "=")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !found {
+		return "", "", fmt.Errorf("variable %q has no value, expected name=value", s)
+	}
+	if !nameRe.MatchString(name) {
+		return "", "", fmt.Errorf("invalid variable name %q: use letters, digits, '_', '.' and '-'", name)
+	}
+	if strings.HasPrefix(value, `"`) {
+		if value, err = strconv.Unquote(value); err != nil {
+			return "", "", fmt.Errorf("variable %s: malformed quoted value", name)
+		}
+	}
+	return name, value, nil
+}
+
+// Set sets a variable of the environment, and writes it to the environment file. The line defining the
+// variable is replaced, keeping the rest of the file as is; new variables are appended.
+func (e *Env) Set(name, value string, secret bool) error {
+	if !nameRe.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q: use letters, digits, '_', '.' and '-'", name)
+	}
+	b, err := os.ReadFile(e.Path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	def := Format(name, value, secret)
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(b) == 0 {
+		lines = nil
+	}
+	replaced := false
+	for i, line := range lines {
+		if n, _, _, ok, _ := parseLine(line); ok && n == name {
+			lines[i], replaced = def, true
+		}
+	}
+	if !replaced {
+		lines = append(lines, def)
+	}
+	if err := os.WriteFile(e.Path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		return err
+	}
+	e.Vars[name], e.Secrets[name] = value, secret
+	return nil
+}
+
+// Format formats a variable as a line of an environment file, quoting values that wouldn't parse back as is.
+func Format(name, value string, secret bool) string {
+	if value != strings.TrimSpace(value) || strings.HasPrefix(value, `"`) || strings.ContainsAny(value, "\n\r") {
+		value = strconv.Quote(value)
+	}
+	line := name + " = " + value
+	if secret {
+		line = SecretPrefix + line
+	}
+	return line
+}
+
+// Masker returns a Replacer masking the values the secret variables of e take in v, the variables requests
+// are templated with. Values are also masked in their JSON-escaped form. It returns nil without secrets.
+func (e *Env) Masker(v vars.Vars) *strings.Replacer {
+	var values []string
+	for name, secret := range e.Secrets {
+		if !secret {
+			continue
+		}
+		val, err := v.Expand(v[name])
+		if err != nil || len(val) == 0 {
+			continue
+		}
+		values = append(values, val)
+		if b, err := json.Marshal(val); err == nil && string(b[1:len(b)-1]) != val {
+			values = append(values, string(b[1:len(b)-1]))
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	// the Replacer tries the values in order, so longer values go first in case one contains another
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	var oldnew []string
+	for _, val := range values {
+		oldnew = append(oldnew, val, Mask)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// Placeholders returns a Replacer turning the values the secret variables of e take in v back into {{name}}
+// placeholders, so that requests can be stored without them. It returns nil without secrets.
+func (e *Env) Placeholders(v vars.Vars) *strings.Replacer {
+	var names []string
+	values := map[string]string{}
+	for name, secret := range e.Secrets {
+		if val, err := v.Expand(v[name]); secret && err == nil && len(val) > 0 {
+			names, values[name] = append(names, name), val
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(values[names[i]]) != len(values[names[j]]) {
+			return len(values[names[i]]) > len(values[names[j]])
+		}
+		return names[i] < names[j]
+	})
+	var oldnew []string
+	for _, name := range names {
+		oldnew = append(oldnew, values[name], "{{"+name+"}}")
+	}
+	return strings.NewReplacer(oldnew...)
+}
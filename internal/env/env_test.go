@@ -0,0 +1,96 @@
+package env
+
+import (
+	"github.com/dark-enstein/scour/internal/dal"
+	"github.com/dark-enstein/scour/internal/vars"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+var (
+	// testEnv is an environment file holding every kind of line.
+	testEnv = `# staging
+base_url = https://staging.example.com
+users=  {{base_url}}/users
+secret token = "s3\"cr3t "
+
+secret pass = p@ss
+`
+)
+
+// TestParse checks parsing of variables, secrets, quoted values and malformed lines.
+func TestParse(t *testing.T) {
+	e, err := Parse(strings.NewReader(testEnv), "staging.env")
+	assert.NoError(t, err)
+	assert.Equal(t, vars.Vars{"base_url": "https://staging.example.com", "users": "{{base_url}}/users", "token": "s3\"cr3t ", "pass": "p@ss"}, e.Vars)
+	assert.Equal(t, map[string]bool{"base_url": false, "users": false, "token": true, "pass": true}, e.Secrets)
+
+	_, err = Parse(strings.NewReader("a = 1\nno value\n"), "bad.env")
+	assert.ErrorContains(t, err, "bad.env:2:")
+	_, err = Parse(strings.NewReader("1a = 1\n"), "bad.env")
+	assert.ErrorContains(t, err, "invalid variable name")
+	_, err = Parse(strings.NewReader("a = \"1\n"), "bad.env")
+	assert.ErrorContains(t, err, "malformed quoted value")
+}
+
+// TestSet checks that variables are replaced in place or appended, and that the file parses back.
+func TestSet(t *testing.T) {
+	t.Setenv(dal.EnvHome, t.TempDir())
+	p, err := dal.EnvPath("staging")
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(p, []byte(testEnv), 0o600))
+	e, err := Load("staging")
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", e.Name)
+
+	assert.NoError(t, e.Set("base_url", "https://stage.example.com", false))
+	assert.NoError(t, e.Set("id", " 42", true))
+	b, err := os.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Replace(testEnv, "staging.example.com", "stage.example.com", 1)+"secret id = \" 42\"\n", string(b))
+
+	loaded, err := Load("staging")
+	assert.NoError(t, err)
+	assert.Equal(t, e.Vars, loaded.Vars)
+	assert.Equal(t, e.Secrets, loaded.Secrets)
+
+	_, err = Load("missing")
+	assert.ErrorIs(t, err, dal.ErrNoEnv)
+}
+
+// TestMasker checks that the values of secret variables are masked, including ones overridden or nested.
+func TestMasker(t *testing.T) {
+	e, err := Parse(strings.NewReader(testEnv+"secret auth = Bearer {{pass}}\n"), "staging.env")
+	assert.NoError(t, err)
+	m := e.Masker(e.Vars.Merge(vars.Vars{"pass": "over"}))
+	assert.Equal(t, `https://staging.example.com?t=**** {"token":"****"} ****`,
+		m.Replace(`https://staging.example.com?t=s3"cr3t  {"token":"s3\"cr3t "} Bearer over`))
+	assert.Equal(t, "p@ss", m.Replace("p@ss"))
+
+	e, err = Parse(strings.NewReader("a = 1\n"), "plain.env")
+	assert.NoError(t, err)
+	assert.Nil(t, e.Masker(e.Vars))
+}
+
+// TestParseVar checks parsing of --var definitions.
+func TestParseVar(t *testing.T) {
+	name, value, err := ParseVar(" id = a=b ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "a=b"}, []string{name, value})
+	_, _, err = ParseVar("id")
+	assert.Error(t, err)
+}
+
+// TestPlaceholders checks that the values of secret variables are turned back into placeholders.
+func TestPlaceholders(t *testing.T) {
+	e, err := Parse(strings.NewReader(testEnv+"secret auth = Bearer {{pass}}\n"), "staging.env")
+	assert.NoError(t, err)
+	p := e.Placeholders(e.Vars.Merge(vars.Vars{"pass": "over"}))
+	assert.Equal(t, "Authorization: {{auth}}; t={{token}}", p.Replace("Authorization: Bearer over; t="+e.Vars["token"]))
+
+	e, err = Parse(strings.NewReader("a = 1\n"), "plain.env")
+	assert.NoError(t, err)
+	assert.Nil(t, e.Placeholders(e.Vars))
+}
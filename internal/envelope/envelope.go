@@ -0,0 +1,208 @@
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	BodyText   = "text"   // Body encoding for valid UTF-8 bodies.
+	BodyBase64 = "base64" // Body encoding for binary bodies.
+)
+
+// Envelope is the machine-readable record of a single transfer.
+type Envelope struct {
+	Url       string          `json:"url"`                 // Url the transfer was made to.
+	Output    string          `json:"output,omitempty"`    // File the response body was written to, if any.
+	Request   Request         `json:"request"`             // Request as sent.
+	Response  *Response       `json:"response,omitempty"`  // Response as received. Nil if none was.
+	Timings   Timings         `json:"timings"`             // Where the time of the transfer was spent.
+	TLS       *invoke.TLSInfo `json:"tls,omitempty"`       // TLS connection details, for https urls.
+	Redirects []string        `json:"redirects,omitempty"` // Urls redirected through before the final response.
+	Error     *Error          `json:"error,omitempty"`     // Error that failed the transfer, if any.
+}
+
+// Request is the request half of an Envelope.
+type Request struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response is the response half of an Envelope. The body is left out when it was written to a file.
+type Response struct {
+	Status       int         `json:"status,omitempty"`
+	StatusText   string      `json:"status_text,omitempty"`
+	Protocol     string      `json:"protocol,omitempty"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"` // Either BodyText or BodyBase64.
+	BodySize     int         `json:"body_size"`
+}
+
+// Timings holds the durations of invoke.Timings in milliseconds.
+type Timings struct {
+	DNS       float64 `json:"dns_ms"`
+	Connect   float64 `json:"connect_ms"`
+	TLS       float64 `json:"tls_ms"`
+	FirstByte float64 `json:"first_byte_ms"`
+	Total     float64 `json:"total_ms"`
+}
+
+// Error describes why a transfer failed, along with the exit code it maps onto.
+type Error struct {
+	Message  string `json:"message"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// NewRequest builds the request half of an Envelope from the method, url, custom headers and payload of a job.
+func NewRequest(job transfer.Job) Request {
+	req := Request{Method: job.Method, Url: job.Url, Headers: http.Header{}, Body: string(job.Data)}
+	for _, h := range job.Headers {
+		name, value, _ := strings.Cut(h, ":")
+		req.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return req
+}
+
+// Job turns the request half of an Envelope back into a job, so that it can be sent again.
+func (r Request) Job() transfer.Job {
+	job := transfer.Job{Url: r.Url, Method: r.Method, Data: []byte(r.Body)}
+	for _, name := range sortedKeys(r.Headers) {
+		for _, value := range r.Headers[name] {
+			job.Headers = append(job.Headers, name+": "+value)
+		}
+	}
+	return job
+}
+
+// New builds the Envelope of a transfer result.
+func New(req Request, res transfer.Result) *Envelope {
+	env := &Envelope{Url: res.Url, Output: res.Output, Request: req, Timings: NewTimings(res)}
+
+	if res.Err != nil {
+		env.Error = &Error{Message: res.Err.Error(), ExitCode: exitcode.Classify(res.Err)}
+	}
+	if res.Headers == nil && len(res.Body) == 0 {
+		return env
+	}
+
+	env.Response = &Response{BodySize: len(res.Body)}
+	if res.ToStdout() {
+		env.Response.Body, env.Response.BodyEncoding = encodeBody(res.Body)
+	}
+	if h := res.Headers; h != nil {
+		env.Response.Status = h.StatusCode
+		env.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(h.RespCode, fmt.Sprint(h.StatusCode)))
+		env.Response.Protocol = h.Proto
+		env.Response.Headers = h.Header
+		env.TLS = h.TLS
+		env.Redirects = h.Redirects
+	}
+	return env
+}
+
+// NewTimings returns the timings of a transfer result in milliseconds. Results without a response only
+// have their total duration.
+func NewTimings(res transfer.Result) Timings {
+	h := res.Headers
+	if h == nil {
+		return Timings{Total: ms(res.Duration)}
+	}
+	return Timings{
+		DNS:       ms(h.Timings.DNS),
+		Connect:   ms(h.Timings.Connect),
+		TLS:       ms(h.Timings.TLS),
+		FirstByte: ms(h.Timings.FirstByte),
+		Total:     ms(h.Timings.Total),
+	}
+}
+
+// Write encodes the envelopes onto w. config.FormatJSON writes them as one indented JSON array, and
+// config.FormatNDJSON writes one compact JSON object per line.
+func Write(w io.Writer, format string, envs []*Envelope) error {
+	switch format {
+	case config.FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(envs)
+	case config.FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, env := range envs {
+			if err := enc.Encode(env); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("output format %q unsupported", format)
+}
+
+// Read reads envelopes written by Write, in either format: a JSON array, or one object per line.
+func Read(r io.Reader) ([]*Envelope, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(1)
+	for err == nil && bytes.ContainsAny(head, " \t\r\n") {
+		_, _ = br.ReadByte()
+		head, err = br.Peek(1)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading envelopes: %w", err)
+	}
+	var envs []*Envelope
+	dec := json.NewDecoder(br)
+	if head[0] == '[' {
+		if err = dec.Decode(&envs); err != nil {
+			return nil, fmt.Errorf("reading envelopes: %w", err)
+		}
+		return envs, nil
+	}
+	for dec.More() {
+		env := &Envelope{}
+		if err = dec.Decode(env); err != nil {
+			return nil, fmt.Errorf("reading envelope %d: %w", len(envs)+1, err)
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+// sortedKeys returns the header names in h, sorted.
+func sortedKeys(h http.Header) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// encodeBody returns the body as text when it is valid UTF-8, and as base64 otherwise.
+func encodeBody(body []byte) (string, string) {
+	if len(body) == 0 {
+		return "", ""
+	}
+	if utf8.Valid(body) {
+		return string(body), BodyText
+	}
+	return base64.StdEncoding.EncodeToString(body), BodyBase64
+}
+
+// ms converts a duration into fractional milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
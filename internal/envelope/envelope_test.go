@@ -0,0 +1,114 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testReq = NewRequest(transfer.Job{Method: http.MethodGet, Url: "https://eu.httpbin.org/get", Headers: []string{"accept: application/json"}})
+	testRes = transfer.Result{
+		Job: transfer.Job{Url: "https://eu.httpbin.org/get"},
+		Headers: &invoke.RespHeaders{
+			StatusCode: 200,
+			RespCode:   "200 OK",
+			Proto:      "HTTP/2.0",
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Timings:    invoke.Timings{Total: 1500 * time.Microsecond},
+		},
+		Body: []byte(`{"url": "https://eu.httpbin.org/get"}`),
+	}
+)
+
+// TestNew checks that a successful transfer maps onto the envelope fields.
+func TestNew(t *testing.T) {
+	env := New(testReq, testRes)
+	assert.Equal(t, []string{"application/json"}, env.Request.Headers.Values("Accept"))
+	require.NotNil(t, env.Response)
+	assert.Equal(t, 200, env.Response.Status)
+	assert.Equal(t, "OK", env.Response.StatusText)
+	assert.Equal(t, "HTTP/2.0", env.Response.Protocol)
+	assert.Equal(t, BodyText, env.Response.BodyEncoding)
+	assert.Equal(t, string(testRes.Body), env.Response.Body)
+	assert.Equal(t, 1.5, env.Timings.Total)
+	assert.Nil(t, env.Error)
+}
+
+// TestNewTimings checks the timings of transfers with and without a response, in milliseconds.
+func TestNewTimings(t *testing.T) {
+	assert.Equal(t, Timings{Total: 1.5}, NewTimings(testRes))
+	assert.Equal(t, Timings{Total: 2.25}, NewTimings(transfer.Result{Duration: 2250 * time.Microsecond}))
+}
+
+// TestNew_BinaryAndErrors checks base64 bodies, bodies written to files, and failed transfers.
+func TestNew_BinaryAndErrors(t *testing.T) {
+	res := testRes
+	res.Body = []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	env := New(testReq, res)
+	assert.Equal(t, BodyBase64, env.Response.BodyEncoding)
+	assert.Equal(t, "iVBORwD/", env.Response.Body)
+
+	res.Output = "image.png"
+	env = New(testReq, res)
+	assert.Empty(t, env.Response.Body)
+	assert.Equal(t, 6, env.Response.BodySize)
+
+	env = New(testReq, transfer.Result{Job: testRes.Job, Err: errors.Join(exitcode.ErrWrite, errors.New("disk full"))})
+	assert.Nil(t, env.Response)
+	require.NotNil(t, env.Error)
+	assert.Equal(t, exitcode.WriteError, env.Error.ExitCode)
+}
+
+// TestWrite checks that json writes one array and ndjson one object per line.
+func TestWrite(t *testing.T) {
+	envs := []*Envelope{New(testReq, testRes), New(testReq, testRes)}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, config.FormatJSON, envs))
+	var arr []Envelope
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &arr))
+	assert.Len(t, arr, 2)
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, config.FormatNDJSON, envs))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.True(t, json.Valid([]byte(line)))
+	}
+
+	assert.Error(t, Write(&buf, "yaml", envs))
+}
+
+// TestRead checks that envelopes written in either format read back into the jobs that produced them.
+func TestRead(t *testing.T) {
+	job := transfer.Job{Method: http.MethodPost, Url: "https://eu.httpbin.org/post", Headers: []string{"B: 2", "A: 1"}, Data: []byte("x=1")}
+	envs := []*Envelope{New(NewRequest(job), testRes), New(testReq, testRes)}
+	for _, format := range config.AllOutputFormats {
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, format, envs))
+		read, err := Read(&buf)
+		require.NoError(t, err, format)
+		require.Len(t, read, 2, format)
+		replayed := read[0].Request.Job()
+		assert.Equal(t, job.Method, replayed.Method, format)
+		assert.Equal(t, job.Url, replayed.Url, format)
+		assert.Equal(t, []string{"A: 1", "B: 2"}, replayed.Headers, format)
+		assert.Equal(t, job.Data, replayed.Data, format)
+	}
+	_, err := Read(strings.NewReader("  "))
+	assert.Error(t, err)
+	_, err = Read(strings.NewReader(`{"url": 1}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package exitcode
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/dark-enstein/scour/internal/invoke/socket"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// Exit codes returned by scour. Where curl has an equivalent, the same number is used so scripts written
+// against curl keep working. Codes specific to scour start at 100.
+const (
+	OK             = 0   // Every transfer succeeded.
+	Failure        = 1   // Any error not covered by a more specific code.
+	Usage          = 2   // Invalid flags or arguments.
+	UrlMalformed   = 3   // A url could not be parsed.
+	DNS            = 6   // The host could not be resolved.
+	ConnectRefused = 7   // The connection was refused by the server.
+	HTTPError      = 22  // The server responded with status >= 400 and --fail or --fail-with-body was set.
+	WriteError     = 23  // The response could not be written to its output.
+	Timeout        = 28  // The transfer timed out.
+	TLS            = 35  // The TLS handshake or certificate verification failed.
+	SocketNotFound = 100 // The socket path passed in does not exist or isn't a socket.
+	Expectation    = 101 // The response didn't meet an --expect-* check.
+	Mismatch       = 102 // The response differed from its snapshot, or the responses diffed differ.
+)
+
+var (
+	ErrHTTPStatus   = errors.New("the requested url returned error") // Error for a status >= 400 in fail mode.
+	ErrWrite        = errors.New("failed writing output")            // Error for output that could not be written.
+	ErrUrlMalformed = errors.New("url malformed")                    // Error for a url that could not be parsed.
+	ErrExpectation  = errors.New("expectations not met")             // Error for a response failing --expect-* checks.
+	ErrMismatch     = errors.New("response differs from snapshot")   // Error for a response differing from its snapshot.
+)
+
+// Classify maps an error returned from a transfer onto the exit code describing its cause.
+func Classify(err error) int {
+	if err == nil {
+		return OK
+	}
+	switch {
+	case errors.Is(err, ErrHTTPStatus):
+		return HTTPError
+	case errors.Is(err, ErrWrite):
+		return WriteError
+	case errors.Is(err, ErrUrlMalformed):
+		return UrlMalformed
+	case errors.Is(err, ErrExpectation):
+		return Expectation
+	case errors.Is(err, ErrMismatch):
+		return Mismatch
+	case errors.Is(err, socket.ERR_PATHNOTSOCKET):
+		return SocketNotFound
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ConnectRefused
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return Timeout
+		}
+		return DNS
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return Timeout
+	}
+	if isTLS(err) {
+		return TLS
+	}
+	return Failure
+}
+
+// isTLS reports whether err came out of the TLS handshake or certificate verification.
+func isTLS(err error) bool {
+	var (
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &recordErr), errors.As(err, &verifyErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return true
+	}
+	// handshake alerts aren't exported as a type in every supported go version
+	return strings.Contains(err.Error(), "tls: ")
+}
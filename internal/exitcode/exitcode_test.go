@@ -0,0 +1,47 @@
+package exitcode
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/invoke/socket"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+var (
+	// testErrors maps errors as they come back from a transfer to the exit code they should produce.
+	testErrors = map[error]int{
+		nil:                          OK,
+		errors.New("something else"): Failure,
+		fmt.Errorf("%w: 404 Not Found", ErrHTTPStatus):                                             HTTPError,
+		fmt.Errorf("%w: out.json: permission denied", ErrWrite):                                    WriteError,
+		fmt.Errorf("%w: 1 of 2 checks failed", ErrExpectation):                                     Expectation,
+		fmt.Errorf("%w: 3 changes", ErrMismatch):                                                   Mismatch,
+		fmt.Errorf("url http://[::1 invalid: %w", ErrUrlMalformed):                                 UrlMalformed,
+		socket.ERR_PATHNOTSOCKET:                                                                   SocketNotFound,
+		urlErr(&net.DNSError{Err: "no such host", Name: "eu.httpbin.org"}):                         DNS,
+		urlErr(&net.DNSError{Err: "i/o timeout", IsTimeout: true}):                                 Timeout,
+		urlErr(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}): ConnectRefused,
+		urlErr(context.DeadlineExceeded):                                                           Timeout,
+		urlErr(x509.UnknownAuthorityError{}):                                                       TLS,
+		urlErr(errors.New("remote error: tls: handshake failure")):                                 TLS,
+	}
+)
+
+// urlErr wraps err the way net/http returns transport errors.
+func urlErr(err error) error {
+	return &url.Error{Op: "Get", URL: "https://eu.httpbin.org/get", Err: err}
+}
+
+// TestClassify checks that transfer errors map onto their documented exit codes.
+func TestClassify(t *testing.T) {
+	for err, expected := range testErrors {
+		assert.Equal(t, expected, Classify(err), fmt.Sprintf("%v", err))
+	}
+}
@@ -0,0 +1,235 @@
+package expect
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/jq"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// statusRe matches a single status of --expect-status: a code, or a class such as 2xx.
+	statusRe = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+)
+
+// Expectations are the checks run on the response of every transfer, as passed in with the --expect-* flags.
+type Expectations struct {
+	// Status lists the accepted statuses, as codes or classes such as 2xx. Empty accepts any status.
+	Status []string
+	// Headers lists headers that must be in the response, as "Name" or "Name: value". The value must be
+	// contained in one of the values of the header.
+	Headers []string
+	// BodyContains lists strings the response body must contain.
+	BodyContains []string
+	// JSON lists jq expressions the JSON response body must make true.
+	JSON []*jq.Program
+	// TimeUnder is the time the transfer must complete in. Zero doesn't check it.
+	TimeUnder time.Duration
+}
+
+// New compiles the expectations passed in with the --expect-* flags. status is a comma-separated list of
+// accepted statuses.
+func New(status string, headers, bodyContains, json []string, timeUnder time.Duration) (*Expectations, error) {
+	e := &Expectations{Headers: headers, BodyContains: bodyContains, TimeUnder: timeUnder}
+	if len(status) > 0 {
+		var err error
+		if e.Status, err = ParseStatus(status); err != nil {
+			return nil, fmt.Errorf("--expect-status: %w", err)
+		}
+	}
+	for _, h := range headers {
+		if name, _, _ := strings.Cut(h, ":"); len(strings.TrimSpace(name)) == 0 {
+			return nil, fmt.Errorf("--expect-header: %q has no header name", h)
+		}
+	}
+	for _, src := range json {
+		p, err := jq.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("--expect-json %s: %w", src, err)
+		}
+		e.JSON = append(e.JSON, p)
+	}
+	if timeUnder < 0 {
+		return nil, fmt.Errorf("--expect-time-under must be positive, got %s", timeUnder)
+	}
+	return e, nil
+}
+
+// ParseStatus parses a comma-separated list of statuses, as codes such as 200 or classes such as 2xx.
+func ParseStatus(status string) ([]string, error) {
+	var statuses []string
+	for _, s := range strings.Split(status, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !statusRe.MatchString(s) {
+			return nil, fmt.Errorf("%q isn't a status, such as 200, or a class, such as 2xx", s)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Len returns the number of checks run on every response.
+func (e *Expectations) Len() int {
+	n := len(e.Headers) + len(e.BodyContains) + len(e.JSON)
+	if len(e.Status) > 0 {
+		n++
+	}
+	if e.TimeUnder > 0 {
+		n++
+	}
+	return n
+}
+
+// Check is the outcome of a single expectation on a response.
+type Check struct {
+	Name   string // What was expected, e.g. "status 200".
+	Passed bool
+	Got    string // What the response had instead, for failed checks.
+}
+
+// Evaluate runs every expectation on res, in the order status, headers, body, JSON and time.
+func (e *Expectations) Evaluate(res *transfer.Result) []Check {
+	var checks []Check
+	if len(e.Status) > 0 {
+		c := Check{Name: "status " + strings.Join(e.Status, " or ")}
+		if res.Headers == nil {
+			c.Got = "no HTTP response"
+		} else if c.Passed = MatchStatus(e.Status, res.Headers.StatusCode); !c.Passed {
+			c.Got = res.Headers.RespCode
+		}
+		checks = append(checks, c)
+	}
+	for _, h := range e.Headers {
+		checks = append(checks, checkHeader(h, res))
+	}
+	for _, s := range e.BodyContains {
+		c := Check{Name: "body contains " + strconv.Quote(s), Passed: bytes.Contains(res.Body, []byte(s))}
+		if !c.Passed {
+			c.Got = "not found"
+		}
+		checks = append(checks, c)
+	}
+	for _, p := range e.JSON {
+		checks = append(checks, checkJSON(p, res.Body))
+	}
+	if e.TimeUnder > 0 {
+		c := Check{Name: "time under " + e.TimeUnder.String(), Passed: res.Duration < e.TimeUnder}
+		if !c.Passed {
+			c.Got = res.Duration.Round(time.Millisecond).String()
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// MatchStatus reports whether code is one of the statuses or classes of statuses.
+func MatchStatus(statuses []string, code int) bool {
+	s := strconv.Itoa(code)
+	for _, want := range statuses {
+		if want == s || (strings.HasSuffix(want, "xx") && want[0] == s[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHeader checks that res has the header h, in "Name" or "Name: value" form.
+func checkHeader(h string, res *transfer.Result) Check {
+	name, value, hasValue := strings.Cut(h, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	c := Check{Name: "header " + name}
+	if hasValue {
+		c.Name += ": " + value
+	}
+	if res.Headers == nil {
+		c.Got = "no HTTP response"
+		return c
+	}
+	values := res.Headers.Header.Values(name)
+	switch {
+	case len(values) == 0:
+		c.Got = "not in the response"
+	case !hasValue:
+		c.Passed = true
+	default:
+		for _, v := range values {
+			if strings.Contains(strings.ToLower(v), strings.ToLower(value)) {
+				c.Passed = true
+			}
+		}
+		if !c.Passed {
+			c.Got = strings.Join(values, ", ")
+		}
+	}
+	return c
+}
+
+// checkJSON checks that every output of p on body is true, and that there is at least one.
+func checkJSON(p *jq.Program, body []byte) Check {
+	c := Check{Name: "json " + p.String()}
+	vals, err := p.RunJSON(body)
+	if err != nil {
+		c.Got = err.Error()
+		return c
+	}
+	if len(vals) == 0 {
+		c.Got = "no output"
+		return c
+	}
+	for _, v := range vals {
+		if v == nil || v == false {
+			out, _ := jq.Encode([]interface{}{v}, false)
+			c.Got = strings.TrimSpace(string(out))
+			return c
+		}
+	}
+	c.Passed = true
+	return c
+}
+
+// Report is the outcome of the expectations on the response of a transfer.
+type Report struct {
+	Result *transfer.Result
+	Checks []Check
+	// Err is the error of the transfer itself, when it failed before expectations were checked.
+	Err error
+}
+
+// Run evaluates the expectations on res, and fails it with an error wrapping exitcode.ErrExpectation
+// when any isn't met.
+func (e *Expectations) Run(res *transfer.Result) Report {
+	r := Report{Result: res, Checks: e.Evaluate(res), Err: res.Err}
+	if failed := r.Failed(); len(failed) > 0 && res.Err == nil {
+		res.Err = fmt.Errorf("%w: %s", exitcode.ErrExpectation, failed[0].String())
+	}
+	return r
+}
+
+// String describes the check, and what the response had instead when it failed.
+func (c Check) String() string {
+	if c.Passed || len(c.Got) == 0 {
+		return c.Name
+	}
+	return c.Name + ", got " + c.Got
+}
+
+// Failed returns the checks that failed.
+func (r Report) Failed() []Check {
+	var failed []Check
+	for _, c := range r.Checks {
+		if !c.Passed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Passed reports whether the transfer succeeded and met every expectation.
+func (r Report) Passed() bool {
+	return r.Err == nil && len(r.Failed()) == 0
+}
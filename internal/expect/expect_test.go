@@ -0,0 +1,119 @@
+package expect
+
+import (
+	"bytes"
+	"errors"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	// testResult is the result of a transfer that meets every expectation of testExpect.
+	testResult = transfer.Result{
+		Job:  transfer.Job{Url: "https://example.com/health", Method: "GET"},
+		Body: []byte(`{"status": "ok", "checks": [1, 2]}`),
+		Headers: &invoke.RespHeaders{StatusCode: 204, RespCode: "204 No Content", Header: http.Header{
+			"Content-Type": []string{"application/json; charset=utf-8"},
+			"X-Id":         []string{"1"},
+		}},
+		Duration: 120 * time.Millisecond,
+	}
+)
+
+// testExpect compiles the expectations testResult meets.
+func testExpect(t *testing.T) *Expectations {
+	e, err := New("200, 2xx", []string{"content-type: JSON", "X-Id"}, []string{`"ok"`},
+		[]string{`.status == "ok"`, `.checks[] > 0`}, 500*time.Millisecond)
+	assert.NoError(t, err)
+	return e
+}
+
+// TestNew checks that malformed expectations are refused.
+func TestNew(t *testing.T) {
+	assert.Equal(t, 7, testExpect(t).Len())
+	for _, status := range []string{"20", "600", "2x", "abc", "200,"} {
+		_, err := New(status, nil, nil, nil, 0)
+		assert.Error(t, err, status)
+	}
+	_, err := New("", []string{": v"}, nil, nil, 0)
+	assert.Error(t, err)
+	_, err = New("", nil, nil, []string{".a =="}, 0)
+	assert.Error(t, err)
+	_, err = New("", nil, nil, nil, -time.Second)
+	assert.Error(t, err)
+}
+
+// TestRun checks the outcome of every kind of expectation, and that results failing them fail.
+func TestRun(t *testing.T) {
+	e := testExpect(t)
+	res := testResult
+	r := e.Run(&res)
+	assert.True(t, r.Passed())
+	assert.Len(t, r.Checks, 7)
+	assert.NoError(t, res.Err)
+
+	res = testResult
+	res.Headers = &invoke.RespHeaders{StatusCode: 500, RespCode: "500 Internal Server Error", Header: http.Header{"Content-Type": []string{"text/html"}}}
+	res.Body = []byte(`{"status": "down", "checks": [1, 0]}`)
+	res.Duration = time.Second
+	r = e.Run(&res)
+	assert.False(t, r.Passed())
+	assert.NoError(t, r.Err)
+	assert.True(t, errors.Is(res.Err, exitcode.ErrExpectation))
+	assert.Equal(t, []string{
+		"status 200 or 2xx, got 500 Internal Server Error",
+		"header content-type: JSON, got text/html",
+		"header X-Id, got not in the response",
+		`body contains "\"ok\"", got not found`,
+		`json .status == "ok", got false`,
+		"json .checks[] > 0, got false",
+		"time under 500ms, got 1s",
+	}, checkStrings(r.Failed()))
+
+	res = transfer.Result{Job: testResult.Job, Err: errors.New("connection refused")}
+	r = e.Run(&res)
+	assert.False(t, r.Passed())
+	assert.EqualError(t, res.Err, "connection refused")
+	assert.Equal(t, "status 200 or 2xx, got no HTTP response", r.Checks[0].String())
+}
+
+// TestWrite checks the text and JUnit reports.
+func TestWrite(t *testing.T) {
+	e := testExpect(t)
+	pass := testResult
+	fail := testResult
+	fail.Url, fail.Body = "https://example.com/down", []byte(`{"status": "down", "checks": [1]}`)
+	broken := transfer.Result{Job: transfer.Job{Url: "https://example.com/x", Method: "POST"}, Err: errors.New("connection refused")}
+	reports := []Report{e.Run(&pass), e.Run(&fail), e.Run(&broken)}
+
+	var text bytes.Buffer
+	assert.NoError(t, WriteText(&text, reports))
+	assert.True(t, strings.HasPrefix(text.String(), "PASS GET https://example.com/health (120ms)\n  ok    status 200 or 2xx\n"), text.String())
+	assert.Contains(t, text.String(), "FAIL GET https://example.com/down (120ms)\n  ok    status 200 or 2xx\n")
+	assert.Contains(t, text.String(), "  FAIL  json .status == \"ok\", got false\n")
+	assert.Contains(t, text.String(), "FAIL POST https://example.com/x (0s)\n  error connection refused\n")
+	assert.True(t, strings.HasSuffix(text.String(), "\n1 passed, 2 failed\n"))
+
+	var junit bytes.Buffer
+	assert.NoError(t, WriteJUnit(&junit, reports))
+	out := junit.String()
+	assert.True(t, strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<testsuites tests="3" failures="1" errors="1" time="0.240">`), out)
+	assert.Contains(t, out, `<testcase classname="example.com" name="GET https://example.com/health" time="0.120">`)
+	assert.Contains(t, out, `<failure message="2 of 7 checks failed" type="expectation">`)
+	assert.Contains(t, out, `<error message="connection refused" type="transfer">connection refused</error>`)
+}
+
+// checkStrings describes every check.
+func checkStrings(checks []Check) []string {
+	var out []string
+	for _, c := range checks {
+		out = append(out, c.String())
+	}
+	return out
+}
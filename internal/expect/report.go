@@ -0,0 +1,137 @@
+package expect
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// SuiteName names the JUnit test suite the transfers of an invocation are reported in.
+	SuiteName = "scour"
+)
+
+// WriteText writes a pass/fail report of every transfer and its checks to w, followed by a tally.
+func WriteText(w io.Writer, reports []Report) error {
+	var sb strings.Builder
+	failed := 0
+	for _, r := range reports {
+		status := "PASS"
+		if !r.Passed() {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(&sb, "%s %s %s (%s)\n", status, r.Result.Method, r.Result.Url, r.Result.Duration.Round(time.Millisecond))
+		if r.Err != nil {
+			fmt.Fprintf(&sb, "  error %s\n", r.Err.Error())
+		}
+		for _, c := range r.Checks {
+			mark := "ok"
+			if !c.Passed {
+				mark = "FAIL"
+			}
+			fmt.Fprintf(&sb, "  %-5s %s\n", mark, c.String())
+		}
+	}
+	fmt.Fprintf(&sb, "%d passed, %d failed\n", len(reports)-failed, failed)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// junitSuites is the root element of a JUnit XML report.
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+// junitSuite is a test suite of a JUnit XML report.
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+// junitCase is a test case of a JUnit XML report: a transfer, failed by its unmet checks or errored by the
+// transfer failing.
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitProblem describes why a test case failed or errored.
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a JUnit XML report to w, with a test case per transfer, named after its method and url
+// and classed by its host. Every check is listed in the output of the test case.
+func WriteJUnit(w io.Writer, reports []Report) error {
+	suite := junitSuite{Name: SuiteName, Tests: len(reports)}
+	var total time.Duration
+	for _, r := range reports {
+		res := r.Result
+		total += res.Duration
+		c := junitCase{ClassName: SuiteName, Name: res.Method + " " + res.Url, Time: seconds(res.Duration)}
+		if u, err := neturl.Parse(res.Url); err == nil && len(u.Host) > 0 {
+			c.ClassName = u.Host
+		}
+		var out strings.Builder
+		for _, check := range r.Checks {
+			status := "ok"
+			if !check.Passed {
+				status = "FAIL"
+			}
+			fmt.Fprintf(&out, "%s %s\n", status, check.String())
+		}
+		c.SystemOut = out.String()
+		if failed := r.Failed(); r.Err != nil {
+			suite.Errors++
+			c.Error = &junitProblem{Message: r.Err.Error(), Type: "transfer", Text: r.Err.Error()}
+		} else if len(failed) > 0 {
+			suite.Failures++
+			var text []string
+			for _, check := range failed {
+				text = append(text, check.String())
+			}
+			c.Failure = &junitProblem{
+				Message: fmt.Sprintf("%d of %d checks failed", len(failed), len(r.Checks)),
+				Type:    "expectation",
+				Text:    strings.Join(text, "\n"),
+			}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Time = seconds(total)
+	doc := junitSuites{Tests: suite.Tests, Failures: suite.Failures, Errors: suite.Errors, Time: suite.Time, Suites: []junitSuite{suite}}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// seconds formats d in seconds, as JUnit reports times.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,280 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/curl"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// renderers maps every export target to the function rendering it.
+	renderers = map[string]func(b *strings.Builder, job transfer.Job, headers []header, s secrets){
+		config.ExportCurl:           renderCurl,
+		config.ExportGo:             renderGo,
+		config.ExportPythonRequests: renderPython,
+		config.ExportJSFetch:        renderFetch,
+		config.ExportHTTPie:         renderHTTPie,
+	}
+	// lineBreak continues a shell command on the next line.
+	lineBreak = " \\\n  "
+)
+
+// header is a single request header, split into its name and value.
+type header struct {
+	name, value string
+}
+
+// secrets maps the {{name}} placeholders of secret variables to the environment variables snippets read
+// their values from.
+type secrets map[string]string
+
+// Render renders the request of job as a runnable snippet for target, one of config.AllExportTargets. The
+// {{name}} placeholders of the secret variables named are rendered as reads of the environment variable
+// EnvName(name), so that snippets can be shared without their values.
+func Render(target string, job transfer.Job, secretNames ...string) (string, error) {
+	render, ok := renderers[target]
+	if !ok {
+		return "", fmt.Errorf("export target \"%s\" is not supported. please pass in a supported target: %s", target, strings.Join(config.AllExportTargets, ", "))
+	}
+	s := secrets{}
+	for _, name := range secretNames {
+		s["{{"+name+"}}"] = EnvName(name)
+	}
+	var b strings.Builder
+	render(&b, job, parseHeaders(job.Headers), s)
+	return b.String(), nil
+}
+
+// EnvName returns the environment variable exported snippets read the secret variable name from: name in
+// upper case, with characters other than letters, digits and underscores replaced by underscores.
+func EnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// used reports whether the url, headers or data of job hold a placeholder of s.
+func (s secrets) used(job transfer.Job) bool {
+	for placeholder := range s {
+		if strings.Contains(job.Url, placeholder) || strings.Contains(string(job.Data), placeholder) {
+			return true
+		}
+		for _, h := range job.Headers {
+			if strings.Contains(h, placeholder) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expr renders str as an expression of the target language: the parts of str between placeholders are
+// quoted with quote, the placeholders rendered with ref, and the whole joined with sep.
+func (s secrets) expr(str string, quote func(string) string, ref func(env string) string, sep string) string {
+	var parts []string
+	for len(str) > 0 {
+		at, placeholder := -1, ""
+		for p := range s {
+			if i := strings.Index(str, p); i >= 0 && (at < 0 || i < at || i == at && len(p) > len(placeholder)) {
+				at, placeholder = i, p
+			}
+		}
+		if at < 0 {
+			break
+		}
+		if at > 0 {
+			parts = append(parts, quote(str[:at]))
+		}
+		parts = append(parts, ref(s[placeholder]))
+		str = str[at+len(placeholder):]
+	}
+	if len(str) > 0 || len(parts) == 0 {
+		parts = append(parts, quote(str))
+	}
+	return strings.Join(parts, sep)
+}
+
+// shell renders str as a word of a POSIX shell command line.
+func (s secrets) shell(str string) string {
+	return s.expr(str, curl.Quote, func(env string) string { return `"$` + env + `"` }, "")
+}
+
+// goString renders str as a Go string expression, spaced as gofmt does in arguments.
+func (s secrets) goString(str string) string {
+	return s.expr(str, strconv.Quote, func(env string) string { return "os.Getenv(" + strconv.Quote(env) + ")" }, "+")
+}
+
+// pyString renders str as a Python string expression.
+func (s secrets) pyString(str string) string {
+	return s.expr(str, jsString, func(env string) string { return "os.environ[" + jsString(env) + "]" }, " + ")
+}
+
+// jsExpr renders str as a JavaScript string expression.
+func (s secrets) jsExpr(str string) string {
+	return s.expr(str, jsString, func(env string) string { return "process.env." + env }, " + ")
+}
+
+// parseHeaders splits headers in "Name: value" form. Headers without a colon are left out.
+func parseHeaders(raw []string) []header {
+	var headers []header
+	for _, h := range raw {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			continue
+		}
+		headers = append(headers, header{strings.TrimSpace(name), strings.TrimSpace(value)})
+	}
+	return headers
+}
+
+// merged returns headers with the values of repeated names joined with ", ", for targets taking a map.
+func merged(headers []header) []header {
+	var out []header
+	index := map[string]int{}
+	for _, h := range headers {
+		key := http.CanonicalHeaderKey(h.name)
+		if i, ok := index[key]; ok {
+			out[i].value += ", " + h.value
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, h)
+	}
+	return out
+}
+
+// jsString quotes s as a JSON string, which is a valid string literal in Python and JavaScript as well.
+func jsString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// renderCurl renders the request as a curl command.
+func renderCurl(b *strings.Builder, job transfer.Job, headers []header, s secrets) {
+	b.WriteString("curl")
+	switch job.Method {
+	case http.MethodGet:
+	case http.MethodHead:
+		b.WriteString(" --head")
+	default:
+		b.WriteString(" -X " + job.Method)
+	}
+	if strings.ContainsAny(job.Url, "{}[]") {
+		// the url was sent as is, so curl must not expand it as a glob
+		b.WriteString(" --globoff")
+	}
+	b.WriteString(" " + s.shell(job.Url))
+	for _, h := range headers {
+		b.WriteString(lineBreak + "-H " + s.shell(h.name+": "+h.value))
+	}
+	if len(job.Data) > 0 {
+		b.WriteString(lineBreak + "--data-raw " + s.shell(string(job.Data)))
+	}
+	b.WriteString("\n")
+}
+
+// renderHTTPie renders the request as an HTTPie command.
+func renderHTTPie(b *strings.Builder, job transfer.Job, headers []header, s secrets) {
+	b.WriteString("http")
+	if len(job.Data) > 0 {
+		b.WriteString(" --raw " + s.shell(string(job.Data)))
+	}
+	b.WriteString(" " + job.Method + " " + s.shell(job.Url))
+	for _, h := range headers {
+		b.WriteString(lineBreak + s.shell(h.name+":"+h.value))
+	}
+	b.WriteString("\n")
+}
+
+// renderGo renders the request as a Go program using net/http.
+func renderGo(b *strings.Builder, job transfer.Job, headers []header, s secrets) {
+	b.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"log\"\n\t\"net/http\"\n")
+	if s.used(job) {
+		b.WriteString("\t\"os\"\n")
+	}
+	body := "nil"
+	if len(job.Data) > 0 {
+		b.WriteString("\t\"strings\"\n")
+		body = "strings.NewReader(" + s.goString(string(job.Data)) + ")"
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+	fmt.Fprintf(b, "\treq, err := http.NewRequest(%s, %s, %s)\n", strconv.Quote(job.Method), s.goString(job.Url), body)
+	b.WriteString("\tif err != nil {\n\t\tlog.Fatal(err)\n\t}\n")
+	for _, h := range headers {
+		fmt.Fprintf(b, "\treq.Header.Add(%s, %s)\n", strconv.Quote(h.name), s.goString(h.value))
+	}
+	b.WriteString(`	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(resp.Status)
+	fmt.Println(string(respBody))
+}
+`)
+}
+
+// renderPython renders the request as a Python script using requests.
+func renderPython(b *strings.Builder, job transfer.Job, headers []header, s secrets) {
+	if s.used(job) {
+		b.WriteString("import os\n")
+	}
+	b.WriteString("import requests\n\n")
+	fmt.Fprintf(b, "url = %s\n", s.pyString(job.Url))
+	args := ""
+	if hs := merged(headers); len(hs) > 0 {
+		b.WriteString("headers = {\n")
+		for _, h := range hs {
+			fmt.Fprintf(b, "    %s: %s,\n", jsString(h.name), s.pyString(h.value))
+		}
+		b.WriteString("}\n")
+		args += ", headers=headers"
+	}
+	if len(job.Data) > 0 {
+		// requests encodes str bodies as latin-1, so send them as UTF-8 bytes instead
+		data := s.pyString(string(job.Data))
+		if data != jsString(string(job.Data)) {
+			data = "(" + data + ")"
+		}
+		fmt.Fprintf(b, "data = %s.encode(\"utf-8\")\n", data)
+		args += ", data=data"
+	}
+	fmt.Fprintf(b, "\nresponse = requests.request(%s, url%s)\n", jsString(job.Method), args)
+	b.WriteString("print(response.status_code)\nprint(response.text)\n")
+}
+
+// renderFetch renders the request as a JavaScript module using fetch.
+func renderFetch(b *strings.Builder, job transfer.Job, headers []header, s secrets) {
+	fmt.Fprintf(b, "const response = await fetch(%s, {\n", s.jsExpr(job.Url))
+	fmt.Fprintf(b, "  method: %s,\n", jsString(job.Method))
+	if hs := merged(headers); len(hs) > 0 {
+		b.WriteString("  headers: {\n")
+		for _, h := range hs {
+			fmt.Fprintf(b, "    %s: %s,\n", jsString(h.name), s.jsExpr(h.value))
+		}
+		b.WriteString("  },\n")
+	}
+	if len(job.Data) > 0 {
+		fmt.Fprintf(b, "  body: %s,\n", s.jsExpr(string(job.Data)))
+	}
+	b.WriteString("});\nconsole.log(response.status);\nconsole.log(await response.text());\n")
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"flag"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	// update rewrites the golden files with the current output: go test ./internal/export -update
+	update = flag.Bool("update", false, "update the golden files")
+	// testJobs maps the name of a golden file case to the request rendered in it.
+	testJobs = map[string]transfer.Job{
+		"get": {Url: "https://example.com/users?page=1&sort=name", Method: "GET"},
+		"post": {
+			Url:    "https://api.example.com/items/{id}?q=it's $HOME",
+			Method: "POST",
+			Headers: []string{
+				"Content-Type: application/json",
+				"Authorization: Bearer tok\"en",
+				"X-Tag: a",
+				"x-tag: b",
+			},
+			Data: []byte("{\"name\": \"it's \\\"quoted\\\"\",\n \"emoji\": \"café ☕\", \"html\": \"<b>&</b>\"}"),
+		},
+		"secret": {
+			Url:     "https://example.com/{{tenant}}/items?key={{api-key}}",
+			Method:  "POST",
+			Headers: []string{"Authorization: Bearer {{token}}"},
+			Data:    []byte(`{"password": "{{token}}"}`),
+		},
+	}
+	// testSecrets maps the name of a golden file case to the secret variables rendered as environment variables.
+	testSecrets = map[string][]string{"secret": {"token", "api-key"}}
+)
+
+// TestRender compares the snippet of every target with its golden file in testdata.
+func TestRender(t *testing.T) {
+	for name, job := range testJobs {
+		for _, target := range config.AllExportTargets {
+			out, err := Render(target, job, testSecrets[name]...)
+			assert.NoError(t, err)
+			golden := filepath.Join("testdata", name+"."+target+".golden")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, []byte(out), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), out, golden)
+		}
+	}
+	_, err := Render("cobol", testJobs["get"])
+	assert.Error(t, err)
+}
+
+// TestEnvName checks the environment variables secret variables are read from.
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "TOKEN", EnvName("token"))
+	assert.Equal(t, "API_KEY_2", EnvName("api-key.2"))
+}
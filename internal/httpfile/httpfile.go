@@ -0,0 +1,243 @@
+package httpfile
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/config"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/dark-enstein/scour/internal/vars"
+	"golang.org/x/exp/slices"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Separator starts a new request. The rest of its line names the request.
+	Separator = "###"
+	// methods lists the request methods recognised at the start of a request line.
+	methods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodTrace, http.MethodConnect}
+	// includeRe matches a body read from a file, "< path" or "<@ path" to substitute variables in it. As with
+	// REST Client, the path follows whitespace, so that inline bodies such as <ping/> are sent as written.
+	includeRe = regexp.MustCompile(`^<(@?)\s+(\S.*)$`)
+)
+
+// Request is a single request of an .http file, before variables are substituted.
+type Request struct {
+	Name    string   // Name from the ### separator or a # @name comment. Empty when unnamed.
+	Index   int      // Position in the file, starting at 1.
+	Line    int      // Line of the request line.
+	Method  string   // Request method, GET when left out.
+	Url     string   // Url, including query continuation lines.
+	Headers []string // Headers, in "Name: value" form.
+	Body    string   // Inline body.
+	// BodyFile holds the file the body is read from with "< path", relative to the .http file.
+	BodyFile string
+	// ExpandBodyFile substitutes variables in the body file as well, for "<@ path".
+	ExpandBodyFile bool
+}
+
+// File is a parsed .http file, in the format of the VS Code REST Client and the JetBrains HTTP client.
+type File struct {
+	Path     string
+	Vars     vars.Vars
+	Requests []Request
+}
+
+// ParseFile reads and parses the .http file at path.
+func ParseFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, path)
+}
+
+// block holds the state of the request being parsed.
+type block struct {
+	req     Request
+	started bool // Whether the request line was seen.
+	inBody  bool
+	handler bool // Whether a JetBrains > {% response handler %} is being skipped.
+	body    []string
+}
+
+// Parse parses an .http file. Requests are separated by lines starting with ###, and made of optional
+// comments, a request line, headers, a blank line and a body. Lines of the form @name = value define
+// variables, usable anywhere in the file as {{name}}.
+func Parse(r io.Reader, path string) (*File, error) {
+	f := &File{Path: path, Vars: vars.Vars{}}
+	b := &block{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, Separator) {
+			f.add(b)
+			b = &block{req: Request{Name: strings.TrimSpace(strings.TrimLeft(trimmed, "#"))}}
+			continue
+		}
+		switch {
+		case b.inBody:
+			b.addBody(line, trimmed)
+		case !b.started:
+			if err := f.preamble(b, trimmed, n); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+		case len(trimmed) == 0:
+			b.inBody = true
+		case isComment(trimmed):
+		case (strings.HasPrefix(trimmed, "?") || strings.HasPrefix(trimmed, "&")) && len(b.req.Headers) == 0:
+			b.req.Url += trimmed
+		default:
+			name, _, ok := strings.Cut(trimmed, ":")
+			if !ok || len(strings.TrimSpace(name)) == 0 {
+				return nil, fmt.Errorf("%s:%d: malformed header %s", path, n, trimmed)
+			}
+			b.req.Headers = append(b.req.Headers, trimmed)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	f.add(b)
+	return f, nil
+}
+
+// preamble parses a line before the request line: a blank line, a comment, a variable definition or the
+// request line itself.
+func (f *File) preamble(b *block, line string, n int) error {
+	switch {
+	case len(line) == 0:
+	case isComment(line):
+		comment := strings.TrimSpace(strings.TrimLeft(line, "#/"))
+		if name, ok := strings.CutPrefix(comment, "@name"); ok {
+			b.req.Name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "="))
+		}
+	case strings.HasPrefix(line, "@"):
+		name, value, ok := strings.Cut(line[1:], "=")
+		if !ok || len(strings.TrimSpace(name)) == 0 {
+			return fmt.Errorf("malformed variable definition %s. Expected: @name = value", line)
+		}
+		f.Vars[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	default:
+		fields := strings.Fields(line)
+		b.req.Method = http.MethodGet
+		if slices.Contains(methods, strings.ToUpper(fields[0])) {
+			b.req.Method, fields = strings.ToUpper(fields[0]), fields[1:]
+		}
+		if len(fields) > 0 && strings.HasPrefix(fields[len(fields)-1], "HTTP/") {
+			fields = fields[:len(fields)-1]
+		}
+		if len(fields) == 0 {
+			return fmt.Errorf("request line %s has no url", line)
+		}
+		b.req.Url, b.req.Line, b.started = strings.Join(fields, " "), n, true
+	}
+	return nil
+}
+
+// addBody adds a line to the body, skipping JetBrains response handlers and redirections.
+func (b *block) addBody(line, trimmed string) {
+	switch {
+	case b.handler:
+		b.handler = !strings.HasSuffix(trimmed, "%}")
+	case strings.HasPrefix(trimmed, "> {%"):
+		b.handler = !strings.HasSuffix(trimmed, "%}")
+	case strings.HasPrefix(trimmed, ">>") || (strings.HasPrefix(trimmed, "> ") && strings.HasSuffix(trimmed, ".js")):
+	default:
+		b.body = append(b.body, line)
+	}
+}
+
+// add adds the request of b to the file, if it has one.
+func (f *File) add(b *block) {
+	if !b.started {
+		return
+	}
+	body := strings.TrimRight(strings.Join(b.body, "\n"), "\n\t ")
+	if m := includeRe.FindStringSubmatch(strings.TrimSpace(body)); m != nil {
+		b.req.ExpandBodyFile, b.req.BodyFile = len(m[1]) > 0, strings.TrimSpace(m[2])
+		body = ""
+	}
+	b.req.Body = body
+	b.req.Index = len(f.Requests) + 1
+	f.Requests = append(f.Requests, b.req)
+}
+
+// isComment reports whether a trimmed line is a comment.
+func isComment(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+}
+
+// Select returns the requests picked by name or by index, starting at 1, in the order asked for. No
+// selection picks every request.
+func (f *File) Select(selection []string) ([]Request, error) {
+	if len(selection) == 0 {
+		return f.Requests, nil
+	}
+	var picked []Request
+	for _, s := range selection {
+		i := slices.IndexFunc(f.Requests, func(r Request) bool { return len(r.Name) > 0 && r.Name == s })
+		if n, err := strconv.Atoi(s); i < 0 && err == nil && n >= 1 && n <= len(f.Requests) {
+			i = n - 1
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("no request named or numbered %s in %s", s, f.Path)
+		}
+		picked = append(picked, f.Requests[i])
+	}
+	return picked, nil
+}
+
+// Resolve substitutes the variables of the file, overridden by extra, into req, reads its body file,
+// and returns it as a transfer job.
+func (f *File) Resolve(req Request, extra vars.Vars) (transfer.Job, error) {
+	v := f.Vars.Merge(extra)
+	where := fmt.Sprintf("%s:%d", f.Path, req.Line)
+	if !slices.Contains(config.AllSupportedConn[1:], req.Method) {
+		return transfer.Job{}, fmt.Errorf("%s: method %s is not supported. please use one of: %s", where, req.Method, strings.Join(config.AllSupportedConn[1:], ", "))
+	}
+	url, err := v.Expand(req.Url)
+	if err != nil {
+		return transfer.Job{}, fmt.Errorf("%s: %w", where, err)
+	}
+	job := transfer.Job{Url: url, Method: req.Method}
+	for _, h := range req.Headers {
+		expanded, err := v.Expand(h)
+		if err != nil {
+			return transfer.Job{}, fmt.Errorf("%s: %w", where, err)
+		}
+		job.Headers = append(job.Headers, expanded)
+	}
+	body := req.Body
+	if len(req.BodyFile) > 0 {
+		p := req.BodyFile
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(f.Path), p)
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return transfer.Job{}, fmt.Errorf("%s: reading the body: %w", where, err)
+		}
+		if !req.ExpandBodyFile {
+			job.Data = b
+			return job, nil
+		}
+		body = string(b)
+	}
+	if body, err = v.Expand(body); err != nil {
+		return transfer.Job{}, fmt.Errorf("%s: %w", where, err)
+	}
+	if len(body) > 0 {
+		job.Data = []byte(body)
+	}
+	return job, nil
+}
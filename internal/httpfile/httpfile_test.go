@@ -0,0 +1,126 @@
+package httpfile
+
+import (
+	"github.com/dark-enstein/scour/internal/vars"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	// testFile is the .http file parsed in tests.
+	testFile = filepath.Join("testdata", "api.http")
+	// testBad maps malformed .http files to the error they are expected to fail with.
+	testBad = map[string]string{
+		"GET https://example.com\nnot a header": "malformed header",
+		"@ = 1":                                 "malformed variable definition",
+		"@host https://example.com":             "malformed variable definition",
+		"GET HTTP/1.1":                          "no url",
+	}
+)
+
+// TestParseFile checks parsing of names, variables, query continuations, headers, bodies and includes.
+func TestParseFile(t *testing.T) {
+	f, err := ParseFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, vars.Vars{"host": "https://api.example.com", "token": "secret", "users": "{{host}}/users"}, f.Vars)
+	if !assert.Len(t, f.Requests, 5) {
+		return
+	}
+
+	list := f.Requests[0]
+	assert.Equal(t, Request{Name: "list users", Index: 1, Line: 6, Method: "GET", Url: "{{users}}?page=1&sort=name",
+		Headers: []string{"Accept: application/json", "Authorization: Bearer {{token}}"}}, list)
+
+	create := f.Requests[1]
+	assert.Equal(t, "createUser", create.Name)
+	assert.Equal(t, "POST", create.Method)
+	assert.Equal(t, "{{users}}", create.Url)
+	assert.Equal(t, "{\n  \"name\": \"scour\",\n  \"id\": \"{{$guid}}\"\n}", create.Body)
+
+	assert.Equal(t, "./body.json", f.Requests[2].BodyFile)
+	assert.False(t, f.Requests[2].ExpandBodyFile)
+	assert.True(t, f.Requests[3].ExpandBodyFile)
+	assert.Equal(t, "GET", f.Requests[4].Method)
+	assert.Empty(t, f.Requests[4].Name)
+}
+
+// TestResolve checks substitution of variables, overrides and body files.
+func TestResolve(t *testing.T) {
+	f, err := ParseFile(testFile)
+	assert.NoError(t, err)
+
+	job, err := f.Resolve(f.Requests[0], vars.Vars{"token": "override"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/users?page=1&sort=name", job.Url)
+	assert.Equal(t, []string{"Accept: application/json", "Authorization: Bearer override"}, job.Headers)
+	assert.Nil(t, job.Data)
+
+	job, err = f.Resolve(f.Requests[1], nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(job.Data), "{{")
+
+	job, err = f.Resolve(f.Requests[2], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"token\": \"{{token}}\"}\n", string(job.Data))
+	job, err = f.Resolve(f.Requests[3], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"token\": \"secret\"}\n", string(job.Data))
+
+	_, err = f.Resolve(Request{Method: "OPTIONS", Url: "{{host}}"}, nil)
+	assert.Error(t, err)
+	_, err = f.Resolve(Request{Method: "GET", Url: "{{nope}}"}, nil)
+	assert.Error(t, err)
+}
+
+// TestSelect checks picking requests by name and index.
+func TestSelect(t *testing.T) {
+	f, err := ParseFile(testFile)
+	assert.NoError(t, err)
+	picked, err := f.Select([]string{"createUser", "1", "upload"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 1, 3}, []int{picked[0].Index, picked[1].Index, picked[2].Index})
+	all, err := f.Select(nil)
+	assert.NoError(t, err)
+	assert.Len(t, all, 5)
+	_, err = f.Select([]string{"6"})
+	assert.Error(t, err)
+	_, err = f.Select([]string{"missing"})
+	assert.Error(t, err)
+}
+
+// TestParse_Errors checks that malformed files are rejected, pointing at the offending line.
+func TestParse_Errors(t *testing.T) {
+	for src, msg := range testBad {
+		_, err := Parse(strings.NewReader(src), "bad.http")
+		if assert.Error(t, err, src) {
+			assert.Contains(t, err.Error(), msg, src)
+			assert.Contains(t, err.Error(), "bad.http:", src)
+		}
+	}
+}
+
+// TestParse_InlineMarkup checks that one-line XML and HTML bodies are sent as written, rather than read
+// from a file.
+func TestParse_InlineMarkup(t *testing.T) {
+	for body, file := range map[string]string{
+		"<user>ann</user>":  "",
+		"<ping/>":           "",
+		"<@user>ann</user>": "",
+		"<  ./user.xml":     "./user.xml",
+		"<@\t./user.xml":    "./user.xml",
+	} {
+		f, err := Parse(strings.NewReader("POST https://example.com\nContent-Type: application/xml\n\n"+body+"\n"), "inline.http")
+		if !assert.NoError(t, err, body) {
+			continue
+		}
+		req := f.Requests[0]
+		assert.Equal(t, file, req.BodyFile, body)
+		if len(file) == 0 {
+			assert.Equal(t, body, req.Body, body)
+		} else {
+			assert.Empty(t, req.Body, body)
+		}
+	}
+}
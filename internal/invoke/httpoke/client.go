@@ -0,0 +1,278 @@
+package httpoke
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/parser"
+	"github.com/dark-enstein/scour/internal/parser/httparser"
+	"github.com/dark-enstein/scour/internal/trace"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// client is shared by every request sent through httpoke, so that transfers to the same host
+	// within one invocation reuse pooled keep-alive connections instead of dialing afresh.
+	client = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	// RequestTimeout bounds how long a single request may take, including reading the response body.
+	RequestTimeout = 5 * time.Second
+	// KeyOptions is the context key the request Options are stored under.
+	KeyOptions = "OPTIONS"
+	// tracedTransports holds a transport per Tracer, so that traced requests still share connections.
+	tracedTransports   = map[*trace.Tracer]*http.Transport{}
+	tracedTransportMux sync.Mutex
+)
+
+// Options holds the request settings that aren't part of the url or payload. They are passed to
+// the request functions through the context, under KeyOptions.
+type Options struct {
+	Headers []string // Custom request headers, each in "Name: value" form.
+}
+
+// ParseOptionsFromCtx extracts the request Options from a context, falling back to the defaults.
+func ParseOptionsFromCtx(ctx context.Context) *Options {
+	if opts, ok := ctx.Value(KeyOptions).(*Options); ok && opts != nil {
+		return opts
+	}
+	return &Options{}
+}
+
+// do sends an HTTP request with the given method and payload to the specified URL.
+// It manages request timeouts using context, logs relevant information,
+// and returns the response headers and body as a byte slice.
+func do(ctx context.Context, method string, url parser.Url, data []byte) (*invoke.RespHeaders, []byte, error) {
+	opts := ParseOptionsFromCtx(ctx)
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	defer cancel()
+
+	timings := newTimingsRecorder(time.Now())
+	ctx = httptrace.WithClientTrace(ctx, timings.Trace())
+	req, err := http.NewRequestWithContext(ctx, method, url.String(), bytes.NewBuffer(data))
+	if err != nil {
+		log.Printf("Error creating request object: %s\n", err.Error())
+		return nil, nil, err
+	}
+	if err = setHeaders(req, opts.Headers); err != nil {
+		return nil, nil, err
+	}
+
+	// a shallow copy keeps the shared transport, and with it the connection pool
+	var redirects []string
+	cli := *client
+	if tracer := trace.FromCtx(ctx); tracer != nil {
+		cli.Transport = tracedTransport(tracer)
+	}
+	cli.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		redirects = append(redirects, via[len(via)-1].URL.String())
+		return nil
+	}
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		log.Printf("%s request failed with: %s\n", method, err.Error())
+		return nil, nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	respH := invoke.NewHeaders(resp.Status, fmt.Sprintf("%s/1.1", url.Protocol().String()), resp.Header.Get("Date"), resp.Header.Get("Content-Type"), resp.Header.Get("Content-Length"), resp.Header.Get("Connection"), resp.Header.Get("Server"), resp.Header.Get("Access-Control-Allow-Origin"), resp.Header.Get("Access-Control-Allow-Credentials"))
+	respH.StatusCode = resp.StatusCode
+	respH.Proto = resp.Proto
+	respH.Header = resp.Header
+	respH.TLS = invoke.NewTLSInfo(resp.TLS)
+	if resp.TLS == nil {
+		// traced connections aren't a bare *tls.Conn, so net/http leaves their state out
+		respH.TLS = invoke.NewTLSInfo(timings.TLSState())
+	}
+	respH.Redirects = redirects
+	if httparser.ParseLogLevelFromCtx(ctx, httparser.KeyV) == true {
+		log.Printf("Response: %v\n", respH)
+	}
+
+	responseStream, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("Error receiving response:", err.Error())
+		return nil, nil, err
+	}
+	respH.Timings = timings.Done()
+
+	if httparser.ParseLogLevelFromCtx(ctx, httparser.KeyV) == true {
+		log.Printf("Buffer length: %d\n", len(responseStream))
+		log.Printf("Time taken: %s\n", respH.Timings.Total.String())
+	}
+
+	return respH, responseStream, nil
+}
+
+// tracedConn is a traced TLS connection, which keeps the state of the TLS connection it wraps within reach
+// of the client trace, as net/http only records the state of a bare *tls.Conn.
+type tracedConn struct {
+	net.Conn
+	tls *tls.Conn
+}
+
+// ConnectionState returns the state of the TLS connection.
+func (c *tracedConn) ConnectionState() tls.ConnectionState {
+	return c.tls.ConnectionState()
+}
+
+// tracedTransport returns the transport whose connections are traced by tracer. TLS is set up by the
+// transport's own dialer, so that the tracer sees the decrypted bytes, and HTTP/2 is turned off, so that
+// those bytes stay readable, which the trace says as it connects. The handshake is reported to the client
+// trace of the request, as the transport only does so for the handshakes it runs itself.
+func tracedTransport(tracer *trace.Tracer) *http.Transport {
+	tracedTransportMux.Lock()
+	defer tracedTransportMux.Unlock()
+	if tr, ok := tracedTransports[tracer]; ok {
+		return tr
+	}
+	tr := client.Transport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		tracer.Infof("Trying %s...", addr)
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			tracer.Infof("Failed to connect to %s: %s", addr, err.Error())
+		}
+		return conn, err
+	}
+	tr.ForceAttemptHTTP2 = false
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return tracer.Conn(conn, addr), nil
+	}
+	tr.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		cfg := &tls.Config{}
+		if tr.TLSClientConfig != nil {
+			cfg = tr.TLSClientConfig.Clone()
+		}
+		cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		cfg.NextProtos = []string{"http/1.1"}
+		tracer.Infof("ALPN: offering http/1.1 only, HTTP/2 is turned off while tracing")
+		ct := httptrace.ContextClientTrace(ctx)
+		if ct != nil && ct.TLSHandshakeStart != nil {
+			ct.TLSHandshakeStart()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		err = tlsConn.HandshakeContext(ctx)
+		state := tlsConn.ConnectionState()
+		if ct != nil && ct.TLSHandshakeDone != nil {
+			ct.TLSHandshakeDone(state, err)
+		}
+		if err != nil {
+			tracer.Infof("TLS handshake with %s failed: %s", addr, err.Error())
+			_ = conn.Close()
+			return nil, err
+		}
+		info := invoke.NewTLSInfo(&state)
+		tracer.Infof("TLS connection to %s using %s / %s", addr, info.Version, info.CipherSuite)
+		return &tracedConn{Conn: tracer.Conn(tlsConn, addr), tls: tlsConn}, nil
+	}
+	tracedTransports[tracer] = tr
+	return tr
+}
+
+// setHeaders adds the custom headers to the request. A header with no value, in the form "Name:",
+// removes that header from the request instead.
+func setHeaders(req *http.Request, headers []string) error {
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || len(name) == 0 {
+			return fmt.Errorf("header %q malformed. Expecting \"Name: value\"", h)
+		}
+		switch {
+		case len(value) == 0:
+			req.Header.Del(name)
+		case strings.EqualFold(name, "Host"):
+			req.Host = value
+		default:
+			req.Header.Add(name, value)
+		}
+	}
+	return nil
+}
+
+// timingsRecorder records the duration of each phase of a request from client trace callbacks, and the
+// TLS state of traced connections. The dialer may race several connection attempts, so every access holds
+// the lock.
+type timingsRecorder struct {
+	mux                           sync.Mutex
+	start                         time.Time
+	dnsStart, connStart, tlsStart time.Time
+	t                             invoke.Timings
+	tlsState                      *tls.ConnectionState
+}
+
+// newTimingsRecorder creates a timingsRecorder measuring from start.
+func newTimingsRecorder(start time.Time) *timingsRecorder {
+	return &timingsRecorder{start: start}
+}
+
+// record runs f with the lock held.
+func (r *timingsRecorder) record(f func()) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	f()
+}
+
+// Trace returns the client trace feeding the recorder.
+func (r *timingsRecorder) Trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { r.record(func() { r.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { r.record(func() { r.t.DNS = time.Since(r.dnsStart) }) },
+		ConnectStart: func(string, string) {
+			r.record(func() { r.connStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			r.record(func() { r.t.Connect = time.Since(r.connStart) })
+		},
+		TLSHandshakeStart: func() { r.record(func() { r.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			r.record(func() { r.t.TLS = time.Since(r.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if c, ok := info.Conn.(*tracedConn); ok {
+				state := c.ConnectionState()
+				r.record(func() { r.tlsState = &state })
+			}
+		},
+		GotFirstResponseByte: func() { r.record(func() { r.t.FirstByte = time.Since(r.start) }) },
+	}
+}
+
+// TLSState returns the state of the traced TLS connection the request was sent over, or nil when there was
+// none.
+func (r *timingsRecorder) TLSState() (state *tls.ConnectionState) {
+	r.record(func() { state = r.tlsState })
+	return
+}
+
+// Done marks the end of the request and returns the recorded timings.
+func (r *timingsRecorder) Done() (t invoke.Timings) {
+	r.record(func() {
+		r.t.Total = time.Since(r.start)
+		t = r.t
+	})
+	return
+}
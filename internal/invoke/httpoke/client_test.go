@@ -0,0 +1,49 @@
+package httpoke
+
+import (
+	"bytes"
+	"context"
+	"github.com/dark-enstein/scour/internal/parser/httparser"
+	"github.com/dark-enstein/scour/internal/trace"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDo_TracedTLS checks that requests over traced TLS connections still report the TLS state and the
+// handshake timing, on new and reused connections alike.
+func TestDo_TracedTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	tr := client.Transport.(*http.Transport)
+	saved := tr.TLSClientConfig
+	tr.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+	defer func() { tr.TLSClientConfig = saved }()
+
+	var out bytes.Buffer
+	ctx := context.WithValue(context.Background(), httparser.KeyV, false)
+	ctx = context.WithValue(ctx, trace.KeyTracer, trace.New(&out, true, false))
+	url, err := httparser.NewUrl(ctx, srv.URL)
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		respH, body, err := do(ctx, http.MethodGet, url, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "hello", string(body))
+		if assert.NotNil(t, respH.TLS) {
+			assert.NotEmpty(t, respH.TLS.Version)
+			assert.NotEmpty(t, respH.TLS.PeerCertificates)
+		}
+		if i == 0 {
+			assert.Positive(t, respH.Timings.TLS)
+		} else {
+			assert.Zero(t, respH.Timings.TLS, "reused connection")
+		}
+	}
+	assert.Contains(t, out.String(), "HTTP/2 is turned off while tracing")
+	assert.Contains(t, out.String(), "GET / HTTP/1.1")
+}
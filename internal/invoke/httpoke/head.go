@@ -0,0 +1,15 @@
+package httpoke
+
+import (
+	"context"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/parser"
+	"net/http"
+)
+
+// Head sends a HEAD HTTP request to the specified URL.
+// It manages request timeouts using context, logs relevant information,
+// and returns the response headers and the, normally empty, body as a byte slice.
+func Head(ctx context.Context, url parser.Url) (*invoke.RespHeaders, []byte, error) {
+	return do(ctx, http.MethodHead, url, nil)
+}
@@ -8,6 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dark-enstein/scour/internal/parser"
+	"github.com/dark-enstein/scour/internal/parser/httparser"
+	"github.com/dark-enstein/scour/internal/pretty"
+	"github.com/dark-enstein/scour/internal/trace"
 	"github.com/dark-enstein/scour/internal/utils"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
@@ -45,12 +48,20 @@ Errors:
 	RCV_PAGESIZE  = 1024             // Default page size for receiving data.
 	CONN_TIMEOUT  = time.Duration(2) // Default connection timeout duration.
 	SOCKET_GET    = "get"
+	KeyBinaryOK   = "BINARYOK" // Key for allowing binary responses to be printed to a terminal in console mode.
+	KeySession    = "SESSION"  // Key for a *Session the console session is recorded into.
 )
 
 var (
 	ERR_PATHNOTSOCKET = errors.New("file resource not a socket") // Error when the provided resource is not a socket.
 )
 
+// Session records a console session: its ID, and the messages sent and received in turn.
+type Session struct {
+	ID       string
+	Messages [][]byte
+}
+
 // UnixSock establishes a Unix socket connection and initiates a console session.
 // It returns a summary of the session including any errors encountered.
 func UnixSock(ctx context.Context, url parser.Url, it bool) ([]byte, error) {
@@ -65,6 +76,9 @@ func UnixSock(ctx context.Context, url parser.Url, it bool) ([]byte, error) {
 		log.Printf("Error connecting to unix socket %s: %s\n", url.Path(), err.Error())
 		return nil, err
 	}
+	if tracer := trace.FromCtx(ctx); tracer != nil {
+		conn = tracer.Conn(conn, url.Path())
+	}
 	defer func(conn net.Conn) {
 		err = conn.Close()
 		if err != nil {
@@ -72,7 +86,10 @@ func UnixSock(ctx context.Context, url parser.Url, it bool) ([]byte, error) {
 		}
 	}(conn)
 
-	_, _, communication, err := NewConsole(ctx, conn, url, it).Enter()
+	id, _, communication, err := NewConsole(ctx, conn, url, it).Enter()
+	if sess, ok := ctx.Value(KeySession).(*Session); ok {
+		sess.ID, sess.Messages = id, communication
+	}
 	flat := flatten(communication, []byte("\n"))
 	//fmt.Printf(UNIXSUMMARY, id, flat, err.Error())
 	return flat, err
@@ -174,7 +191,11 @@ func (c *Console) Enter() (sessID string, code int, communication [][]byte, err
 			respBuf, errRcv := c.socRcv(ctx)
 			err = fmt.Errorf("%s: %w", err, errRcv)
 			c.Unlock()
-			fmt.Printf("< %s\n", string(respBuf))
+			if utils.IsTerminal(os.Stdout) && pretty.Binary("", respBuf) && !binaryOK(c.ctx) {
+				log.Printf("< %d bytes of binary response not shown. %s\n", len(respBuf), pretty.BinaryWarning)
+			} else {
+				fmt.Printf("< %s\n", string(respBuf))
+			}
 			communication = append(communication, respBuf)
 		}
 	case false:
@@ -196,26 +217,39 @@ func (c *Console) Enter() (sessID string, code int, communication [][]byte, err
 
 }
 
+// binaryOK reports whether binary responses may be printed to a terminal, as set under KeyBinaryOK.
+func binaryOK(ctx context.Context) bool {
+	ok, _ := ctx.Value(KeyBinaryOK).(bool)
+	return ok
+}
+
 // socRcv handles receiving data from the network connection.
 // It returns the received data and any error encountered.
 func (c *Console) socRcv(ctx context.Context) ([]byte, error) {
-	color.Green("<< receiving:\n")
+	verbose := httparser.ParseLogLevelFromCtx(ctx, httparser.KeyV)
+	if verbose {
+		color.Green("<< receiving:\n")
+	}
 	stream, err := io.ReadAll(c.conn)
 	if err != nil {
 		log.Println("error encountered from socket connection:", err.Error())
 		return nil, err
 	}
-	fmt.Printf("Received stream: %s\n", stream)
+	if verbose {
+		log.Printf("Received stream: %s\n", stream)
+	}
 	return stream, nil
 }
 
 // socSend handles sending data over the network connection.
 // It returns the number of bytes sent and any error encountered.
 func (c *Console) socSend(ctx context.Context) (int, error) {
-	color.Yellow(">> sending to %s: %s\n", c.url.Path(), c.resource)
+	if httparser.ParseLogLevelFromCtx(ctx, httparser.KeyV) {
+		color.Yellow(">> sending to %s: %s\n", c.url.Path(), c.resource)
+	}
 	_, err := c.Write([]byte(c.resource))
 	if err != nil {
-		fmt.Printf("&> Error sending message: %s\n", err.Error())
+		log.Printf("&> Error sending message: %s\n", err.Error())
 		if c.it && retrySend(string(c.resource)) {
 			if c.recurse.Can() {
 				c.recurse.Iter()
@@ -1,8 +1,17 @@
 package invoke
 
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+)
+
 // RespHeaders defines the structure for storing HTTP response headers.
 type RespHeaders struct {
-	RespCode                      string // HTTP response status code.
+	StatusCode                    int    // HTTP response status code.
+	RespCode                      string // HTTP response status line, e.g. "200 OK".
 	Protocol                      string // Protocol used in the response (e.g., HTTP/1.1).
 	Date                          string // Date of the response.
 	ContentType                   string // MIME type of the response content.
@@ -11,6 +20,88 @@ type RespHeaders struct {
 	Server                        string // Server information.
 	AccessControlAllowOrigin      string // Allowed origins for cross-origin requests.
 	AccessControlAllowCredentials bool   // Indicates if credentials are allowed in cross-origin requests.
+
+	Proto     string      // Protocol as reported on the status line, e.g. HTTP/2.0.
+	Header    http.Header // Every header of the final response.
+	Timings   Timings     // Breakdown of where the time of the request was spent.
+	TLS       *TLSInfo    // TLS connection details. Nil for plain HTTP.
+	Redirects []string    // Urls redirected through, in order, before the final response.
+}
+
+// Dump renders the status line and every header of the response, in HTTP/1.x wire form and ending with
+// the blank line that separates headers from the body. Headers are sorted by name.
+func (h *RespHeaders) Dump() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s\r\n", h.Proto, h.RespCode)
+	_ = h.Header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// Timings breaks down where the time of a request was spent. Phases skipped because a pooled
+// connection was reused are left at zero.
+type Timings struct {
+	DNS       time.Duration // Time spent resolving the host.
+	Connect   time.Duration // Time spent establishing the TCP connection.
+	TLS       time.Duration // Time spent on the TLS handshake.
+	FirstByte time.Duration // Time from sending the request until the first response byte arrived.
+	Total     time.Duration // Time from sending the request until the whole body was read.
+}
+
+// TLSInfo describes the TLS connection a response was received over.
+type TLSInfo struct {
+	Version          string     `json:"version"`           // Negotiated TLS version, e.g. TLS 1.3.
+	CipherSuite      string     `json:"cipher_suite"`      // Negotiated cipher suite.
+	ServerName       string     `json:"server_name"`       // Server name sent via SNI.
+	ALPN             string     `json:"alpn,omitempty"`    // Negotiated application protocol.
+	PeerCertificates []CertInfo `json:"peer_certificates"` // Certificate chain presented by the server, leaf first.
+}
+
+// CertInfo summarises a single certificate of a chain.
+type CertInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// tlsVersionName returns the name of a TLS protocol version, e.g. TLS 1.3.
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04X", v)
+}
+
+// NewTLSInfo summarises the connection state of a TLS connection. It returns nil for a nil state.
+func NewTLSInfo(state *tls.ConnectionState) *TLSInfo {
+	if state == nil {
+		return nil
+	}
+	info := &TLSInfo{
+		Version:     tlsVersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+		ALPN:        state.NegotiatedProtocol,
+	}
+	for _, cert := range state.PeerCertificates {
+		info.PeerCertificates = append(info.PeerCertificates, CertInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			DNSNames:  cert.DNSNames,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		})
+	}
+	return info
 }
 
 // NewHeaders creates a new instance of RespHeaders with provided header values.
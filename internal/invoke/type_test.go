@@ -0,0 +1,23 @@
+package invoke
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// TestRespHeaders_Dump checks that the status line and headers are rendered in wire form, sorted by name.
+func TestRespHeaders_Dump(t *testing.T) {
+	headers := &RespHeaders{
+		StatusCode: http.StatusNotFound,
+		RespCode:   "404 Not Found",
+		Proto:      "HTTP/1.1",
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+			"Set-Cookie":   {"a=1", "b=2"},
+			"Age":          {"0"},
+		},
+	}
+	expected := "HTTP/1.1 404 Not Found\r\nAge: 0\r\nContent-Type: application/json\r\nSet-Cookie: a=1\r\nSet-Cookie: b=2\r\n\r\n"
+	assert.Equal(t, expected, string(headers.Dump()))
+}
@@ -0,0 +1,299 @@
+package jq
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtin is a function callable from a filter. Its arguments are filters evaluated against the input.
+type builtin struct {
+	arity int
+	fn    func(in interface{}, args []node) ([]interface{}, error)
+}
+
+// callNode calls a builtin.
+type callNode struct {
+	name string
+	args []node
+}
+
+func (n *callNode) eval(in interface{}) ([]interface{}, error) {
+	return builtins[n.name].fn(in, n.args)
+}
+
+// builtins lists every function a filter may call, keyed by name.
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		"empty": {0, func(interface{}, []node) ([]interface{}, error) { return nil, nil }},
+		"not":   {0, func(in interface{}, _ []node) ([]interface{}, error) { return one(!truthy(in)) }},
+		"type":  {0, func(in interface{}, _ []node) ([]interface{}, error) { return one(typeOf(in)) }},
+		"select": {1, func(in interface{}, args []node) ([]interface{}, error) {
+			conds, err := args[0].eval(in)
+			if err != nil {
+				return nil, err
+			}
+			var out []interface{}
+			for _, c := range conds {
+				if truthy(c) {
+					out = append(out, in)
+				}
+			}
+			return out, nil
+		}},
+		"map": {1, func(in interface{}, args []node) ([]interface{}, error) {
+			return (&arrayNode{&pipeNode{&iterateNode{identityNode{}}, args[0]}}).eval(in)
+		}},
+		"length": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			switch t := in.(type) {
+			case nil:
+				return one(0.0)
+			case bool:
+				return nil, fmt.Errorf("boolean (%v) has no length", t)
+			case string:
+				return one(float64(utf8.RuneCountInString(t)))
+			case []interface{}:
+				return one(float64(len(t)))
+			case map[string]interface{}:
+				return one(float64(len(t)))
+			}
+			f, _ := toFloat(in)
+			return one(math.Abs(f))
+		}},
+		"keys": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			switch t := in.(type) {
+			case map[string]interface{}:
+				var out []interface{}
+				for _, k := range sortedKeys(t) {
+					out = append(out, k)
+				}
+				return one(orEmpty(out))
+			case []interface{}:
+				out := make([]interface{}, len(t))
+				for i := range t {
+					out[i] = float64(i)
+				}
+				return one(out)
+			}
+			return nil, fmt.Errorf("%s has no keys", describe(in))
+		}},
+		"has": {1, func(in interface{}, args []node) ([]interface{}, error) {
+			return eachArg(in, args[0], func(k interface{}) (interface{}, error) {
+				switch t := in.(type) {
+				case map[string]interface{}:
+					if ks, ok := k.(string); ok {
+						_, found := t[ks]
+						return found, nil
+					}
+				case []interface{}:
+					if f, ok := toFloat(k); ok {
+						return f >= 0 && int(f) < len(t), nil
+					}
+				}
+				return nil, fmt.Errorf("cannot check whether %s has a key %s", typeOf(in), describe(k))
+			})
+		}},
+		"first": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			v, err := index(in, 0.0)
+			return []interface{}{v}, err
+		}},
+		"last": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			v, err := index(in, -1.0)
+			return []interface{}{v}, err
+		}},
+		"add": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			items, err := values(in)
+			if err != nil {
+				return nil, err
+			}
+			var sum interface{}
+			for _, item := range items {
+				if sum, err = add(sum, item); err != nil {
+					return nil, err
+				}
+			}
+			return one(sum)
+		}},
+		"sort": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			arr, ok := in.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s cannot be sorted, as it is not an array", describe(in))
+			}
+			out := append([]interface{}{}, arr...)
+			sort.SliceStable(out, func(i, j int) bool { return compare(out[i], out[j]) < 0 })
+			return one(out)
+		}},
+		"unique": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			sorted, err := builtins["sort"].fn(in, nil)
+			if err != nil {
+				return nil, err
+			}
+			var out []interface{}
+			for _, v := range sorted[0].([]interface{}) {
+				if len(out) == 0 || compare(out[len(out)-1], v) != 0 {
+					out = append(out, v)
+				}
+			}
+			return one(orEmpty(out))
+		}},
+		"tostring": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			if s, ok := in.(string); ok {
+				return one(s)
+			}
+			b, err := json.Marshal(in)
+			return []interface{}{string(b)}, err
+		}},
+		"tonumber": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			if f, ok := toFloat(in); ok {
+				return one(f)
+			}
+			if s, ok := in.(string); ok {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+					return one(f)
+				}
+			}
+			return nil, fmt.Errorf("%s cannot be parsed as a number", describe(in))
+		}},
+		"contains": {1, func(in interface{}, args []node) ([]interface{}, error) {
+			return eachArg(in, args[0], func(b interface{}) (interface{}, error) {
+				if typeOf(in) != typeOf(b) {
+					return nil, fmt.Errorf("%s and %s cannot have their containment checked", describe(in), describe(b))
+				}
+				return contains(in, b), nil
+			})
+		}},
+		"startswith": {1, stringTest(strings.HasPrefix)},
+		"endswith":   {1, stringTest(strings.HasSuffix)},
+		"test": {1, stringTest(func(s, pattern string) bool {
+			re, err := regexp.Compile(pattern)
+			return err == nil && re.MatchString(s)
+		})},
+		"join": {1, func(in interface{}, args []node) ([]interface{}, error) {
+			return eachArg(in, args[0], func(sep interface{}) (interface{}, error) {
+				items, err := values(in)
+				if err != nil {
+					return nil, err
+				}
+				parts := make([]string, len(items))
+				for i, item := range items {
+					switch t := item.(type) {
+					case nil:
+					case string:
+						parts[i] = t
+					default:
+						b, _ := json.Marshal(t)
+						parts[i] = string(b)
+					}
+				}
+				s, _ := sep.(string)
+				return strings.Join(parts, s), nil
+			})
+		}},
+		"ascii_downcase": {0, stringMap(strings.ToLower)},
+		"ascii_upcase":   {0, stringMap(strings.ToUpper)},
+		"to_entries": {0, func(in interface{}, _ []node) ([]interface{}, error) {
+			obj, ok := in.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s has no keys", describe(in))
+			}
+			out := []interface{}{}
+			for _, k := range sortedKeys(obj) {
+				out = append(out, map[string]interface{}{"key": k, "value": obj[k]})
+			}
+			return one(out)
+		}},
+	}
+}
+
+// one wraps a single output.
+func one(v interface{}) ([]interface{}, error) {
+	return []interface{}{v}, nil
+}
+
+// orEmpty turns a nil slice into an empty array, so that it encodes as [] rather than null.
+func orEmpty(v []interface{}) []interface{} {
+	if v == nil {
+		return []interface{}{}
+	}
+	return v
+}
+
+// eachArg calls f once per output of arg.
+func eachArg(in interface{}, arg node, f func(interface{}) (interface{}, error)) ([]interface{}, error) {
+	vals, err := arg.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, v := range vals {
+		r, err := f(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// stringTest builds a builtin testing a string input against a string argument.
+func stringTest(test func(s, arg string) bool) func(interface{}, []node) ([]interface{}, error) {
+	return func(in interface{}, args []node) ([]interface{}, error) {
+		return eachArg(in, args[0], func(a interface{}) (interface{}, error) {
+			s, ok := in.(string)
+			as, aok := a.(string)
+			if !ok || !aok {
+				return nil, fmt.Errorf("%s and %s must both be strings", describe(in), describe(a))
+			}
+			return test(s, as), nil
+		})
+	}
+}
+
+// stringMap builds a builtin transforming a string input.
+func stringMap(f func(string) string) func(interface{}, []node) ([]interface{}, error) {
+	return func(in interface{}, _ []node) ([]interface{}, error) {
+		s, ok := in.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", describe(in))
+		}
+		return one(f(s))
+	}
+}
+
+// contains reports whether b is contained in a: substrings, array subsets and object subsets count.
+func contains(a, b interface{}) bool {
+	switch at := a.(type) {
+	case string:
+		return strings.Contains(at, b.(string))
+	case []interface{}:
+		for _, bi := range b.([]interface{}) {
+			found := false
+			for _, ai := range at {
+				if typeOf(ai) == typeOf(bi) && contains(ai, bi) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		for k, bv := range b.(map[string]interface{}) {
+			av, ok := at[k]
+			if !ok || typeOf(av) != typeOf(bv) || !contains(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return compare(a, b) == 0
+}
@@ -0,0 +1,616 @@
+package jq
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// identityNode is ".", passing its input through.
+type identityNode struct{}
+
+func (identityNode) eval(in interface{}) ([]interface{}, error) {
+	return []interface{}{in}, nil
+}
+
+// recurseNode is "..", producing its input and every value nested within it.
+type recurseNode struct{}
+
+func (recurseNode) eval(in interface{}) ([]interface{}, error) {
+	out := []interface{}{in}
+	switch v := in.(type) {
+	case []interface{}:
+		for _, item := range v {
+			sub, _ := recurseNode{}.eval(item)
+			out = append(out, sub...)
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			sub, _ := recurseNode{}.eval(v[k])
+			out = append(out, sub...)
+		}
+	}
+	return out, nil
+}
+
+// literalNode produces a constant value.
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(interface{}) ([]interface{}, error) {
+	return []interface{}{n.value}, nil
+}
+
+// pipeNode feeds every output of left into right.
+type pipeNode struct {
+	left, right node
+}
+
+func (n *pipeNode) eval(in interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, l := range lefts {
+		rights, err := n.right.eval(l)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rights...)
+	}
+	return out, nil
+}
+
+// commaNode produces the outputs of left followed by the outputs of right.
+type commaNode struct {
+	left, right node
+}
+
+func (n *commaNode) eval(in interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	rights, err := n.right.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	return append(lefts, rights...), nil
+}
+
+// tryNode is the ? suffix, dropping the error of its inner filter.
+type tryNode struct {
+	inner node
+}
+
+func (n *tryNode) eval(in interface{}) ([]interface{}, error) {
+	out, err := n.inner.eval(in)
+	if err != nil {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// indexNode is .name, ."name" or .[key], looking up an object key or array index.
+type indexNode struct {
+	target, key node
+}
+
+func (n *indexNode) eval(in interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, t := range targets {
+		keys, err := n.key.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			v, err := index(t, k)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// index looks up key in v.
+func index(v, key interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		if k, ok := key.(string); ok {
+			return t[k], nil
+		}
+	case []interface{}:
+		if f, ok := toFloat(key); ok {
+			i := int(math.Floor(f))
+			if i < 0 {
+				i += len(t)
+			}
+			if i < 0 || i >= len(t) {
+				return nil, nil
+			}
+			return t[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cannot index %s with %s", typeOf(v), describe(key))
+}
+
+// sliceNode is .[from:to], slicing an array or string.
+type sliceNode struct {
+	target, from, to node
+}
+
+func (n *sliceNode) eval(in interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	bound := func(b node, def int, length int) (int, error) {
+		if b == nil {
+			return def, nil
+		}
+		vals, err := b.eval(in)
+		if err != nil {
+			return 0, err
+		}
+		f, ok := toFloat(first(vals))
+		if !ok {
+			return 0, fmt.Errorf("slice bounds must be numbers")
+		}
+		i := int(math.Floor(f))
+		if i < 0 {
+			i += length
+		}
+		return int(math.Max(0, math.Min(float64(i), float64(length)))), nil
+	}
+	var out []interface{}
+	for _, t := range targets {
+		var length int
+		switch v := t.(type) {
+		case nil:
+			out = append(out, nil)
+			continue
+		case []interface{}:
+			length = len(v)
+		case string:
+			length = len([]rune(v))
+		default:
+			return nil, fmt.Errorf("cannot slice %s", typeOf(t))
+		}
+		lo, err := bound(n.from, 0, length)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := bound(n.to, length, length)
+		if err != nil {
+			return nil, err
+		}
+		if hi < lo {
+			hi = lo
+		}
+		if s, ok := t.(string); ok {
+			out = append(out, string([]rune(s)[lo:hi]))
+		} else {
+			out = append(out, append([]interface{}{}, t.([]interface{})[lo:hi]...))
+		}
+	}
+	return out, nil
+}
+
+// iterateNode is .[], producing every item of an array or value of an object.
+type iterateNode struct {
+	target node
+}
+
+func (n *iterateNode) eval(in interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, t := range targets {
+		vals, err := values(t)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vals...)
+	}
+	return out, nil
+}
+
+// values returns the items of an array or the values of an object, ordered by key.
+func values(v interface{}) ([]interface{}, error) {
+	switch t := v.(type) {
+	case []interface{}:
+		return t, nil
+	case map[string]interface{}:
+		var out []interface{}
+		for _, k := range sortedKeys(t) {
+			out = append(out, t[k])
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", describe(v))
+}
+
+// arrayNode is [f], collecting every output of f into an array.
+type arrayNode struct {
+	inner node
+}
+
+func (n *arrayNode) eval(in interface{}) ([]interface{}, error) {
+	if n.inner == nil {
+		return []interface{}{[]interface{}{}}, nil
+	}
+	items, err := n.inner.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	return []interface{}{items}, nil
+}
+
+// objectEntry is a single key: value pair of an object construction.
+type objectEntry struct {
+	key, value node
+}
+
+// objectNode is {key: value, ...}. Keys or values producing several outputs produce one object per combination.
+type objectNode struct {
+	entries []objectEntry
+}
+
+func (n *objectNode) eval(in interface{}) ([]interface{}, error) {
+	objs := []map[string]interface{}{{}}
+	for _, e := range n.entries {
+		keys, err := e.key.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		vals, err := e.value.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		var next []map[string]interface{}
+		for _, obj := range objs {
+			for _, k := range keys {
+				ks, ok := k.(string)
+				if !ok {
+					return nil, fmt.Errorf("object keys must be strings, got %s", typeOf(k))
+				}
+				for _, v := range vals {
+					cp := make(map[string]interface{}, len(obj)+1)
+					for ok, ov := range obj {
+						cp[ok] = ov
+					}
+					cp[ks] = v
+					next = append(next, cp)
+				}
+			}
+		}
+		objs = next
+	}
+	out := make([]interface{}, len(objs))
+	for i := range objs {
+		out[i] = objs[i]
+	}
+	return out, nil
+}
+
+// binaryNode applies a binary operator to every combination of the outputs of left and right.
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(in interface{}) ([]interface{}, error) {
+	switch n.op {
+	case "and", "or":
+		return n.evalLogic(in)
+	case "//":
+		lefts, err := n.left.eval(in)
+		var out []interface{}
+		if err == nil {
+			for _, l := range lefts {
+				if truthy(l) {
+					out = append(out, l)
+				}
+			}
+		}
+		if len(out) > 0 {
+			return out, nil
+		}
+		return n.right.eval(in)
+	}
+
+	rights, err := n.right.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	lefts, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, r := range rights {
+		for _, l := range lefts {
+			v, err := apply(n.op, l, r)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// evalLogic evaluates and/or, short-circuiting on the left operand.
+func (n *binaryNode) evalLogic(in interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, l := range lefts {
+		if n.op == "and" && !truthy(l) || n.op == "or" && truthy(l) {
+			out = append(out, truthy(l))
+			continue
+		}
+		rights, err := n.right.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rights {
+			out = append(out, truthy(r))
+		}
+	}
+	return out, nil
+}
+
+// apply applies an arithmetic or comparison operator.
+func apply(op string, l, r interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return compare(l, r) == 0, nil
+	case "!=":
+		return compare(l, r) != 0, nil
+	case "<":
+		return compare(l, r) < 0, nil
+	case "<=":
+		return compare(l, r) <= 0, nil
+	case ">":
+		return compare(l, r) > 0, nil
+	case ">=":
+		return compare(l, r) >= 0, nil
+	case "+":
+		return add(l, r)
+	}
+
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	switch {
+	case op == "-" && lok && rok:
+		return lf - rf, nil
+	case op == "-":
+		la, lok := l.([]interface{})
+		ra, rok := r.([]interface{})
+		if lok && rok {
+			var out []interface{}
+			for _, item := range la {
+				keep := true
+				for _, drop := range ra {
+					if compare(item, drop) == 0 {
+						keep = false
+					}
+				}
+				if keep {
+					out = append(out, item)
+				}
+			}
+			return out, nil
+		}
+	case op == "*" && lok && rok:
+		return lf * rf, nil
+	case (op == "/" || op == "%") && lok && rok:
+		if rf == 0 {
+			return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", describe(l), describe(r))
+		}
+		if op == "/" {
+			return lf / rf, nil
+		}
+		return float64(int(lf) % int(rf)), nil
+	case op == "/":
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if lok && rok {
+			var out []interface{}
+			for _, part := range strings.Split(ls, rs) {
+				out = append(out, part)
+			}
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("%s and %s cannot be combined with %s", describe(l), describe(r), op)
+}
+
+// add implements +, which also concatenates strings and arrays and merges objects. null is the identity.
+func add(l, r interface{}) (interface{}, error) {
+	if l == nil {
+		return r, nil
+	}
+	if r == nil {
+		return l, nil
+	}
+	if lf, ok := toFloat(l); ok {
+		if rf, ok := toFloat(r); ok {
+			return lf + rf, nil
+		}
+	}
+	switch lv := l.(type) {
+	case string:
+		if rv, ok := r.(string); ok {
+			return lv + rv, nil
+		}
+	case []interface{}:
+		if rv, ok := r.([]interface{}); ok {
+			return append(append([]interface{}{}, lv...), rv...), nil
+		}
+	case map[string]interface{}:
+		if rv, ok := r.(map[string]interface{}); ok {
+			out := make(map[string]interface{}, len(lv)+len(rv))
+			for k, v := range lv {
+				out[k] = v
+			}
+			for k, v := range rv {
+				out[k] = v
+			}
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("%s and %s cannot be added", describe(l), describe(r))
+}
+
+// typeRank orders values of different types: null < false < true < numbers < strings < arrays < objects.
+func typeRank(v interface{}) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if t {
+			return 2
+		}
+		return 1
+	case float64, json.Number:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+// compare orders two values the way jq does, returning -1, 0 or 1.
+func compare(l, r interface{}) int {
+	lr, rr := typeRank(l), typeRank(r)
+	if lr != rr {
+		return sign(float64(lr - rr))
+	}
+	switch lv := l.(type) {
+	case string:
+		return strings.Compare(lv, r.(string))
+	case []interface{}:
+		rv := r.([]interface{})
+		for i := 0; i < len(lv) && i < len(rv); i++ {
+			if c := compare(lv[i], rv[i]); c != 0 {
+				return c
+			}
+		}
+		return sign(float64(len(lv) - len(rv)))
+	case map[string]interface{}:
+		rv := r.(map[string]interface{})
+		lk, rk := sortedKeys(lv), sortedKeys(rv)
+		lki, rki := make([]interface{}, len(lk)), make([]interface{}, len(rk))
+		for i := range lk {
+			lki[i] = lk[i]
+		}
+		for i := range rk {
+			rki[i] = rk[i]
+		}
+		if c := compare(lki, rki); c != 0 {
+			return c
+		}
+		for _, k := range lk {
+			if c := compare(lv[k], rv[k]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	if lr == 3 {
+		lf, _ := toFloat(l)
+		rf, _ := toFloat(r)
+		return sign(lf - rf)
+	}
+	return 0
+}
+
+// sign returns the sign of f as -1, 0 or 1.
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+// truthy reports whether v counts as true: everything but false and null does.
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	}
+	return true
+}
+
+// toFloat converts a JSON number to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// typeOf returns the jq type name of v.
+func typeOf(v interface{}) string {
+	return []string{"null", "boolean", "boolean", "number", "string", "array", "object"}[typeRank(v)]
+}
+
+// describe renders v for use in an error message, e.g. string ("foo").
+func describe(v interface{}) string {
+	b, _ := json.Marshal(v)
+	s := string(b)
+	if len(s) > 11 {
+		s = s[:10] + "..."
+	}
+	return fmt.Sprintf("%s (%s)", typeOf(v), s)
+}
+
+// sortedKeys returns the keys of an object in sorted order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// first returns the first of vals, or nil if there are none.
+func first(vals []interface{}) interface{} {
+	if len(vals) == 0 {
+		return nil
+	}
+	return vals[0]
+}
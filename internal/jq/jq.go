@@ -0,0 +1,188 @@
+package jq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrNotJSON = errors.New("body isn't valid JSON") // Error for filtering a body that isn't JSON.
+	// jsonPathSegRe matches one segment of a JSONPath: ..name, .name, .*, [*], [?(...)] or [...].
+	jsonPathSegRe = regexp.MustCompile(`^(\.\.[A-Za-z_$][\w$]*|\.\.\*|\.\.|\.[A-Za-z_$][\w$]*|\.\*|\[\*\]|\[\?\((.*?)\)\]|\[[^\]]*\])`)
+)
+
+// Program is a compiled filter, supporting a subset of the jq language: paths (.a.b, ."a", .[0], .[1:3],
+// .[], ..), the ? suffix, pipes, commas, literals, comparisons, arithmetic, and/or, the // alternative
+// operator, array and object construction, and common builtins such as select, map, length and keys.
+type Program struct {
+	src  string
+	root node
+}
+
+// Compile parses a filter into a Program.
+func Compile(src string) (*Program, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return &Program{src: src, root: identityNode{}}, nil
+	}
+	root, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return &Program{src: src, root: root}, nil
+}
+
+// String returns the source of the filter.
+func (p *Program) String() string {
+	return p.src
+}
+
+// Run runs the filter against a decoded JSON value and returns every output.
+func (p *Program) Run(in interface{}) ([]interface{}, error) {
+	return p.root.eval(in)
+}
+
+// RunJSON decodes a JSON body and runs the filter against every top-level value in it.
+func (p *Program) RunJSON(body []byte) ([]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var out []interface{}
+	decoded := false
+	for {
+		var in interface{}
+		err := dec.Decode(&in)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotJSON, err.Error())
+		}
+		decoded = true
+		vals, err := p.Run(in)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vals...)
+	}
+	if !decoded {
+		return nil, fmt.Errorf("%w: body is empty", ErrNotJSON)
+	}
+	return out, nil
+}
+
+// Encode renders filter outputs one per line. With raw set, strings are written without quotes.
+func Encode(vals []interface{}, raw bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, v := range vals {
+		if s, ok := v.(string); ok && raw {
+			buf.WriteString(s + "\n")
+			continue
+		}
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// FromJSONPath translates a JSONPath expression, such as $.store.book[?(@.price < 10)].title, into the
+// equivalent jq filter.
+func FromJSONPath(path string) (string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return "", fmt.Errorf("json path %q must start with $", path)
+	}
+	var stages []string
+	for rest := path[1:]; len(rest) > 0; {
+		m := jsonPathSegRe.FindStringSubmatch(rest)
+		if m == nil {
+			return "", fmt.Errorf("json path %q malformed near %q", path, rest)
+		}
+		seg := m[1]
+		rest = rest[len(seg):]
+		switch {
+		case seg == "..", seg == "..*":
+			stages = append(stages, "..")
+		case strings.HasPrefix(seg, ".."):
+			name := seg[2:]
+			stages = append(stages, fmt.Sprintf(`.. | select(type == "object" and has(%s)) | .[%s]`, quote(name), quote(name)))
+		case seg == ".*", seg == "[*]":
+			stages = append(stages, ".[]")
+		case strings.HasPrefix(seg, "."):
+			stages = append(stages, "."+quote(seg[1:]))
+		case strings.HasPrefix(seg, "[?("):
+			stages = append(stages, ".[] | select("+jsonPathFilter(m[2])+")")
+		default:
+			var members []string
+			for _, member := range strings.Split(seg[1:len(seg)-1], ",") {
+				member = strings.TrimSpace(member)
+				if strings.HasPrefix(member, "'") || strings.HasPrefix(member, "\"") {
+					member = quote(strings.Trim(member, `'"`))
+				}
+				if strings.Contains(member, ":") {
+					// JSONPath slices select elements, where jq slices produce an array
+					members = append(members, ".["+member+"][]")
+					continue
+				}
+				members = append(members, ".["+member+"]")
+			}
+			if len(members) == 1 {
+				stages = append(stages, members[0])
+			} else {
+				stages = append(stages, "("+strings.Join(members, ", ")+")")
+			}
+		}
+	}
+	if len(stages) == 0 {
+		return ".", nil
+	}
+	return strings.Join(stages, " | "), nil
+}
+
+// jsonPathFilter translates the expression of a [?(...)] filter into jq.
+func jsonPathFilter(expr string) string {
+	var sb strings.Builder
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '@':
+			if i+1 >= len(expr) || expr[i+1] != '.' {
+				sb.WriteByte('.')
+			}
+		case c == '\'':
+			end := strings.IndexByte(expr[i+1:], '\'')
+			if end < 0 {
+				end = len(expr) - i - 1
+			}
+			sb.WriteString(quote(expr[i+1 : i+1+end]))
+			i += end + 1
+		case strings.HasPrefix(expr[i:], "&&"):
+			sb.WriteString("and")
+			i++
+		case strings.HasPrefix(expr[i:], "||"):
+			sb.WriteString("or")
+			i++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// quote renders s as a JSON string literal.
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,161 @@
+package jq
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var (
+	// testBody is the JSON document the test filters run against.
+	testBody = `{
+  "status": "ok",
+  "count": 3,
+  "tags": ["a", "b", "a"],
+  "users": [
+    {"name": "ada", "age": 36, "admin": true},
+    {"name": "bob", "age": 17, "admin": false},
+    {"name": "cy", "age": 52, "admin": false, "email": "cy@example.com"}
+  ]
+}`
+	// testFilters maps a filter to its expected output when run over testBody, encoded one value per line.
+	testFilters = map[string]string{
+		``:                                         "",
+		`.status`:                                  `"ok"` + "\n",
+		`."status"`:                                `"ok"` + "\n",
+		`.users[0].name`:                           `"ada"` + "\n",
+		`.users[-1].name`:                          `"cy"` + "\n",
+		`.users[].name`:                            "\"ada\"\n\"bob\"\n\"cy\"\n",
+		`.users[1:].[0].age`:                       "17\n",
+		`.missing`:                                 "null\n",
+		`.missing.deeper`:                          "null\n",
+		`.users | length`:                          "3\n",
+		`.count + 1, .count * 2`:                   "4\n6\n",
+		`.users[] | select(.age > 18) | .name`:     "\"ada\"\n\"cy\"\n",
+		`.users[] | select(.admin).name`:           `"ada"` + "\n",
+		`[.users[] | select(.email) | .name]`:      `["cy"]` + "\n",
+		`.users | map(.age)`:                       "[36,17,52]\n",
+		`{status, n: .count}`:                      `{"n":3,"status":"ok"}` + "\n",
+		`{(.status): .count}`:                      `{"ok":3}` + "\n",
+		`.tags | unique`:                           `["a","b"]` + "\n",
+		`.tags | join("-")`:                        `"a-b-a"` + "\n",
+		`.users[0] | keys`:                         `["admin","age","name"]` + "\n",
+		`.users[0] | has("email")`:                 "false\n",
+		`.users[2].email // "none"`:                `"cy@example.com"` + "\n",
+		`.users[0].email // "none"`:                `"none"` + "\n",
+		`.status == "ok" and .count >= 3`:          "true\n",
+		`.users[] | .name | test("^[ab]")`:         "true\ntrue\nfalse\n",
+		`.users | map(.name | ascii_upcase)`:       `["ADA","BOB","CY"]` + "\n",
+		`.count | tostring`:                        `"3"` + "\n",
+		`.tags | contains(["b"])`:                  "true\n",
+		`.status[0]?`:                              "",
+		`[.users[].age] | add`:                     "105\n",
+		`[..  | .name? | select(. != null)]`:       `["ada","bob","cy"]` + "\n",
+		`.users[0] | to_entries | .[0].key`:        `"admin"` + "\n",
+		`.users | first.name, last.name`:           "\"ada\"\n\"cy\"\n",
+		`.users[] | select(.name == "bob") | .age`: "17\n",
+	}
+	// testBadFilters lists filters that must fail to compile.
+	testBadFilters = []string{
+		`.users[`,
+		`.status |`,
+		`nosuchfunction`,
+		`select(.a; .b)`,
+		`{"a" .b}`,
+		`"unterminated`,
+		`.a ~ .b`,
+	}
+	// testJSONPaths maps a JSONPath expression to its expected output when run over testBody.
+	testJSONPaths = map[string]string{
+		`$`:                                "",
+		`$.status`:                         `"ok"` + "\n",
+		`$.users[0].name`:                  `"ada"` + "\n",
+		`$.users[*].age`:                   "36\n17\n52\n",
+		`$['status']`:                      `"ok"` + "\n",
+		`$.users[0,2].name`:                "\"ada\"\n\"cy\"\n",
+		`$.users[1:2].name`:                `"bob"` + "\n",
+		`$..email`:                         `"cy@example.com"` + "\n",
+		`$.users[?(@.age < 18)].name`:      `"bob"` + "\n",
+		`$.users[?(@.name == 'cy')].age`:   "52\n",
+		`$.users[?(@.admin && @.age > 1)]`: `{"admin":true,"age":36,"name":"ada"}` + "\n",
+	}
+)
+
+// TestCompile checks that filters produce the expected outputs over testBody.
+func TestCompile(t *testing.T) {
+	for filter, expected := range testFilters {
+		prog, err := Compile(filter)
+		if !assert.NoError(t, err, filter) {
+			continue
+		}
+		vals, err := prog.RunJSON([]byte(testBody))
+		if !assert.NoError(t, err, filter) {
+			continue
+		}
+		if len(filter) == 0 {
+			assert.Len(t, vals, 1, filter)
+			continue
+		}
+		out, err := Encode(vals, false)
+		assert.NoError(t, err, filter)
+		assert.Equal(t, expected, string(out), filter)
+	}
+	for _, filter := range testBadFilters {
+		_, err := Compile(filter)
+		assert.Error(t, err, filter)
+	}
+}
+
+// TestRunJSON checks that bodies that aren't JSON are reported as such, and that runtime errors are not.
+func TestRunJSON(t *testing.T) {
+	prog, err := Compile(".a")
+	assert.NoError(t, err)
+	for _, body := range []string{"", "<html></html>", `{"a": 1`, "plain text"} {
+		_, err = prog.RunJSON([]byte(body))
+		assert.ErrorIs(t, err, ErrNotJSON, body)
+	}
+	vals, err := prog.RunJSON([]byte(`{"a": 1} {"a": 2}`))
+	assert.NoError(t, err)
+	assert.Len(t, vals, 2)
+	_, err = prog.RunJSON([]byte(`[1, 2]`))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotJSON)
+}
+
+// TestEncode checks that raw encoding leaves strings unquoted and everything else as JSON.
+func TestEncode(t *testing.T) {
+	vals := []interface{}{"a<b", 1.5, map[string]interface{}{"k": "v"}}
+	out, err := Encode(vals, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "\"a<b\"\n1.5\n{\"k\":\"v\"}\n", string(out))
+	out, err = Encode(vals, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "a<b\n1.5\n{\"k\":\"v\"}\n", string(out))
+}
+
+// TestFromJSONPath checks that JSONPath expressions translate into filters with the same meaning.
+func TestFromJSONPath(t *testing.T) {
+	for path, expected := range testJSONPaths {
+		filter, err := FromJSONPath(path)
+		if !assert.NoError(t, err, path) {
+			continue
+		}
+		prog, err := Compile(filter)
+		if !assert.NoError(t, err, path+" -> "+filter) {
+			continue
+		}
+		vals, err := prog.RunJSON([]byte(testBody))
+		if !assert.NoError(t, err, path+" -> "+filter) {
+			continue
+		}
+		if len(expected) == 0 && path == "$" {
+			assert.Len(t, vals, 1)
+			continue
+		}
+		out, _ := Encode(vals, false)
+		assert.Equal(t, expected, string(out), path+" -> "+filter)
+	}
+	for _, path := range []string{"users", "$.users[", "$!"} {
+		_, err := FromJSONPath(path)
+		assert.Error(t, err, path)
+	}
+}
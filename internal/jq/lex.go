@@ -0,0 +1,112 @@
+package jq
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	tokEOF     = iota // End of the filter.
+	tokDot            // "." on its own.
+	tokField          // ".name", with the name as text.
+	tokRecurse        // "..".
+	tokIdent          // Bare identifier, e.g. select, true, and.
+	tokNumber         // Number literal.
+	tokString         // String literal, with the unquoted string as text.
+	tokPunct          // Operator or punctuation, e.g. | , ( == //.
+)
+
+// token is a single lexical token of a filter.
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// punctuation lists every operator, longest first so that the lexer matches greedily.
+var punctuation = []string{"//", "==", "!=", "<=", ">=", "|", ",", "(", ")", "[", "]", "{", "}", ":", ";", "?", "<", ">", "+", "-", "*", "/", "%"}
+
+// lex splits a filter into tokens.
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '.':
+			switch {
+			case i+1 < len(src) && src[i+1] == '.':
+				toks = append(toks, token{tokRecurse, "..", i})
+				i += 2
+			case i+1 < len(src) && isIdentStart(src[i+1]):
+				j := i + 1
+				for j < len(src) && isIdentChar(src[j]) {
+					j++
+				}
+				toks = append(toks, token{tokField, src[i+1 : j], i})
+				i = j
+			default:
+				toks = append(toks, token{tokDot, ".", i})
+				i++
+			}
+		case isIdentStart(c):
+			j := i
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j], i})
+			i = j
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			toks = append(toks, token{tokNumber, src[i:j], i})
+			i = j
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			var s string
+			if err := json.Unmarshal([]byte(src[i:j+1]), &s); err != nil {
+				return nil, fmt.Errorf("bad string at position %d: %w", i, err)
+			}
+			toks = append(toks, token{tokString, s, i})
+			i = j + 1
+		default:
+			matched := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(src[i:], p) {
+					toks = append(toks, token{tokPunct, p, i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src)}), nil
+}
+
+// isIdentStart reports whether c may start an identifier.
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isIdentChar reports whether c may continue an identifier.
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
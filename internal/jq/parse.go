@@ -0,0 +1,368 @@
+package jq
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// node is a parsed filter expression. eval runs it against a single input and returns every output.
+type node interface {
+	eval(in interface{}) ([]interface{}, error)
+}
+
+// parser is a recursive descent parser over the tokens of a filter. Precedence, loosest first:
+// | then , then // then or then and then comparisons then + - then * / %.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// peek returns the current token without consuming it.
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+// next consumes and returns the current token.
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isPunct reports whether the current token is the punctuation s.
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+// isIdent reports whether the current token is the identifier s.
+func (p *parser) isIdent(s string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.text == s
+}
+
+// expect consumes the punctuation s, or fails.
+func (p *parser) expect(s string) error {
+	if !p.isPunct(s) {
+		return p.errorf("expected %q", s)
+	}
+	p.next()
+	return nil
+}
+
+// errorf returns a syntax error pointing at the current token.
+func (p *parser) errorf(format string, a ...interface{}) error {
+	t := p.peek()
+	at := "end of filter"
+	if t.kind != tokEOF {
+		at = fmt.Sprintf("%q at position %d", t.text, t.pos)
+	}
+	return fmt.Errorf("syntax error: %s, near %s", fmt.Sprintf(format, a...), at)
+}
+
+// parsePipe parses a | separated chain.
+func (p *parser) parsePipe() (node, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("|") {
+		p.next()
+		right, err := p.parseComma()
+		if err != nil {
+			return nil, err
+		}
+		left = &pipeNode{left, right}
+	}
+	return left, nil
+}
+
+// parseComma parses a , separated list of alternatives.
+func (p *parser) parseComma() (node, error) {
+	left, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct(",") {
+		p.next()
+		right, err := p.parseAlt()
+		if err != nil {
+			return nil, err
+		}
+		left = &commaNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAlt parses the // alternative operator.
+func (p *parser) parseAlt() (node, error) {
+	return p.parseBinary(p.parseOr, "//")
+}
+
+// parseOr parses the or operator.
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary(p.parseAnd, "or")
+}
+
+// parseAnd parses the and operator.
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseCompare, "and")
+}
+
+// parseCompare parses a single, non-associative comparison.
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.isPunct(op) {
+			p.next()
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{op, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+// parseAdditive parses + and -.
+func (p *parser) parseAdditive() (node, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+// parseMultiplicative parses *, / and %.
+func (p *parser) parseMultiplicative() (node, error) {
+	return p.parseBinary(p.parsePostfix, "*", "/", "%")
+}
+
+// parseBinary parses a left associative chain of the given operators over operands parsed by operand.
+func (p *parser) parseBinary(operand func() (node, error), ops ...string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		for _, o := range ops {
+			if p.isPunct(o) || p.isIdent(o) {
+				op = o
+			}
+		}
+		if len(op) == 0 {
+			return left, nil
+		}
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op, left, right}
+	}
+}
+
+// parsePostfix parses a term followed by any number of .field, [index], [] and ? suffixes.
+func (p *parser) parsePostfix() (node, error) {
+	term, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokField:
+			p.next()
+			term = &indexNode{target: term, key: &literalNode{t.text}}
+		case t.kind == tokDot && p.toks[p.pos+1].kind == tokString:
+			p.next()
+			term = &indexNode{target: term, key: &literalNode{p.next().text}}
+		case t.kind == tokDot && p.toks[p.pos+1].kind == tokPunct && p.toks[p.pos+1].text == "[":
+			p.next()
+		case p.isPunct("["):
+			if term, err = p.parseBracket(term); err != nil {
+				return nil, err
+			}
+		case p.isPunct("?"):
+			p.next()
+			term = &tryNode{term}
+		default:
+			return term, nil
+		}
+	}
+}
+
+// parseBracket parses the [], [index] and [from:to] suffixes applied to target.
+func (p *parser) parseBracket(target node) (node, error) {
+	p.next()
+	if p.isPunct("]") {
+		p.next()
+		return &iterateNode{target}, nil
+	}
+	var from, to node
+	var err error
+	if !p.isPunct(":") {
+		if from, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isPunct(":") {
+		p.next()
+		if !p.isPunct("]") {
+			if to, err = p.parsePipe(); err != nil {
+				return nil, err
+			}
+		}
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &sliceNode{target, from, to}, nil
+	}
+	if err = p.expect("]"); err != nil {
+		return nil, err
+	}
+	return &indexNode{target: target, key: from}, nil
+}
+
+// parseTerm parses a single term: a path, literal, parenthesised filter, construction or function call.
+func (p *parser) parseTerm() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokDot:
+		if p.peek().kind == tokString {
+			return &indexNode{target: identityNode{}, key: &literalNode{p.next().text}}, nil
+		}
+		return identityNode{}, nil
+	case tokField:
+		return &indexNode{target: identityNode{}, key: &literalNode{t.text}}, nil
+	case tokRecurse:
+		return recurseNode{}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("syntax error: bad number %q at position %d", t.text, t.pos)
+		}
+		return &literalNode{f}, nil
+	case tokString:
+		return &literalNode{t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "null":
+			return &literalNode{nil}, nil
+		}
+		return p.parseCall(t)
+	case tokPunct:
+		switch t.text {
+		case "(":
+			inner, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			if p.isPunct("]") {
+				p.next()
+				return &arrayNode{}, nil
+			}
+			inner, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			return &arrayNode{inner}, p.expect("]")
+		case "{":
+			return p.parseObject()
+		case "-":
+			operand, err := p.parsePostfix()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{"-", &literalNode{0.0}, operand}, nil
+		}
+	}
+	p.pos--
+	return nil, p.errorf("unexpected token")
+}
+
+// parseCall parses a call of a builtin, with its ; separated arguments if any.
+func (p *parser) parseCall(name token) (node, error) {
+	call := &callNode{name: name.text}
+	if p.isPunct("(") {
+		p.next()
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if !p.isPunct(";") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	b, ok := builtins[call.name]
+	if !ok || b.arity != len(call.args) {
+		return nil, fmt.Errorf("syntax error: %s/%d is not defined, at position %d", call.name, len(call.args), name.pos)
+	}
+	return call, nil
+}
+
+// parseObject parses an object construction, after its opening brace.
+func (p *parser) parseObject() (node, error) {
+	obj := &objectNode{}
+	for !p.isPunct("}") {
+		var entry objectEntry
+		t := p.next()
+		switch {
+		case t.kind == tokIdent || t.kind == tokString:
+			entry.key = &literalNode{t.text}
+		case t.kind == tokField:
+			// {.name} is shorthand for {name: .name}
+			entry.key = &literalNode{t.text}
+			entry.value = &indexNode{target: identityNode{}, key: &literalNode{t.text}}
+		case t.kind == tokPunct && t.text == "(":
+			key, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			entry.key = key
+		default:
+			p.pos--
+			return nil, p.errorf("bad object key")
+		}
+		if p.isPunct(":") {
+			p.next()
+			value, err := p.parseAlt()
+			if err != nil {
+				return nil, err
+			}
+			entry.value = value
+		} else if entry.value == nil {
+			lit, ok := entry.key.(*literalNode)
+			if !ok {
+				return nil, p.errorf("computed object key needs a value")
+			}
+			// {name} is shorthand for {name: .name}
+			entry.value = &indexNode{target: identityNode{}, key: lit}
+		}
+		obj.entries = append(obj.entries, entry)
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	return obj, p.expect("}")
+}
@@ -0,0 +1,127 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kind tells how a value differs between the two sides of a diff.
+type Kind string
+
+var (
+	Added   Kind = "+" // The value is only on the right side.
+	Removed Kind = "-" // The value is only on the left side.
+	Changed Kind = "~" // The value differs between the sides.
+)
+
+// Change is a difference between two JSON values, at the path of the value that differs.
+type Change struct {
+	Path string
+	Kind Kind
+	Old  interface{} // Value on the left side. Nil for added values.
+	New  interface{} // Value on the right side. Nil for removed values.
+}
+
+// String renders the change on a line, e.g. ~ $.user.name: "ann" -> "bob".
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return string(c.Kind) + " " + c.Path + ": " + encode(c.New)
+	case Removed:
+		return string(c.Kind) + " " + c.Path + ": " + encode(c.Old)
+	}
+	return string(c.Kind) + " " + c.Path + ": " + encode(c.Old) + " -> " + encode(c.New)
+}
+
+// Diff compares two values decoded from JSON, as by encoding/json into an interface{}, and returns their
+// differences sorted by path. Object members are matched by key, and array elements by index.
+func Diff(a, b interface{}) []Change {
+	var changes []Change
+	diff("$", a, b, &changes)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// diff appends the differences between a and b, found at path, to changes.
+func diff(path string, a, b interface{}, changes *[]Change) {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		if b, ok := b.(map[string]interface{}); ok {
+			for k, av := range a {
+				if bv, ok := b[k]; ok {
+					diff(FormatKey(path, k), av, bv, changes)
+				} else {
+					*changes = append(*changes, Change{Path: FormatKey(path, k), Kind: Removed, Old: av})
+				}
+			}
+			for k, bv := range b {
+				if _, ok := a[k]; !ok {
+					*changes = append(*changes, Change{Path: FormatKey(path, k), Kind: Added, New: bv})
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok {
+			for i := 0; i < len(a) || i < len(b); i++ {
+				switch {
+				case i >= len(b):
+					*changes = append(*changes, Change{Path: FormatIndex(path, i), Kind: Removed, Old: a[i]})
+				case i >= len(a):
+					*changes = append(*changes, Change{Path: FormatIndex(path, i), Kind: Added, New: b[i]})
+				default:
+					diff(FormatIndex(path, i), a[i], b[i], changes)
+				}
+			}
+			return
+		}
+	}
+	if !equal(a, b) {
+		*changes = append(*changes, Change{Path: path, Kind: Changed, Old: a, New: b})
+	}
+}
+
+// equal reports whether two scalars decoded from JSON are equal. Numbers compare by value, whether
+// decoded as float64 or json.Number.
+func equal(a, b interface{}) bool {
+	if an, ok := number(a); ok {
+		bn, ok := number(b)
+		return ok && an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// number returns the value of a number decoded from JSON.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Format renders every change on its own line.
+func Format(changes []Change) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		sb.WriteString(c.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// encode renders a value as compact JSON.
+func encode(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "?"
+	}
+	return string(b)
+}
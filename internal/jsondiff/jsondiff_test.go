@@ -0,0 +1,68 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// decode decodes a JSON document for the tests.
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	assert.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+// TestDiff checks that added, removed and changed values are found at their path, in path order.
+func TestDiff(t *testing.T) {
+	a := decode(t, `{"id": 1, "user": {"name": "ann", "tags": ["a", "b"]}, "gone": true, "odd key": 1}`)
+	b := decode(t, `{"id": 1.0, "user": {"name": "bob", "tags": ["a"]}, "new": null, "odd key": "1"}`)
+	assert.Equal(t, `- $.gone: true
++ $.new: null
+~ $.user.name: "ann" -> "bob"
+- $.user.tags[1]: "b"
+~ $["odd key"]: 1 -> "1"
+`, Format(Diff(a, b)))
+	assert.Empty(t, Diff(a, decode(t, `{"odd key": 1, "gone": true, "user": {"tags": ["a", "b"], "name": "ann"}, "id": 1}`)))
+	assert.Equal(t, []Change{{Path: "$", Kind: Changed, Old: "a", New: []interface{}{}}}, Diff("a", []interface{}{}))
+}
+
+// TestEqualNumbers checks that numbers compare by value, however they were decoded.
+func TestEqualNumbers(t *testing.T) {
+	assert.Empty(t, Diff(json.Number("1.50"), 1.5))
+	assert.Len(t, Diff(json.Number("1"), "1"), 1)
+}
+
+// TestParsePath checks that every supported form parses, and malformed paths don't.
+func TestParsePath(t *testing.T) {
+	for _, src := range []string{"$", "$.a.b", "a[0]", "$['odd key'][*]", `$["x"].*`, "$..id", "$.items[*].id"} {
+		p, err := ParsePath(src)
+		assert.NoError(t, err, src)
+		assert.Equal(t, src, p.String())
+	}
+	for _, src := range []string{"$.", "$..", "$[", "$[-1]", "$[x]", "$a"} {
+		_, err := ParsePath(src)
+		assert.Error(t, err, src)
+	}
+}
+
+// TestReplace checks that every value matched by a path is replaced, and nothing else.
+func TestReplace(t *testing.T) {
+	hide := func(interface{}) interface{} { return "x" }
+	for src, want := range map[string]string{
+		"$.id":              `{"id":"x","items":[{"at":3,"id":2},{"at":5,"id":4}],"meta":{"at":6}}`,
+		"$.items[*].id":     `{"id":1,"items":[{"at":3,"id":"x"},{"at":5,"id":"x"}],"meta":{"at":6}}`,
+		"$.items[1]":        `{"id":1,"items":[{"at":3,"id":2},"x"],"meta":{"at":6}}`,
+		"$..at":             `{"id":1,"items":[{"at":"x","id":2},{"at":"x","id":4}],"meta":{"at":"x"}}`,
+		"$.meta.*":          `{"id":1,"items":[{"at":3,"id":2},{"at":5,"id":4}],"meta":{"at":"x"}}`,
+		"$.missing[0].deep": `{"id":1,"items":[{"at":3,"id":2},{"at":5,"id":4}],"meta":{"at":6}}`,
+		"$":                 `"x"`,
+	} {
+		p, err := ParsePath(src)
+		assert.NoError(t, err)
+		v := p.Replace(decode(t, `{"id":1,"items":[{"at":3,"id":2},{"at":5,"id":4}],"meta":{"at":6}}`), hide)
+		b, err := json.Marshal(v)
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(b), src)
+	}
+}
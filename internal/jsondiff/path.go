@@ -0,0 +1,151 @@
+package jsondiff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// identRe matches keys that can be written in dot notation in a path.
+	identRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$-]*$`)
+)
+
+// segment is a step of a Path: an object key, an array index, a wildcard matching every member or
+// element, or a descent matching a key at any depth.
+type segment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+	descent  bool
+}
+
+// Path is a parsed JSONPath expression, in the subset made of $, .key, ['key'], [n], [*], .* and ..key.
+type Path struct {
+	src  string
+	segs []segment
+}
+
+// ParsePath parses a JSONPath expression, such as $.items[*].id or $..updated_at. The leading $ is optional.
+func ParsePath(src string) (Path, error) {
+	p := Path{src: src}
+	s := strings.TrimSpace(src)
+	if strings.HasPrefix(s, "$") {
+		s = s[1:]
+	} else if len(s) > 0 && s[0] != '.' && s[0] != '[' {
+		s = "." + s
+	}
+	for len(s) > 0 {
+		var seg segment
+		switch {
+		case strings.HasPrefix(s, ".."):
+			s = s[2:]
+			seg.descent = true
+			n := nameLen(s)
+			if n == 0 {
+				return Path{}, fmt.Errorf("path %s: .. must be followed by a key", src)
+			}
+			seg.key, s = s[:n], s[n:]
+		case strings.HasPrefix(s, ".*"):
+			seg.wildcard, s = true, s[2:]
+		case strings.HasPrefix(s, "."):
+			s = s[1:]
+			n := nameLen(s)
+			if n == 0 {
+				return Path{}, fmt.Errorf("path %s: . must be followed by a key", src)
+			}
+			seg.key, s = s[:n], s[n:]
+		case strings.HasPrefix(s, "["):
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return Path{}, fmt.Errorf("path %s: unterminated [", src)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				seg.wildcard = true
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				seg.key = inner[1 : len(inner)-1]
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil || i < 0 {
+					return Path{}, fmt.Errorf("path %s: [%s] isn't an index, a quoted key or *", src, inner)
+				}
+				seg.index, seg.isIndex = i, true
+			}
+		default:
+			return Path{}, fmt.Errorf("path %s: unexpected %q", src, s)
+		}
+		p.segs = append(p.segs, seg)
+	}
+	return p, nil
+}
+
+// nameLen returns the length of the key at the start of s, in dot notation.
+func nameLen(s string) int {
+	n := strings.IndexAny(s, ".[")
+	if n < 0 {
+		n = len(s)
+	}
+	return n
+}
+
+// String returns the expression the path was parsed from.
+func (p Path) String() string {
+	return p.src
+}
+
+// Replace replaces every value of v matched by the path with the value returned by with, which is passed the
+// matched value. It returns v, with the replacements made in place; replacing the root returns the replacement.
+func (p Path) Replace(v interface{}, with func(old interface{}) interface{}) interface{} {
+	return replace(v, p.segs, with)
+}
+
+// replace replaces the values of v matched by segs.
+func replace(v interface{}, segs []segment, with func(interface{}) interface{}) interface{} {
+	if len(segs) == 0 {
+		return with(v)
+	}
+	seg, rest := segs[0], segs[1:]
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			switch {
+			case seg.descent:
+				if k == seg.key {
+					node[k] = replace(child, rest, with)
+				} else {
+					node[k] = replace(child, segs, with)
+				}
+			case seg.wildcard || (!seg.isIndex && k == seg.key):
+				node[k] = replace(child, rest, with)
+			}
+		}
+	case []interface{}:
+		for i, child := range node {
+			switch {
+			case seg.descent:
+				node[i] = replace(child, segs, with)
+			case seg.wildcard || (seg.isIndex && i == seg.index):
+				node[i] = replace(child, rest, with)
+			}
+		}
+	}
+	return v
+}
+
+// FormatKey appends an object key to a path, in dot notation when possible.
+func FormatKey(path, key string) string {
+	if identRe.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+// FormatIndex appends an array index to a path.
+func FormatIndex(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
@@ -0,0 +1,224 @@
+package httparser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	GlobMaxUrls      = 100000                                                                // Maximum number of urls a single pattern may expand into.
+	ErrGlobUnmatched = errors.New("glob: unmatched bracket or brace")                        // Error for a '[' or '{' without its closing pair.
+	ErrGlobNested    = errors.New("glob: nested globs unsupported")                          // Error for a glob opened inside another glob.
+	ErrGlobRange     = errors.New("glob: bad range")                                         // Error for a malformed [start-end:step] range.
+	ErrGlobTooLarge  = fmt.Errorf("glob: pattern expands to more than %d urls", GlobMaxUrls) // Error for a pattern expanding into too many urls.
+)
+
+// GlobUrl is one url produced by expanding a url pattern, along with the text each glob in the pattern matched.
+type GlobUrl struct {
+	Url     string   // The expanded url.
+	Matches []string // Text matched by each glob, in the order the globs appear in the pattern.
+}
+
+// globPart is a literal piece of a pattern when alternatives is nil, otherwise one glob.
+type globPart struct {
+	literal      string
+	alternatives []string
+}
+
+// ExpandGlob expands every {set} and [range] in pattern into the urls they describe, curl style:
+//
+//	{alpha,beta}   one url per listed alternative
+//	[1-100]        numeric range
+//	[001-100:5]    zero-padded numeric range with a step of 5
+//	[a-z]          letter range
+//
+// A backslash escapes the next character. Urls are returned with the rightmost glob varying fastest.
+// A pattern without globs expands into itself.
+func ExpandGlob(pattern string) ([]GlobUrl, error) {
+	parts, err := parseGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 1
+	for _, p := range parts {
+		if p.alternatives == nil {
+			continue
+		}
+		total *= len(p.alternatives)
+		if total > GlobMaxUrls {
+			return nil, ErrGlobTooLarge
+		}
+	}
+
+	urls := []GlobUrl{{}}
+	for _, p := range parts {
+		if p.alternatives == nil {
+			for i := range urls {
+				urls[i].Url += p.literal
+			}
+			continue
+		}
+		next := make([]GlobUrl, 0, len(urls)*len(p.alternatives))
+		for _, u := range urls {
+			for _, alt := range p.alternatives {
+				matches := append(append([]string{}, u.Matches...), alt)
+				next = append(next, GlobUrl{Url: u.Url + alt, Matches: matches})
+			}
+		}
+		urls = next
+	}
+	return urls, nil
+}
+
+// GlobOutput substitutes every #N in name with the text matched by the Nth glob of the url. References to
+// globs that do not exist are left untouched.
+func GlobOutput(name string, matches []string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '#' {
+			sb.WriteByte(name[i])
+			continue
+		}
+		j := i + 1
+		for j < len(name) && name[j] >= '0' && name[j] <= '9' {
+			j++
+		}
+		n, err := strconv.Atoi(name[i+1 : j])
+		if err != nil || n < 1 || n > len(matches) {
+			sb.WriteByte(name[i])
+			continue
+		}
+		sb.WriteString(matches[n-1])
+		i = j - 1
+	}
+	return sb.String()
+}
+
+// parseGlob splits pattern into its literal pieces and globs.
+func parseGlob(pattern string) (parts []globPart, err error) {
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			parts = append(parts, globPart{literal: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			lit.WriteByte(pattern[i])
+		case '{', '[':
+			closer := byte('}')
+			if c == '[' {
+				closer = ']'
+			}
+			end := strings.IndexByte(pattern[i+1:], closer)
+			if end < 0 {
+				return nil, ErrGlobUnmatched
+			}
+			body := pattern[i+1 : i+1+end]
+			if c == '[' && atHost(pattern[:i]) && isIPLiteral(body) {
+				// like curl, an IPv6 literal host such as [::1] isn't a range
+				lit.WriteString(pattern[i : i+end+2])
+				i += end + 1
+				continue
+			}
+			if strings.ContainsAny(body, "{[") {
+				return nil, ErrGlobNested
+			}
+			var alts []string
+			if c == '{' {
+				alts = strings.Split(body, ",")
+			} else if alts, err = globRange(body); err != nil {
+				return nil, err
+			}
+			flush()
+			parts = append(parts, globPart{alternatives: alts})
+			i += end + 1
+		case '}', ']':
+			return nil, ErrGlobUnmatched
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+// atHost reports whether a url pattern starting with prefix continues with its host: prefix is empty, or
+// holds a scheme, and user info if any.
+func atHost(prefix string) bool {
+	if _, rest, ok := strings.Cut(prefix, "://"); ok {
+		prefix = rest
+	}
+	if at := strings.LastIndexByte(prefix, '@'); at >= 0 {
+		prefix = prefix[at+1:]
+	}
+	return len(prefix) == 0
+}
+
+// isIPLiteral reports whether the body of brackets is an IPv6 address, with a zone such as %25eth0 if any.
+func isIPLiteral(body string) bool {
+	addr, _, _ := strings.Cut(body, "%")
+	return strings.Contains(addr, ":") && net.ParseIP(addr) != nil
+}
+
+// globRange expands the body of a [start-end:step] range.
+func globRange(body string) ([]string, error) {
+	step := 1
+	if rng, stepStr, ok := strings.Cut(body, ":"); ok {
+		s, err := strconv.Atoi(stepStr)
+		if err != nil || s < 1 {
+			return nil, fmt.Errorf("%w: step %q", ErrGlobRange, stepStr)
+		}
+		body, step = rng, s
+	}
+	start, end, ok := strings.Cut(body, "-")
+	if !ok || len(start) == 0 || len(end) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrGlobRange, body)
+	}
+
+	var alts []string
+	// letter range, e.g. [a-z]
+	if len(start) == 1 && len(end) == 1 && isLetter(start[0]) && isLetter(end[0]) {
+		if start[0] > end[0] {
+			return nil, fmt.Errorf("%w: %q", ErrGlobRange, body)
+		}
+		for c := int(start[0]); c <= int(end[0]); c += step {
+			alts = append(alts, string(rune(c)))
+		}
+		return alts, nil
+	}
+
+	lo, errLo := strconv.Atoi(start)
+	hi, errHi := strconv.Atoi(end)
+	if errLo != nil || errHi != nil || lo < 0 || lo > hi {
+		return nil, fmt.Errorf("%w: %q", ErrGlobRange, body)
+	}
+	if (hi-lo)/step+1 > GlobMaxUrls {
+		return nil, ErrGlobTooLarge
+	}
+	// a leading zero on the start value pads every number to its width, e.g. [001-100]
+	width := 0
+	if len(start) > 1 && start[0] == '0' {
+		width = len(start)
+	}
+	for n := lo; n <= hi; n += step {
+		alts = append(alts, fmt.Sprintf("%0*d", width, n))
+	}
+	return alts, nil
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,87 @@
+package httparser
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var (
+	// testGlobs maps a url pattern to the urls it is expected to expand into.
+	testGlobs = map[string][]string{
+		"http://eu.httpbin.org/get": {
+			"http://eu.httpbin.org/get",
+		},
+		"http://eu.httpbin.org/items?page=[1-3]": {
+			"http://eu.httpbin.org/items?page=1",
+			"http://eu.httpbin.org/items?page=2",
+			"http://eu.httpbin.org/items?page=3",
+		},
+		"http://eu.httpbin.org/[001-011:5].json": {
+			"http://eu.httpbin.org/001.json",
+			"http://eu.httpbin.org/006.json",
+			"http://eu.httpbin.org/011.json",
+		},
+		"http://{alpha,beta}.httpbin.org/[a-b]": {
+			"http://alpha.httpbin.org/a",
+			"http://alpha.httpbin.org/b",
+			"http://beta.httpbin.org/a",
+			"http://beta.httpbin.org/b",
+		},
+		"http://eu.httpbin.org/\\[1-2\\]": {
+			"http://eu.httpbin.org/[1-2]",
+		},
+		"http://[::1]:8080/": {
+			"http://[::1]:8080/",
+		},
+		"http://[fe80::1%25eth0]/items/[1-2]": {
+			"http://[fe80::1%25eth0]/items/1",
+			"http://[fe80::1%25eth0]/items/2",
+		},
+		"https://user@[2001:db8::7]/": {
+			"https://user@[2001:db8::7]/",
+		},
+		"[::1]/{a,b}": {
+			"[::1]/a",
+			"[::1]/b",
+		},
+	}
+	// testBadGlobs maps a malformed pattern to the error it is expected to produce.
+	testBadGlobs = map[string]error{
+		"http://eu.httpbin.org/[1-2":                      ErrGlobUnmatched,
+		"http://eu.httpbin.org/{a,b":                      ErrGlobUnmatched,
+		"http://eu.httpbin.org/1-2]":                      ErrGlobUnmatched,
+		"http://eu.httpbin.org/{a,[1-2]}":                 ErrGlobNested,
+		"http://eu.httpbin.org/[5-1]":                     ErrGlobRange,
+		"http://eu.httpbin.org/[1-5:0]":                   ErrGlobRange,
+		"http://eu.httpbin.org/[1-999999]":                ErrGlobTooLarge,
+		"http://eu.httpbin.org/[x]":                       ErrGlobRange,
+		"http://eu.httpbin.org/[::1]":                     ErrGlobRange,
+		"http://eu.httpbin.org/[1-100]/[a-z]/[a-z]/[a-z]": ErrGlobTooLarge,
+	}
+)
+
+// TestExpandGlob checks that patterns expand into the expected urls, rightmost glob varying fastest.
+func TestExpandGlob(t *testing.T) {
+	for pattern, expected := range testGlobs {
+		actual, err := ExpandGlob(pattern)
+		assert.NoError(t, err, pattern)
+		var urls []string
+		for _, u := range actual {
+			urls = append(urls, u.Url)
+		}
+		assert.Equal(t, expected, urls, pattern)
+	}
+	for pattern, expected := range testBadGlobs {
+		_, err := ExpandGlob(pattern)
+		assert.ErrorIs(t, err, expected, pattern)
+	}
+}
+
+// TestGlobOutput checks #N substitution in output file names.
+func TestGlobOutput(t *testing.T) {
+	urls, err := ExpandGlob("http://{alpha,beta}.httpbin.org/[1-2]")
+	assert.NoError(t, err)
+	assert.Equal(t, "beta_2.json", GlobOutput("#1_#2.json", urls[3].Matches))
+	assert.Equal(t, "page#3.json", GlobOutput("page#3.json", urls[3].Matches))
+	assert.Equal(t, "plain.json", GlobOutput("plain.json", urls[3].Matches))
+}
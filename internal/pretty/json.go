@@ -0,0 +1,182 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"sort"
+	"strings"
+)
+
+// jsonNode is a parsed JSON value which, unlike a map, remembers the order of object keys.
+type jsonNode struct {
+	delim json.Delim  // '{' or '[' for containers, zero for scalars.
+	keys  []string    // Object keys, in document order.
+	items []*jsonNode // Object values or array items.
+	value json.Token  // Scalar value.
+}
+
+// palette holds the color functions for each syntax element. Every function is the identity when
+// color is off.
+type palette struct {
+	key, str, num, boolean, null, tag, attr, comment func(a ...interface{}) string
+}
+
+// newPalette builds the palette for opts.
+func newPalette(opts Options) palette {
+	mk := func(attrs ...color.Attribute) func(a ...interface{}) string {
+		if !opts.Color {
+			return fmt.Sprint
+		}
+		c := color.New(attrs...)
+		// color is decided by the caller for stdout, independent of the global setting for stderr
+		c.EnableColor()
+		return c.SprintFunc()
+	}
+	return palette{
+		key:     mk(color.FgBlue, color.Bold),
+		str:     mk(color.FgGreen),
+		num:     mk(color.FgCyan),
+		boolean: mk(color.FgYellow),
+		null:    mk(color.FgMagenta),
+		tag:     mk(color.FgBlue),
+		attr:    mk(color.FgCyan),
+		comment: mk(color.FgHiBlack),
+	}
+}
+
+// JSON indents and colorizes a JSON body. Bodies holding several top-level values, such as NDJSON,
+// have each value formatted in turn.
+func JSON(body []byte, opts Options) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	p := newPalette(opts)
+	var out bytes.Buffer
+	for {
+		n, err := parseJSON(dec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		writeJSON(&out, n, 0, p, opts.SortKeys)
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
+
+// validJSON reports whether body is a sequence of one or more valid JSON values.
+func validJSON(body []byte) bool {
+	_, err := JSON(body, Options{})
+	return err == nil
+}
+
+// parseJSON reads the next JSON value off dec.
+func parseJSON(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return &jsonNode{value: tok}, nil
+	}
+	n := &jsonNode{delim: delim}
+	for dec.More() {
+		if delim == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, key.(string))
+		}
+		child, err := parseJSON(dec)
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, child)
+	}
+	// closing delimiter
+	if _, err = dec.Token(); errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// writeJSON renders n at the given nesting depth.
+func writeJSON(out *bytes.Buffer, n *jsonNode, depth int, p palette, sortKeys bool) {
+	switch n.delim {
+	case '{':
+		if len(n.items) == 0 {
+			out.WriteString("{}")
+			return
+		}
+		order := make([]int, len(n.keys))
+		for i := range order {
+			order[i] = i
+		}
+		if sortKeys {
+			sort.SliceStable(order, func(a, b int) bool { return n.keys[order[a]] < n.keys[order[b]] })
+		}
+		out.WriteString("{\n")
+		for i, idx := range order {
+			out.WriteString(strings.Repeat(Indent, depth+1))
+			out.WriteString(p.key(quoteJSON(n.keys[idx])))
+			out.WriteString(": ")
+			writeJSON(out, n.items[idx], depth+1, p, sortKeys)
+			if i < len(order)-1 {
+				out.WriteByte(',')
+			}
+			out.WriteByte('\n')
+		}
+		out.WriteString(strings.Repeat(Indent, depth) + "}")
+	case '[':
+		if len(n.items) == 0 {
+			out.WriteString("[]")
+			return
+		}
+		out.WriteString("[\n")
+		for i, item := range n.items {
+			out.WriteString(strings.Repeat(Indent, depth+1))
+			writeJSON(out, item, depth+1, p, sortKeys)
+			if i < len(n.items)-1 {
+				out.WriteByte(',')
+			}
+			out.WriteByte('\n')
+		}
+		out.WriteString(strings.Repeat(Indent, depth) + "]")
+	default:
+		switch v := n.value.(type) {
+		case string:
+			out.WriteString(p.str(quoteJSON(v)))
+		case json.Number:
+			out.WriteString(p.num(v.String()))
+		case bool:
+			if v {
+				out.WriteString(p.boolean("true"))
+			} else {
+				out.WriteString(p.boolean("false"))
+			}
+		case nil:
+			out.WriteString(p.null("null"))
+		}
+	}
+}
+
+// quoteJSON quotes s as a JSON string, leaving HTML characters unescaped.
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,135 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// htmlTagRe matches HTML comments and tags.
+	htmlTagRe = regexp.MustCompile(`<!--[\s\S]*?-->|<[^<>]+>`)
+	// htmlTagPartsRe splits a tag into its opening, name, attributes and closing parts.
+	htmlTagPartsRe = regexp.MustCompile(`^(</?!?)([^\s/>]*)([\s\S]*?)(/?>)$`)
+	// htmlAttrRe matches a single attribute, with or without a value.
+	htmlAttrRe = regexp.MustCompile(`([^\s=]+)(\s*=\s*("[^"]*"|'[^']*'|[^\s"']+))?`)
+)
+
+// XML indents and colorizes an XML body.
+func XML(body []byte, opts Options) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	var toks []xml.Token
+	for {
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, xml.CopyToken(tok))
+	}
+
+	p := newPalette(opts)
+	var out bytes.Buffer
+	depth := 0
+	line := func(s string) {
+		out.WriteString(strings.Repeat(Indent, depth) + s + "\n")
+	}
+	for i := 0; i < len(toks); i++ {
+		switch t := toks[i].(type) {
+		case xml.StartElement:
+			open := xmlStart(t, p)
+			// keep <a>text</a> and <a></a> on a single line
+			if i+1 < len(toks) {
+				if _, ok := toks[i+1].(xml.EndElement); ok {
+					line(open + p.tag("</"+xmlName(t.Name)+">"))
+					i++
+					continue
+				}
+			}
+			if i+2 < len(toks) {
+				cd, isText := toks[i+1].(xml.CharData)
+				_, isEnd := toks[i+2].(xml.EndElement)
+				if isText && isEnd {
+					line(open + xmlEscape(strings.TrimSpace(string(cd))) + p.tag("</"+xmlName(t.Name)+">"))
+					i += 2
+					continue
+				}
+			}
+			line(open)
+			depth++
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+			}
+			line(p.tag("</" + xmlName(t.Name) + ">"))
+		case xml.CharData:
+			if text := strings.TrimSpace(string(t)); len(text) > 0 {
+				line(xmlEscape(text))
+			}
+		case xml.Comment:
+			line(p.comment("<!--" + string(t) + "-->"))
+		case xml.ProcInst:
+			line(p.tag("<?" + t.Target + " " + string(t.Inst) + "?>"))
+		case xml.Directive:
+			line(p.tag("<!" + string(t) + ">"))
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// xmlStart renders an opening tag along with its attributes.
+func xmlStart(t xml.StartElement, p palette) string {
+	var sb strings.Builder
+	sb.WriteString(p.tag("<" + xmlName(t.Name)))
+	for _, a := range t.Attr {
+		sb.WriteString(" " + p.attr(xmlName(a.Name)) + "=" + p.str("\""+xmlEscape(a.Value)+"\""))
+	}
+	sb.WriteString(p.tag(">"))
+	return sb.String()
+}
+
+// xmlName renders a raw, unresolved element or attribute name.
+func xmlName(n xml.Name) string {
+	if len(n.Space) > 0 {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// xmlEscape escapes text for use in XML character data or attribute values.
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// HTML colorizes the tags and comments of an HTML body, leaving its layout untouched.
+func HTML(body []byte, opts Options) []byte {
+	if !opts.Color {
+		return body
+	}
+	p := newPalette(opts)
+	return htmlTagRe.ReplaceAllFunc(body, func(tag []byte) []byte {
+		if bytes.HasPrefix(tag, []byte("<!--")) {
+			return []byte(p.comment(string(tag)))
+		}
+		parts := htmlTagPartsRe.FindStringSubmatch(string(tag))
+		if parts == nil {
+			return tag
+		}
+		attrs := htmlAttrRe.ReplaceAllStringFunc(parts[3], func(attr string) string {
+			m := htmlAttrRe.FindStringSubmatch(attr)
+			if len(m[2]) == 0 {
+				return p.attr(m[1])
+			}
+			return p.attr(m[1]) + strings.TrimSuffix(m[2], m[3]) + p.str(m[3])
+		})
+		return []byte(p.tag(parts[1]+parts[2]) + attrs + p.tag(parts[4]))
+	})
+}
@@ -0,0 +1,126 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	KindText   = iota + 1 // Plain text, printed as is.
+	KindJSON              // JSON, indented and colorized.
+	KindXML               // XML, indented and colorized.
+	KindHTML              // HTML, with tags colorized.
+	KindBinary            // Binary content, shown as a hexdump.
+)
+
+var (
+	// Indent is the indentation used for every nesting level of JSON and XML.
+	Indent = "  "
+	// BinaryWarning is shown instead of a binary body that would otherwise be printed to a terminal.
+	BinaryWarning = `Binary output can mess up your terminal. Use "--output -" to tell scour to output it to your terminal anyway, or consider "--output <FILE>" to save to a file.`
+)
+
+// Options controls how a body is formatted.
+type Options struct {
+	Color    bool // Color syntax elements with ANSI escapes.
+	SortKeys bool // Sort the keys of JSON objects.
+}
+
+// Body formats a response body for display in a terminal, according to its content type. Binary bodies,
+// as told by Binary, are shown as a hexdump. Bodies that can't be parsed as the type they claim to be are
+// returned unchanged.
+func Body(contentType string, body []byte, opts Options) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	if Binary(contentType, body) {
+		return []byte(hex.Dump(body))
+	}
+	var (
+		out []byte
+		err error
+	)
+	switch Detect(contentType, body) {
+	case KindJSON:
+		out, err = JSON(body, opts)
+	case KindXML:
+		out, err = XML(body, opts)
+	case KindHTML:
+		out = HTML(body, opts)
+	default:
+		return body
+	}
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// Detect works out the kind of a body from its content type, sniffing the body itself when the content
+// type is missing or too generic to tell.
+func Detect(contentType string, body []byte) int {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.Contains(mediaType, "json"):
+		return KindJSON
+	case strings.Contains(mediaType, "html"):
+		return KindHTML
+	case strings.Contains(mediaType, "xml"):
+		return KindXML
+	case strings.HasPrefix(mediaType, "text/"):
+		return KindText
+	case len(mediaType) > 0 && mediaType != "application/octet-stream":
+		if IsBinary(body) {
+			return KindBinary
+		}
+		return KindText
+	}
+
+	// no usable content type, so sniff
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case IsBinary(body):
+		return KindBinary
+	case (bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("["))) && validJSON(trimmed):
+		return KindJSON
+	}
+	sniffed := http.DetectContentType(body)
+	switch {
+	case strings.HasPrefix(sniffed, "text/html"):
+		return KindHTML
+	case strings.HasPrefix(sniffed, "text/xml"):
+		return KindXML
+	}
+	return KindText
+}
+
+// Binary reports whether a body is binary and unsafe to print to a terminal, judging by its content type
+// and by sniffing it for NUL bytes.
+func Binary(contentType string, body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	head := body
+	if len(head) > 8192 {
+		head = head[:8192]
+	}
+	return bytes.IndexByte(head, 0) >= 0 || Detect(contentType, body) == KindBinary
+}
+
+// IsBinary reports whether a body is binary, i.e. holds a NUL byte or isn't valid UTF-8. Only the first
+// 8KiB are inspected.
+func IsBinary(body []byte) bool {
+	head := body
+	if len(head) > 8192 {
+		head = head[:8192]
+		// don't let a multibyte rune cut at the boundary count as invalid
+		for i := 0; i < utf8.UTFMax && len(head) > 0 && !utf8.Valid(head); i++ {
+			head = head[:len(head)-1]
+		}
+	}
+	return bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(head)
+}
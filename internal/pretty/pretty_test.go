@@ -0,0 +1,110 @@
+package pretty
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var (
+	// testDetect maps a content type and body to the kind they are expected to be detected as.
+	testDetect = []struct {
+		contentType string
+		body        string
+		kind        int
+	}{
+		{"application/json; charset=utf-8", `{"a": 1}`, KindJSON},
+		{"application/problem+json", `{"a": 1}`, KindJSON},
+		{"application/xml", `<a/>`, KindXML},
+		{"text/html", `<html></html>`, KindHTML},
+		{"text/plain", `{"a": 1}`, KindText},
+		{"image/png", "\x89PNG\r\n\x1a\n\x00\x00", KindBinary},
+		{"", `{"a": 1}`, KindJSON},
+		{"", "<!DOCTYPE html><html></html>", KindHTML},
+		{"", `<?xml version="1.0"?><a/>`, KindXML},
+		{"", "f42d83e4-632a-4f3c-a0c1-23e7af3b7d7a", KindText},
+		{"application/octet-stream", "\x00\x01\x02", KindBinary},
+	}
+)
+
+// TestDetect checks content type and sniffing based detection.
+func TestDetect(t *testing.T) {
+	for _, tc := range testDetect {
+		assert.Equal(t, tc.kind, Detect(tc.contentType, []byte(tc.body)), tc.contentType+" "+tc.body)
+	}
+}
+
+// TestBinary checks that binary bodies are caught by content type or NUL bytes, and text bodies aren't.
+func TestBinary(t *testing.T) {
+	assert.True(t, Binary("image/png", []byte("\x89PNG\r\n\x1a\n\x00\x00")))
+	assert.True(t, Binary("", []byte("\x1f\x8b\x08\x00")))
+	assert.True(t, Binary("text/plain", []byte("a\x00b")))
+	assert.False(t, Binary("text/plain", []byte("plain text")))
+	assert.False(t, Binary("application/json", []byte(`{"a": "ü"}`)))
+	assert.False(t, Binary("", []byte("socket response\n")))
+	assert.False(t, Binary("image/png", nil))
+}
+
+// TestJSON checks indentation, key order, key sorting and NDJSON bodies.
+func TestJSON(t *testing.T) {
+	body := []byte(`{"b": [1, 2.5, {}], "a": {"c": null, "d": true, "e": "<x>"}, "f": []}`)
+	out, err := JSON(body, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "b": [
+    1,
+    2.5,
+    {}
+  ],
+  "a": {
+    "c": null,
+    "d": true,
+    "e": "<x>"
+  },
+  "f": []
+}
+`, string(out))
+
+	out, err = JSON(body, Options{SortKeys: true})
+	require.NoError(t, err)
+	assert.True(t, strings.Index(string(out), `"a"`) < strings.Index(string(out), `"b"`))
+
+	out, err = JSON([]byte("{\"a\":1}\n{\"a\":2}\n"), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": 1\n}\n{\n  \"a\": 2\n}\n", string(out))
+
+	_, err = JSON([]byte(`{"a": `), Options{})
+	assert.Error(t, err)
+
+	out, err = JSON(body, Options{Color: true})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "\x1b[")
+}
+
+// TestXML checks indentation of nested elements, inline text and attributes.
+func TestXML(t *testing.T) {
+	out, err := XML([]byte(`<?xml version="1.0"?><root a="1"><item>one &amp; two</item><empty></empty><ns:x/></root>`), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0"?>
+<root a="1">
+  <item>one &amp; two</item>
+  <empty></empty>
+  <ns:x></ns:x>
+</root>
+`, string(out))
+}
+
+// TestBody checks that unparseable and binary bodies are handled safely.
+func TestBody(t *testing.T) {
+	broken := []byte(`{"a": `)
+	assert.Equal(t, broken, Body("application/json", broken, Options{}))
+	dump := string(Body("image/png", []byte("\x89PNG\x00"), Options{}))
+	assert.True(t, strings.HasPrefix(dump, "00000000  89 50 4e 47 00"), dump)
+	dump = string(Body("text/plain", []byte("a\x00b"), Options{}))
+	assert.True(t, strings.HasPrefix(dump, "00000000  61 00 62"), dump)
+
+	html := string(Body("text/html", []byte(`<a href="/x">link</a>`), Options{Color: true}))
+	assert.Contains(t, html, "link")
+	assert.Contains(t, html, "\x1b[")
+}
@@ -0,0 +1,75 @@
+package respdiff
+
+import (
+	"github.com/dark-enstein/scour/internal/jsondiff"
+	"github.com/dark-enstein/scour/internal/snapshot"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	// DefaultIgnoreHeaders are the response headers left out of diffs, unless set otherwise. Their values
+	// change from one response, or one server, to the next, whether the responses match or not.
+	DefaultIgnoreHeaders = []string{"Age", "Content-Length", "Date", "ETag", "Expires", "Last-Modified", "Server",
+		"Set-Cookie", "Via", "X-Request-Id"}
+)
+
+// IsFile reports whether arg names an existing file, rather than a url.
+func IsFile(arg string) bool {
+	if strings.Contains(arg, "://") {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// ReadSaved reads the response body saved in the file at p, as the result of a transfer without status or
+// headers.
+func ReadSaved(p string) (*transfer.Result, error) {
+	body, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer.Result{Job: transfer.Job{Url: p}, Body: body}, nil
+}
+
+// Headers returns the names of the response headers of a and b, sorted and without the ignored ones. "*"
+// ignores every header.
+func Headers(a, b *transfer.Result, ignored []string) []string {
+	skip := map[string]bool{}
+	for _, name := range ignored {
+		if name == "*" {
+			return nil
+		}
+		skip[http.CanonicalHeaderKey(name)] = true
+	}
+	var names []string
+	for _, res := range []*transfer.Result{a, b} {
+		if res.Headers == nil {
+			continue
+		}
+		for name := range res.Headers.Header {
+			if name = http.CanonicalHeaderKey(name); !skip[name] {
+				skip[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Compare returns the differences from the response of a to that of b, normalized with rules: status,
+// headers but the ignored ones, then body. When either is a saved body, only the bodies are compared.
+func Compare(a, b *transfer.Result, ignoredHeaders []string, rules *snapshot.Rules) []jsondiff.Change {
+	headers := Headers(a, b, ignoredHeaders)
+	left, right := snapshot.NewResponse(a, headers, rules), snapshot.NewResponse(b, headers, rules)
+	if a.Headers == nil || b.Headers == nil {
+		// a saved body has no status or headers to compare with
+		left.Status, left.Headers, right.Status, right.Headers = 0, nil, 0, nil
+	}
+	return snapshot.Compare(left, right)
+}
@@ -0,0 +1,113 @@
+package respdiff
+
+import (
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/jsondiff"
+	"github.com/dark-enstein/scour/internal/snapshot"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// result builds the result of an HTTP transfer with headers, in "Name", "value" pairs.
+func result(status int, body string, headers ...string) *transfer.Result {
+	h := &invoke.RespHeaders{StatusCode: status, Header: http.Header{}}
+	for i := 0; i+1 < len(headers); i += 2 {
+		h.Header.Add(headers[i], headers[i+1])
+	}
+	return &transfer.Result{Headers: h, Body: []byte(body)}
+}
+
+// TestIsFile checks telling saved bodies from urls.
+func TestIsFile(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "user.json")
+	assert.NoError(t, os.WriteFile(p, []byte(`{}`), 0o600))
+	for arg, expected := range map[string]bool{
+		p:                            true,
+		dir:                          false,
+		filepath.Join(dir, "nope"):   false,
+		"https://example.com/a.json": false,
+		"file://" + p:                false,
+	} {
+		assert.Equal(t, expected, IsFile(arg), arg)
+	}
+}
+
+// TestHeaders checks the headers compared, by default and with headers ignored.
+func TestHeaders(t *testing.T) {
+	a := result(200, "", "Content-Type", "application/json", "Date", "Mon, 19 Oct 2026 10:00:00 GMT", "X-Request-Id", "1")
+	b := result(200, "", "content-type", "application/json", "Cache-Control", "no-cache", "Server", "envoy")
+	saved := &transfer.Result{Body: []byte("{}")}
+	for _, tc := range []struct {
+		name     string
+		a, b     *transfer.Result
+		ignored  []string
+		expected []string
+	}{
+		{"defaults", a, b, DefaultIgnoreHeaders, []string{"Cache-Control", "Content-Type"}},
+		{"none ignored", a, b, nil, []string{"Cache-Control", "Content-Type", "Date", "Server", "X-Request-Id"}},
+		{"named in any case", a, b, []string{"cache-control", "x-request-id"}, []string{"Content-Type", "Date", "Server"}},
+		{"every header", a, b, []string{"Date", "*"}, nil},
+		{"saved body", a, saved, []string{"Date"}, []string{"Content-Type", "X-Request-Id"}},
+	} {
+		assert.Equal(t, tc.expected, Headers(tc.a, tc.b, tc.ignored), tc.name)
+	}
+}
+
+// TestCompare checks the differences found between responses, and between a response and a saved body.
+func TestCompare(t *testing.T) {
+	rules, err := snapshot.Ignore{Paths: []string{"$.request_id"}}.Compile()
+	assert.NoError(t, err)
+	for _, tc := range []struct {
+		name     string
+		a, b     *transfer.Result
+		expected []jsondiff.Change
+	}{
+		{
+			"volatile headers and ignored paths",
+			result(200, `{"id": 1, "request_id": "a"}`, "Date", "Mon", "Content-Length", "28", "ETag", `"a"`),
+			result(200, `{"id": 1, "request_id": "bb"}`, "Date", "Tue", "Content-Length", "29", "ETag", `"b"`),
+			nil,
+		},
+		{
+			"status, header and body",
+			result(200, `{"id": 1, "name": "Ann"}`, "Content-Type", "application/json"),
+			result(201, `{"id": 1, "name": "Anne", "tags": []}`, "Content-Type", "application/json; charset=utf-8"),
+			[]jsondiff.Change{
+				{Path: "status", Kind: jsondiff.Changed, Old: 200, New: 201},
+				{Path: "header Content-Type", Kind: jsondiff.Changed, Old: "application/json", New: "application/json; charset=utf-8"},
+				{Path: "$.name", Kind: jsondiff.Changed, Old: "Ann", New: "Anne"},
+				{Path: "$.tags", Kind: jsondiff.Added, New: []interface{}{}},
+			},
+		},
+		{
+			"saved body leaves out status and headers",
+			result(404, `{"id": 1}`, "Content-Type", "application/json"),
+			&transfer.Result{Body: []byte(`{"id":1}`)},
+			nil,
+		},
+		{
+			"saved body differing",
+			&transfer.Result{Body: []byte("a\nb\n")},
+			result(500, "a\nc\n", "Content-Type", "text/plain"),
+			[]jsondiff.Change{{Path: "line 2", Kind: jsondiff.Changed, Old: "b", New: "c"}},
+		},
+	} {
+		assert.Equal(t, tc.expected, Compare(tc.a, tc.b, DefaultIgnoreHeaders, rules), tc.name)
+	}
+}
+
+// TestReadSaved checks reading saved bodies.
+func TestReadSaved(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "user.json")
+	assert.NoError(t, os.WriteFile(p, []byte(`{"id": 1}`), 0o600))
+	res, err := ReadSaved(p)
+	assert.NoError(t, err)
+	assert.Equal(t, &transfer.Result{Job: transfer.Job{Url: p}, Body: []byte(`{"id": 1}`)}, res)
+	_, err = ReadSaved(p + ".missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/batch"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/jq"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/dark-enstein/scour/internal/vars"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	// ErrExtract is the error of a step a value couldn't be extracted from the response of.
+	ErrExtract = errors.New("extraction failed")
+)
+
+// Scenario is a sequence of requests, run in order, where each step can extract values from its response
+// into variables that later steps are templated with.
+type Scenario struct {
+	Name  string
+	Vars  vars.Vars // Variables every step is templated with, unless overridden.
+	Steps []Step
+	Path  string
+}
+
+// document is a scenario file as decoded. Variables may be YAML scalars of any type.
+type document struct {
+	Name  string                 `json:"name"`
+	Vars  map[string]interface{} `json:"vars"`
+	Steps []Step                 `json:"steps"`
+}
+
+// Step is a request of a scenario, as described in batch files, and the values to extract from its response.
+type Step struct {
+	batch.Request
+	// Extract maps the names of variables onto where to extract their value from.
+	Extract map[string]*Extract `json:"extract,omitempty"`
+}
+
+// Extract describes where to extract a value from the response of a step. Exactly one of JSON, Header,
+// Regex and Cookie is set.
+type Extract struct {
+	JSON   string `json:"json,omitempty"`   // JSONPath expression evaluated on the body, e.g. $.token.
+	Header string `json:"header,omitempty"` // Name of a response header.
+	Regex  string `json:"regex,omitempty"`  // Regular expression matched on the body. Its first group, if any, is the value.
+	Cookie string `json:"cookie,omitempty"` // Name of a cookie set by the response.
+	Secret bool   `json:"secret,omitempty"` // Masks the value in output.
+
+	path *jq.Program
+	re   *regexp.Regexp
+}
+
+// UnmarshalJSON decodes an Extract from an object, or from a string holding a JSONPath expression.
+func (e *Extract) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &e.JSON); err == nil {
+		return nil
+	}
+	type plain Extract
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*plain)(e))
+}
+
+// Value is a value extracted from the response of a step.
+type Value struct {
+	Name   string
+	Value  string
+	Secret bool
+}
+
+// ParseFile reads and parses the scenario file at p.
+func ParseFile(p string) (*Scenario, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b, p)
+}
+
+// Parse parses a scenario file, in YAML or JSON:
+//
+//	name: login
+//	vars:
+//	  user: ann
+//	steps:
+//	  - name: login
+//	    method: POST
+//	    url: "{{base_url}}/login"
+//	    body: {"user": "{{user}}"}
+//	    expect_status: 200
+//	    extract:
+//	      token: $.token
+//	      session: {cookie: SESSION, secret: true}
+//	  - url: "{{base_url}}/me"
+//	    headers: {Authorization: "Bearer {{token}}"}
+//
+// Steps take the fields of batch file requests, and an extract object.
+func Parse(b []byte, p string) (*Scenario, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	// steps are decoded through JSON, so they take the same fields, with the same checks, as batch requests
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+	var d document
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("%s: malformed scenario: %w", p, err)
+	}
+	if len(d.Steps) == 0 {
+		return nil, fmt.Errorf("%s: the scenario has no steps", p)
+	}
+	s := &Scenario{Name: d.Name, Vars: vars.Vars{}, Steps: d.Steps, Path: p}
+	for name, v := range d.Vars {
+		switch v := v.(type) {
+		case string:
+			s.Vars[name] = v
+		case json.Number, bool:
+			s.Vars[name] = fmt.Sprint(v)
+		case nil:
+			s.Vars[name] = ""
+		default:
+			return nil, fmt.Errorf("%s: variable %s must be a string, number or boolean", p, name)
+		}
+	}
+	for i := range s.Steps {
+		if err := s.Steps[i].compile(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", p, s.Steps[i].Label(i), err)
+		}
+	}
+	return s, nil
+}
+
+// compile validates the step, and compiles the expressions of its extracts.
+func (s *Step) compile() error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	for name, e := range s.Extract {
+		set := 0
+		for _, field := range []string{e.JSON, e.Header, e.Regex, e.Cookie} {
+			if len(field) > 0 {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("extract %s: set exactly one of json, header, regex and cookie", name)
+		}
+		var err error
+		switch {
+		case len(e.JSON) > 0:
+			var src string
+			if src, err = jq.FromJSONPath(e.JSON); err == nil {
+				e.path, err = jq.Compile(src)
+			}
+		case len(e.Regex) > 0:
+			e.re, err = regexp.Compile(e.Regex)
+		}
+		if err != nil {
+			return fmt.Errorf("extract %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Label returns the name of the step with index i, or its 1-based position when it has none.
+func (s *Step) Label(i int) string {
+	if len(s.Name) > 0 {
+		return s.Name
+	}
+	return "step " + strconv.Itoa(i+1)
+}
+
+// Check fails res, the result of the step, when its status isn't one of the expected ones. Without an
+// expected status, statuses >= 400 fail.
+func (s *Step) Check(res *transfer.Result) {
+	if res.Err != nil || res.Headers == nil {
+		return
+	}
+	switch code := res.Headers.StatusCode; {
+	case len(s.ExpectStatus) > 0 && !s.ExpectStatus.Match(code):
+		res.Err = fmt.Errorf("%w: expected status %s, got %s", exitcode.ErrHTTPStatus, s.ExpectStatus, res.Headers.RespCode)
+	case len(s.ExpectStatus) == 0 && code >= 400:
+		res.Err = fmt.Errorf("%w: %s", exitcode.ErrHTTPStatus, res.Headers.RespCode)
+	}
+}
+
+// Values extracts the values of the step from res, its result, sorted by name. A value that can't be
+// found is an error wrapping ErrExtract.
+func (s *Step) Values(res *transfer.Result) ([]Value, error) {
+	names := make([]string, 0, len(s.Extract))
+	for name := range s.Extract {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]Value, 0, len(names))
+	for _, name := range names {
+		e := s.Extract[name]
+		v, err := e.value(res)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrExtract, name, err)
+		}
+		values = append(values, Value{Name: name, Value: v, Secret: e.Secret})
+	}
+	return values, nil
+}
+
+// value extracts the value described by e from res.
+func (e *Extract) value(res *transfer.Result) (string, error) {
+	switch {
+	case e.path != nil:
+		vals, err := e.path.RunJSON(res.Body)
+		if err != nil {
+			return "", fmt.Errorf("json %s: %w", e.JSON, err)
+		}
+		if len(vals) == 0 || vals[0] == nil {
+			return "", fmt.Errorf("json %s: no value in the response body", e.JSON)
+		}
+		if s, ok := vals[0].(string); ok {
+			return s, nil
+		}
+		b, err := json.Marshal(vals[0])
+		return string(b), err
+	case e.re != nil:
+		m := e.re.FindSubmatch(res.Body)
+		if m == nil {
+			return "", fmt.Errorf("regex %s: no match in the response body", e.Regex)
+		}
+		if len(m) > 1 {
+			return string(m[1]), nil
+		}
+		return string(m[0]), nil
+	case res.Headers == nil:
+		return "", fmt.Errorf("no response headers")
+	case len(e.Header) > 0:
+		if v := res.Headers.Header.Get(e.Header); len(v) > 0 {
+			return v, nil
+		}
+		return "", fmt.Errorf("header %s: not in the response", e.Header)
+	default:
+		for _, c := range (&http.Response{Header: res.Headers.Header}).Cookies() {
+			if c.Name == e.Cookie {
+				return c.Value, nil
+			}
+		}
+		return "", fmt.Errorf("cookie %s: not set by the response", e.Cookie)
+	}
+}
@@ -0,0 +1,131 @@
+package scenario
+
+import (
+	"errors"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/dark-enstein/scour/internal/vars"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+var (
+	// testScenario is a scenario file using every kind of extract.
+	testScenario = `
+name: login
+vars:
+  user: ann
+  id: 7
+  admin: false
+steps:
+  - name: login
+    method: post
+    url: "{{base_url}}/login"
+    body: {"user": "{{user}}"}
+    expect_status: 200
+    extract:
+      token: $.token
+      uid: {json: $.user.id}
+      session: {cookie: SESSION, secret: true}
+      rid: {header: X-Request-Id}
+      order: {regex: 'order-(\d+)'}
+      whole: {regex: 'ord\w+'}
+  - url: "{{base_url}}/me"
+    headers: ["Authorization: Bearer {{token}}"]
+`
+	// testResult is the result of the login step of testScenario.
+	testResult = transfer.Result{
+		Body: []byte(`{"token": "abc", "user": {"id": 7}, "note": "order-4411"}`),
+		Headers: &invoke.RespHeaders{StatusCode: 200, RespCode: "200 OK", Header: http.Header{
+			"Set-Cookie":   []string{"OTHER=1", "SESSION=s3; Path=/; HttpOnly"},
+			"X-Request-Id": []string{"req-9"},
+		}},
+	}
+)
+
+// TestParse checks parsing of scenario files, and their validation.
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(testScenario), "login.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "login", s.Name)
+	assert.Equal(t, vars.Vars{"user": "ann", "id": "7", "admin": "false"}, s.Vars)
+	if !assert.Len(t, s.Steps, 2) {
+		return
+	}
+	assert.Equal(t, "POST", s.Steps[0].Method)
+	assert.Equal(t, "$.token", s.Steps[0].Extract["token"].JSON)
+	assert.True(t, s.Steps[0].Extract["session"].Secret)
+	assert.Equal(t, transfer.Job{Url: "{{base_url}}/login", Method: "POST", Data: []byte(`{"user":"{{user}}"}`),
+		Headers: []string{"Content-Type: application/json"}}, s.Steps[0].Job())
+	assert.Equal(t, "login", s.Steps[0].Label(0))
+	assert.Equal(t, "step 2", s.Steps[1].Label(1))
+
+	for doc, msg := range map[string]string{
+		"steps: []":                   "has no steps",
+		"steps: [{url: x, bogus: 1}]": "unknown field",
+		"steps: [{url: x, extract: {a: {json: $.a, header: b}}}]": "set exactly one",
+		"steps: [{url: x, extract: {a: {regex: '('}}}]":           "extract a",
+		"steps: [{method: GET}]":                                  "url is missing",
+		"vars: {a: [1]}\nsteps: [{url: x}]":                       "variable a",
+		"steps: [{url: x":                                         "login.yaml",
+	} {
+		_, err := Parse([]byte(doc), "login.yaml")
+		assert.ErrorContains(t, err, msg, doc)
+	}
+}
+
+// TestValues checks extraction of values from JSON, headers, regexes and cookies.
+func TestValues(t *testing.T) {
+	s, err := Parse([]byte(testScenario), "login.yaml")
+	assert.NoError(t, err)
+	res := testResult
+	values, err := s.Steps[0].Values(&res)
+	assert.NoError(t, err)
+	assert.Equal(t, []Value{
+		{Name: "order", Value: "4411"},
+		{Name: "rid", Value: "req-9"},
+		{Name: "session", Value: "s3", Secret: true},
+		{Name: "token", Value: "abc"},
+		{Name: "uid", Value: "7"},
+		{Name: "whole", Value: "order"},
+	}, values)
+
+	res.Headers = &invoke.RespHeaders{StatusCode: 200, Header: http.Header{}}
+	_, err = s.Steps[0].Values(&res)
+	assert.True(t, errors.Is(err, ErrExtract))
+	res.Body = []byte("not json, order-1")
+	_, err = s.Steps[0].Values(&res)
+	assert.ErrorContains(t, err, "rid: header X-Request-Id")
+	res = testResult
+	res.Body = []byte("order-1")
+	_, err = s.Steps[0].Values(&res)
+	assert.ErrorContains(t, err, "token: json $.token")
+}
+
+// TestCheck checks that steps fail on unexpected statuses.
+func TestCheck(t *testing.T) {
+	s, err := Parse([]byte(testScenario), "login.yaml")
+	assert.NoError(t, err)
+	for _, tc := range []struct {
+		step   int
+		status int
+		fails  bool
+	}{{0, 200, false}, {0, 201, true}, {1, 302, false}, {1, 404, true}} {
+		res := transfer.Result{Headers: &invoke.RespHeaders{StatusCode: tc.status}}
+		s.Steps[tc.step].Check(&res)
+		assert.Equal(t, tc.fails, res.Err != nil, tc)
+		if tc.fails {
+			assert.Equal(t, exitcode.HTTPError, exitcode.Classify(res.Err))
+		}
+	}
+
+	s, err = Parse([]byte("steps:\n  - url: x\n    expect_status: 2xx,404\n"), "classes.yaml")
+	assert.NoError(t, err)
+	for status, fails := range map[int]bool{204: false, 404: false, 500: true, 302: true} {
+		res := transfer.Result{Headers: &invoke.RespHeaders{StatusCode: status}}
+		s.Steps[0].Check(&res)
+		assert.Equal(t, fails, res.Err != nil, status)
+	}
+}
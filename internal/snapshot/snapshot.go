@@ -0,0 +1,319 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/envelope"
+	"github.com/dark-enstein/scour/internal/jsondiff"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"golang.org/x/exp/slices"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// Ignored replaces the values matched by ignore rules, in stored and compared responses.
+	Ignored = "<ignored>"
+	// DefaultHeaders are the response headers kept in snapshots, unless set otherwise.
+	DefaultHeaders = []string{"Content-Type"}
+)
+
+// File is a snapshot file: the requests recorded, the normalized responses they got, and the rules used
+// to normalize them.
+type File struct {
+	Ignore  Ignore   `json:"ignore"`
+	Headers []string `json:"headers"` // Response headers compared, on top of status and body.
+	Entries []Entry  `json:"entries"`
+}
+
+// Ignore lists the parts of responses left out of comparisons, as they change from one response to the
+// next: timestamps, UUIDs, request IDs.
+type Ignore struct {
+	// Paths are JSONPath expressions of values in JSON bodies, e.g. $.meta.request_id or $..updated_at.
+	Paths []string `json:"paths,omitempty"`
+	// Regexes are regular expressions matched against text bodies, strings of JSON bodies and header values.
+	Regexes []string `json:"regexes,omitempty"`
+}
+
+// Entry is a request of a snapshot and the response it got.
+type Entry struct {
+	Request  envelope.Request `json:"request"`
+	Response Response         `json:"response"`
+}
+
+// Response is a response as stored in a snapshot. A body is stored as JSON when it is valid JSON, as text when
+// it is valid UTF-8, and as its SHA-256 digest otherwise.
+type Response struct {
+	Status  int               `json:"status,omitempty"` // Zero for socket transfers.
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Text    string            `json:"text,omitempty"`
+	SHA256  string            `json:"sha256,omitempty"`
+}
+
+// Add appends the rules of other that i doesn't have yet.
+func (i *Ignore) Add(other Ignore) {
+	for _, p := range other.Paths {
+		if !slices.Contains(i.Paths, p) {
+			i.Paths = append(i.Paths, p)
+		}
+	}
+	for _, r := range other.Regexes {
+		if !slices.Contains(i.Regexes, r) {
+			i.Regexes = append(i.Regexes, r)
+		}
+	}
+}
+
+// Rules are compiled ignore rules.
+type Rules struct {
+	paths   []jsondiff.Path
+	regexes []*regexp.Regexp
+	// Secrets replaces the values of secret variables in responses, ahead of the regular expressions, so
+	// that they aren't stored. Nil replaces nothing.
+	Secrets *strings.Replacer
+}
+
+// Compile parses the paths and regular expressions of i.
+func (i Ignore) Compile() (*Rules, error) {
+	r := &Rules{}
+	for _, src := range i.Paths {
+		p, err := jsondiff.ParsePath(src)
+		if err != nil {
+			return nil, fmt.Errorf("ignore %w", err)
+		}
+		r.paths = append(r.paths, p)
+	}
+	for _, src := range i.Regexes {
+		re, err := regexp.Compile(src)
+		if err != nil {
+			return nil, fmt.Errorf("ignore regex %s: %w", src, err)
+		}
+		r.regexes = append(r.regexes, re)
+	}
+	return r, nil
+}
+
+// Read reads a snapshot file.
+func Read(name string) (*File, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{}
+	if err := json.Unmarshal(b, f); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", name, err)
+	}
+	// bodies come back indented as written, compacted they compare and rewrite as they were recorded
+	for i := range f.Entries {
+		if body := f.Entries[i].Response.Body; len(body) > 0 {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, body); err == nil {
+				f.Entries[i].Response.Body = buf.Bytes()
+			}
+		}
+	}
+	return f, nil
+}
+
+// Write writes the snapshot file, indented and with the keys of bodies sorted so that it diffs well.
+func (f *File) Write(name string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf.Bytes(), 0o644)
+}
+
+// NewResponse stores the response of res, keeping the headers named and normalized with rules.
+func NewResponse(res *transfer.Result, headers []string, rules *Rules) Response {
+	r := Response{}
+	if h := res.Headers; h != nil {
+		r.Status = h.StatusCode
+		for _, name := range headers {
+			if values := h.Header.Values(name); len(values) > 0 {
+				if r.Headers == nil {
+					r.Headers = map[string]string{}
+				}
+				r.Headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+			}
+		}
+	}
+	switch body := bytes.TrimSpace(res.Body); {
+	case len(body) > 0 && json.Valid(body):
+		r.Body = json.RawMessage(body)
+	case utf8.Valid(res.Body):
+		r.Text = string(res.Body)
+	default:
+		sum := sha256.Sum256(res.Body)
+		r.SHA256 = hex.EncodeToString(sum[:])
+	}
+	return rules.Normalize(r)
+}
+
+// Normalize replaces the parts of r matched by the rules with Ignored. Normalizing twice changes nothing.
+func (rules *Rules) Normalize(r Response) Response {
+	if len(r.Headers) > 0 {
+		headers := make(map[string]string, len(r.Headers))
+		for name, value := range r.Headers {
+			headers[name] = rules.replace(value)
+		}
+		r.Headers = headers
+	}
+	r.Text = rules.replace(r.Text)
+	if len(r.Body) > 0 {
+		v, err := decode(r.Body)
+		if err != nil {
+			return r
+		}
+		for _, p := range rules.paths {
+			v = p.Replace(v, func(interface{}) interface{} { return Ignored })
+		}
+		if b, err := encode(rules.strings(v)); err == nil {
+			r.Body = b
+		}
+	}
+	return r
+}
+
+// replace replaces secrets, then the matches of every regular expression, in s.
+func (rules *Rules) replace(s string) string {
+	if rules.Secrets != nil {
+		s = rules.Secrets.Replace(s)
+	}
+	for _, re := range rules.regexes {
+		s = re.ReplaceAllLiteralString(s, Ignored)
+	}
+	return s
+}
+
+// strings replaces secrets and the matches of every regular expression in the strings of v, a decoded JSON value.
+func (rules *Rules) strings(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return rules.replace(v)
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = rules.strings(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = rules.strings(child)
+		}
+	}
+	return v
+}
+
+// Compare returns the differences from the stored response want to the response got: status, headers, then
+// body. JSON bodies are compared value by value, text bodies line by line.
+func Compare(want, got Response) []jsondiff.Change {
+	var changes []jsondiff.Change
+	if want.Status != got.Status {
+		changes = append(changes, jsondiff.Change{Path: "status", Kind: jsondiff.Changed, Old: want.Status, New: got.Status})
+	}
+	names := make([]string, 0, len(want.Headers)+len(got.Headers))
+	for name := range want.Headers {
+		names = append(names, name)
+	}
+	for name := range got.Headers {
+		if _, ok := want.Headers[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w, inWant := want.Headers[name]
+		g, inGot := got.Headers[name]
+		switch {
+		case !inGot:
+			changes = append(changes, jsondiff.Change{Path: "header " + name, Kind: jsondiff.Removed, Old: w})
+		case !inWant:
+			changes = append(changes, jsondiff.Change{Path: "header " + name, Kind: jsondiff.Added, New: g})
+		case w != g:
+			changes = append(changes, jsondiff.Change{Path: "header " + name, Kind: jsondiff.Changed, Old: w, New: g})
+		}
+	}
+	return append(changes, compareBody(want, got)...)
+}
+
+// compareBody returns the differences between the bodies of two responses.
+func compareBody(want, got Response) []jsondiff.Change {
+	switch {
+	case len(want.Body) > 0 && len(got.Body) > 0:
+		w, errW := decode(want.Body)
+		g, errG := decode(got.Body)
+		if errW == nil && errG == nil {
+			return jsondiff.Diff(w, g)
+		}
+	case len(want.Body) == 0 && len(got.Body) == 0 && len(want.SHA256) == 0 && len(got.SHA256) == 0:
+		return compareLines(want.Text, got.Text)
+	}
+	if bytes.Equal(want.Body, got.Body) && want.Text == got.Text && want.SHA256 == got.SHA256 {
+		return nil
+	}
+	return []jsondiff.Change{{Path: "body", Kind: jsondiff.Changed, Old: want.summary(), New: got.summary()}}
+}
+
+// compareLines returns the lines that differ between two text bodies, by line number.
+func compareLines(want, got string) []jsondiff.Change {
+	if want == got {
+		return nil
+	}
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var changes []jsondiff.Change
+	for i := 0; i < len(w) || i < len(g); i++ {
+		path := fmt.Sprintf("line %d", i+1)
+		switch {
+		case i >= len(g):
+			changes = append(changes, jsondiff.Change{Path: path, Kind: jsondiff.Removed, Old: w[i]})
+		case i >= len(w):
+			changes = append(changes, jsondiff.Change{Path: path, Kind: jsondiff.Added, New: g[i]})
+		case w[i] != g[i]:
+			changes = append(changes, jsondiff.Change{Path: path, Kind: jsondiff.Changed, Old: w[i], New: g[i]})
+		}
+	}
+	return changes
+}
+
+// summary describes the body of r in a word, for bodies that can't be compared value by value.
+func (r Response) summary() string {
+	switch {
+	case len(r.Body) > 0:
+		return "JSON body"
+	case len(r.SHA256) > 0:
+		return "binary body sha256:" + r.SHA256
+	case len(r.Text) > 0:
+		return "text body"
+	}
+	return "no body"
+}
+
+// decode decodes a JSON body, keeping numbers as is.
+func decode(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// encode encodes a decoded JSON body compactly, with object keys sorted.
+func encode(v interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
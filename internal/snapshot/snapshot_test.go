@@ -0,0 +1,104 @@
+package snapshot
+
+import (
+	"github.com/dark-enstein/scour/internal/envelope"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"github.com/dark-enstein/scour/internal/jsondiff"
+	"github.com/dark-enstein/scour/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	// testIgnore ignores a request id by path and UUIDs by regex.
+	testIgnore = Ignore{
+		Paths:   []string{"$.meta.request_id"},
+		Regexes: []string{`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`},
+	}
+)
+
+// result builds the result of an HTTP transfer for the tests.
+func result(status int, contentType, body string) *transfer.Result {
+	h := &invoke.RespHeaders{StatusCode: status, Header: http.Header{}}
+	h.Header.Set("Content-Type", contentType)
+	h.Header.Set("Date", "Mon, 19 Oct 2026 10:00:00 GMT")
+	return &transfer.Result{Headers: h, Body: []byte(body)}
+}
+
+// TestNewResponse checks that responses are stored normalized, with only the headers asked for.
+func TestNewResponse(t *testing.T) {
+	rules, err := testIgnore.Compile()
+	assert.NoError(t, err)
+	r := NewResponse(result(200, "application/json", `{"id": 7, "ref": "order 0c6d2f0e-8b1a-4d3c-9e2f-1a2b3c4d5e6f", "meta": {"request_id": 42}}`), DefaultHeaders, rules)
+	assert.Equal(t, Response{
+		Status:  200,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    []byte(`{"id":7,"meta":{"request_id":"<ignored>"},"ref":"order <ignored>"}`),
+	}, r)
+	assert.Equal(t, r, rules.Normalize(r))
+
+	r = NewResponse(result(404, "text/plain", "not found\n0c6d2f0e-8b1a-4d3c-9e2f-1a2b3c4d5e6f"), []string{"date"}, rules)
+	assert.Equal(t, Response{Status: 404, Headers: map[string]string{"Date": "Mon, 19 Oct 2026 10:00:00 GMT"}, Text: "not found\n<ignored>"}, r)
+
+	r = NewResponse(&transfer.Result{Body: []byte{0xff, 0xfe}}, DefaultHeaders, rules)
+	assert.Equal(t, Response{SHA256: "b3d510ef04275ca8e698e5b3cbb0ece3949ef9252f0cdc839e9ee347409a2209"}, r)
+}
+
+// TestCompile checks that malformed ignore rules are refused.
+func TestCompile(t *testing.T) {
+	_, err := Ignore{Paths: []string{"$["}}.Compile()
+	assert.Error(t, err)
+	_, err = Ignore{Regexes: []string{"("}}.Compile()
+	assert.Error(t, err)
+}
+
+// TestCompare checks that status, header and body differences are reported in order.
+func TestCompare(t *testing.T) {
+	want := Response{Status: 200, Headers: map[string]string{"Content-Type": "application/json", "X-Old": "1"}, Body: []byte(`{"id":7,"tags":["a"]}`)}
+	got := Response{Status: 201, Headers: map[string]string{"Content-Type": "application/json; charset=utf-8", "X-New": "2"}, Body: []byte(`{"id":8,"tags":["a","b"]}`)}
+	assert.Equal(t, `~ status: 200 -> 201
+~ header Content-Type: "application/json" -> "application/json; charset=utf-8"
++ header X-New: "2"
+- header X-Old: "1"
+~ $.id: 7 -> 8
++ $.tags[1]: "b"
+`, jsondiff.Format(Compare(want, got)))
+	assert.Empty(t, Compare(want, want))
+
+	assert.Equal(t, []jsondiff.Change{
+		{Path: "line 2", Kind: jsondiff.Changed, Old: "b", New: "c"},
+		{Path: "line 3", Kind: jsondiff.Added, New: "d"},
+	}, Compare(Response{Text: "a\nb"}, Response{Text: "a\nc\nd"}))
+	assert.Equal(t, []jsondiff.Change{{Path: "body", Kind: jsondiff.Changed, Old: "text body", New: "JSON body"}},
+		Compare(Response{Text: "a"}, Response{Body: []byte(`"a"`)}))
+	assert.Empty(t, Compare(Response{SHA256: "ab"}, Response{SHA256: "ab"}))
+}
+
+// TestReadWrite checks that a snapshot file reads back as written.
+func TestReadWrite(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "snap.json")
+	f := &File{Ignore: testIgnore, Headers: DefaultHeaders, Entries: []Entry{{
+		Request:  envelope.Request{Method: "GET", Url: "http://localhost/users/7", Headers: http.Header{"Authorization": {"Bearer {{token}}"}}},
+		Response: Response{Status: 200, Body: []byte(`{"id":7,"at":"<ignored>"}`)},
+	}}}
+	assert.NoError(t, f.Write(name))
+	read, err := Read(name)
+	assert.NoError(t, err)
+	assert.Equal(t, f, read)
+
+	ignore := Ignore{Paths: []string{"$.meta.request_id"}}
+	ignore.Add(Ignore{Paths: []string{"$..at", "$.meta.request_id"}, Regexes: []string{"x"}})
+	assert.Equal(t, Ignore{Paths: []string{"$.meta.request_id", "$..at"}, Regexes: []string{"x"}}, ignore)
+}
+
+// TestSecrets checks that secrets are replaced in responses before regular expressions are matched.
+func TestSecrets(t *testing.T) {
+	rules, err := Ignore{Regexes: []string{`s3cr3t-\d+`}}.Compile()
+	assert.NoError(t, err)
+	rules.Secrets = strings.NewReplacer("s3cr3t", "{{token}}")
+	r := NewResponse(result(200, "application/json", `{"auth": "Bearer s3cr3t", "id": "s3cr3t-12"}`), nil, rules)
+	assert.Equal(t, `{"auth":"Bearer {{token}}","id":"{{token}}-12"}`, string(r.Body))
+}
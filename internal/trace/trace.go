@@ -0,0 +1,166 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// KeyTracer is the context key the Tracer of an invocation is stored under.
+	KeyTracer = "TRACER"
+	// BytesPerLine is the number of bytes shown on each line of a hex trace.
+	BytesPerLine = 16
+	// TimeFormat is the layout of the timestamps prefixed to every line with --trace-time.
+	TimeFormat = "15:04:05.000000"
+)
+
+// Tracer writes every byte sent and received over the connections it wraps to w, in the style of
+// curl's --trace and --trace-ascii. It is safe for concurrent use by parallel transfers.
+type Tracer struct {
+	mux        sync.Mutex
+	w          io.Writer
+	ascii      bool
+	timestamps bool
+	conns      int
+}
+
+// New creates a Tracer writing to w. With ascii set, data is dumped as text instead of hex, and with
+// timestamps set, every line is prefixed with the time of day it was traced at.
+func New(w io.Writer, ascii, timestamps bool) *Tracer {
+	return &Tracer{w: w, ascii: ascii, timestamps: timestamps}
+}
+
+// FromCtx extracts the Tracer from a context. It returns nil when tracing is off.
+func FromCtx(ctx context.Context) *Tracer {
+	t, _ := ctx.Value(KeyTracer).(*Tracer)
+	return t
+}
+
+// Infof traces an informational line.
+func (t *Tracer) Infof(format string, a ...interface{}) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.line(time.Now(), "== Info: "+fmt.Sprintf(format, a...))
+}
+
+// Conn wraps c so that everything written to and read from it is traced. The name identifies the
+// connection in the trace, e.g. the address dialed.
+func (t *Tracer) Conn(c net.Conn, name string) net.Conn {
+	t.mux.Lock()
+	t.conns++
+	id := t.conns
+	t.mux.Unlock()
+	t.Infof("Connected to %s (#%d)", name, id)
+	return &conn{Conn: c, t: t, id: id}
+}
+
+// dump traces a chunk of data sent, or received, over connection id.
+func (t *Tracer) dump(id int, sent bool, data []byte) {
+	now := time.Now()
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	dir := "<= Recv"
+	if sent {
+		dir = "=> Send"
+	}
+	t.line(now, fmt.Sprintf("%s data, %d bytes (0x%x) (#%d)", dir, len(data), len(data), id))
+	if t.ascii {
+		for off := 0; off < len(data); {
+			end := off + BytesPerLine*4
+			if nl := indexNewline(data[off:]); nl >= 0 && off+nl+1 < end {
+				end = off + nl + 1
+			}
+			if end > len(data) {
+				end = len(data)
+			}
+			t.line(now, fmt.Sprintf("%04x: %s", off, printable(data[off:end], true)))
+			off = end
+		}
+		return
+	}
+	for off := 0; off < len(data); off += BytesPerLine {
+		end := off + BytesPerLine
+		if end > len(data) {
+			end = len(data)
+		}
+		var hex strings.Builder
+		for i := off; i < off+BytesPerLine; i++ {
+			if i < end {
+				fmt.Fprintf(&hex, "%02x ", data[i])
+			} else {
+				hex.WriteString("   ")
+			}
+		}
+		t.line(now, fmt.Sprintf("%04x: %s%s", off, hex.String(), printable(data[off:end], false)))
+	}
+}
+
+// line writes a single line of trace, with the lock held.
+func (t *Tracer) line(now time.Time, s string) {
+	if t.timestamps {
+		s = now.Format(TimeFormat) + " " + s
+	}
+	_, _ = io.WriteString(t.w, s+"\n")
+}
+
+// indexNewline returns the index of the first \n in b, or -1.
+func indexNewline(b []byte) int {
+	for i, c := range b {
+		if c == '\n' {
+			return i
+		}
+	}
+	return -1
+}
+
+// printable renders b with every byte outside printable ASCII shown as a dot. With trimEOL set, a
+// trailing CRLF or LF is dropped, since the trace puts each line on its own line anyway.
+func printable(b []byte, trimEOL bool) string {
+	if trimEOL {
+		b = []byte(strings.TrimRight(string(b), "\r\n"))
+	}
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if c < 0x20 || c > 0x7e {
+			c = '.'
+		}
+		out[i] = c
+	}
+	return string(out)
+}
+
+// conn is a net.Conn traced by a Tracer.
+type conn struct {
+	net.Conn
+	t  *Tracer
+	id int
+}
+
+// Read reads from the wrapped connection, tracing whatever was received.
+func (c *conn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.t.dump(c.id, false, b[:n])
+	}
+	return n, err
+}
+
+// Write writes to the wrapped connection, tracing whatever was sent.
+func (c *conn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.t.dump(c.id, true, b[:n])
+	}
+	return n, err
+}
+
+// Close closes the wrapped connection.
+func (c *conn) Close() error {
+	c.t.Infof("Closing connection #%d", c.id)
+	return c.Conn.Close()
+}
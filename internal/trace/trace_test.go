@@ -0,0 +1,63 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// exchange sends req over a pipe traced by tracer, with the far end replying resp.
+func exchange(t *testing.T, tracer *Tracer, req, resp string) {
+	client, server := net.Pipe()
+	traced := tracer.Conn(client, "pipe")
+	go func() {
+		buf := make([]byte, len(req))
+		_, _ = io.ReadFull(server, buf)
+		_, _ = server.Write([]byte(resp))
+		_ = server.Close()
+	}()
+	_, err := traced.Write([]byte(req))
+	assert.NoError(t, err)
+	_, err = io.ReadAll(traced)
+	assert.NoError(t, err)
+	assert.NoError(t, traced.Close())
+}
+
+// TestTracer_ASCII checks that data is dumped line by line with offsets, in both directions.
+func TestTracer_ASCII(t *testing.T) {
+	var out bytes.Buffer
+	exchange(t, New(&out, true, false), "GET / HTTP/1.1\r\nHost: a\r\n\r\n", "ok\x00")
+	expected := strings.Join([]string{
+		"== Info: Connected to pipe (#1)",
+		"=> Send data, 27 bytes (0x1b) (#1)",
+		"0000: GET / HTTP/1.1",
+		"0010: Host: a",
+		"0019: ",
+		"<= Recv data, 3 bytes (0x3) (#1)",
+		"0000: ok.",
+		"== Info: Closing connection #1",
+	}, "\n") + "\n"
+	assert.Equal(t, expected, out.String())
+}
+
+// TestTracer_Hex checks the hex dump layout, padding the last line, and timestamps.
+func TestTracer_Hex(t *testing.T) {
+	var out bytes.Buffer
+	exchange(t, New(&out, false, true), "0123456789abcdefXY", "")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 5)
+	assert.Regexp(t, `^\d\d:\d\d:\d\d\.\d{6} => Send data, 18 bytes \(0x12\) \(#1\)$`, lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], "0000: 30 31 32 33 34 35 36 37 38 39 61 62 63 64 65 66 0123456789abcdef"), lines[2])
+	assert.True(t, strings.HasSuffix(lines[3], "0010: 58 59"+strings.Repeat(" ", 14*3+1)+"XY"), lines[3])
+}
+
+// TestFromCtx checks that a missing tracer means tracing is off.
+func TestFromCtx(t *testing.T) {
+	assert.Nil(t, FromCtx(context.Background()))
+	tracer := New(io.Discard, false, false)
+	assert.Equal(t, tracer, FromCtx(context.WithValue(context.Background(), KeyTracer, tracer)))
+}
@@ -0,0 +1,119 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/dark-enstein/scour/internal/invoke"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Stdout is the output name that explicitly asks for a response body to be written to stdout.
+	Stdout = "-"
+	// DefaultParallelMax is the number of transfers allowed to run at once in parallel mode, unless overridden.
+	DefaultParallelMax = 50
+)
+
+// Job describes a single request: its url, how to send it, and the file its response should be written to.
+type Job struct {
+	Url     string   // Raw url argument, as passed on the command line.
+	Output  string   // File the response body is written to. Empty or Stdout means stdout.
+	Method  string   // Request method, or config.MethodSocket for socket transfers.
+	Headers []string // Custom request headers, each in "Name: value" form.
+	Data    []byte   // Request payload.
+}
+
+// ToStdout reports whether the response body of the job is written to stdout.
+func (j Job) ToStdout() bool {
+	return len(j.Output) == 0 || j.Output == Stdout
+}
+
+// Result holds the outcome of a single Job.
+type Result struct {
+	Job
+	Headers  *invoke.RespHeaders // Response headers. Nil for socket transfers and failed requests.
+	Verbose  string              // Verbose connection and response metadata, when verbose mode is on.
+	Body     []byte              // Response body.
+	Err      error               // Any error encountered while carrying out the transfer.
+	Duration time.Duration       // Wall time spent on the transfer.
+	// SessionID and Session hold the ID and the messages, sent and received in turn, of socket transfers.
+	SessionID string
+	Session   [][]byte
+}
+
+// Runner carries out a single Job and reports its Result.
+type Runner func(ctx context.Context, job Job) Result
+
+// Run executes every job with run. Jobs run one after the other unless parallel is set, in which case
+// at most max of them are in flight at once. Results are always returned in the same order as jobs.
+func Run(ctx context.Context, jobs []Job, parallel bool, max int, run Runner) []Result {
+	results := make([]Result, len(jobs))
+	timed := func(i int) {
+		t1 := time.Now()
+		results[i] = run(ctx, jobs[i])
+		results[i].Job = jobs[i]
+		results[i].Duration = time.Since(t1)
+	}
+
+	if !parallel || len(jobs) < 2 {
+		for i := range jobs {
+			timed(i)
+		}
+		return results
+	}
+
+	if max < 1 {
+		max = DefaultParallelMax
+	}
+	sem := make(chan struct{}, max)
+	wg := sync.WaitGroup{}
+	for i := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			timed(i)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// Status returns a short human-readable status of the result.
+func (r Result) Status() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("failed: %s", r.Err.Error())
+	case r.Headers != nil:
+		return r.Headers.RespCode
+	}
+	return "done"
+}
+
+// Summary renders one status line per result, in the order the jobs were passed in.
+func Summary(results []Result) string {
+	var sb strings.Builder
+	for i, r := range results {
+		dest := "stdout"
+		if !r.ToStdout() {
+			dest = r.Output
+		}
+		sb.WriteString(fmt.Sprintf("[%d/%d] %s -> %s (%s, %s)\n", i+1, len(results), r.Url, r.Status(), dest, r.Duration.Round(time.Millisecond)))
+	}
+	return sb.String()
+}
+
+// ExitCode combines the outcome of every result into a single process exit code.
+// It is 0 only when every transfer succeeded, otherwise it is the code describing the first failure.
+func ExitCode(results []Result) int {
+	for _, r := range results {
+		if r.Err != nil {
+			return exitcode.Classify(r.Err)
+		}
+	}
+	return exitcode.OK
+}
@@ -0,0 +1,65 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dark-enstein/scour/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	testJobs = []Job{
+		{Url: "https://eu.httpbin.org/get", Output: "one.json"},
+		{Url: "https://eu.httpbin.org/uuid"},
+		{Url: "https://eu.httpbin.org/ip", Output: "three.json"},
+		{Url: "https://eu.httpbin.org/headers"},
+	}
+)
+
+// TestRun_Order checks that results come back in job order, sequentially and in parallel.
+func TestRun_Order(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		results := Run(context.Background(), testJobs, parallel, 2, func(ctx context.Context, job Job) Result {
+			return Result{Body: []byte(job.Url)}
+		})
+		assert.Len(t, results, len(testJobs))
+		for i := range testJobs {
+			assert.Equal(t, testJobs[i], results[i].Job)
+			assert.Equal(t, testJobs[i].Url, string(results[i].Body))
+		}
+	}
+}
+
+// TestRun_ParallelMax checks that no more than max jobs are ever in flight at once.
+func TestRun_ParallelMax(t *testing.T) {
+	var inFlight, peak int32
+	jobs := make([]Job, 10)
+	for i := range jobs {
+		jobs[i] = Job{Url: fmt.Sprintf("http://localhost/%d", i)}
+	}
+	Run(context.Background(), jobs, true, 3, func(ctx context.Context, job Job) Result {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return Result{}
+	})
+	assert.LessOrEqual(t, peak, int32(3))
+	assert.Greater(t, peak, int32(1))
+}
+
+// TestExitCode checks that a single failed transfer fails the whole invocation.
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode([]Result{{}, {}}))
+	assert.Equal(t, 1, ExitCode([]Result{{}, {Err: errors.New("connection refused")}}))
+	assert.Equal(t, 22, ExitCode([]Result{{}, {Err: exitcode.ErrHTTPStatus}, {Err: exitcode.ErrWrite}}))
+}
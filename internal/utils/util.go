@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"github.com/mattn/go-isatty"
 	"io/fs"
 	"log"
 	"os"
@@ -16,3 +17,8 @@ func IsSocket(path string) (bool, error) {
 	}
 	return fileInfo.Mode().Type() == fs.ModeSocket, nil
 }
+
+// IsTerminal checks if the provided file is attached to a terminal.
+func IsTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
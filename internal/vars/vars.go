@@ -0,0 +1,94 @@
+package vars
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// MaxDepth caps how deep variables referencing other variables are expanded, to catch cycles.
+	MaxDepth = 16
+	// refRe matches a {{name}} reference, with optional whitespace around the name.
+	refRe = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+	// dynamic maps the names of the built-in $variables to the function computing their value from the
+	// arguments following the name.
+	dynamic = map[string]func(args []string) (string, error){
+		"$guid": func([]string) (string, error) {
+			return uuid.NewString(), nil
+		},
+		"$timestamp": func([]string) (string, error) {
+			return strconv.FormatInt(time.Now().Unix(), 10), nil
+		},
+		"$randomInt": func(args []string) (string, error) {
+			if len(args) != 2 {
+				return "", fmt.Errorf("$randomInt takes a min and a max")
+			}
+			min, err1 := strconv.Atoi(args[0])
+			max, err2 := strconv.Atoi(args[1])
+			if err1 != nil || err2 != nil || max <= min {
+				return "", fmt.Errorf("$randomInt takes a min and a greater max")
+			}
+			return strconv.Itoa(min + rand.Intn(max-min)), nil
+		},
+		"$processEnv": func(args []string) (string, error) {
+			if len(args) != 1 {
+				return "", fmt.Errorf("$processEnv takes the name of an environment variable")
+			}
+			return os.Getenv(args[0]), nil
+		},
+	}
+)
+
+// Vars maps variable names to their value. Values may reference other variables.
+type Vars map[string]string
+
+// Merge returns the variables of v overridden by every one of others, in order.
+func (v Vars) Merge(others ...Vars) Vars {
+	out := Vars{}
+	for _, vs := range append([]Vars{v}, others...) {
+		for k, val := range vs {
+			out[k] = val
+		}
+	}
+	return out
+}
+
+// Expand replaces every {{name}} reference in s with the value of the variable. The built-in $guid,
+// $timestamp, {{$randomInt min max}} and {{$processEnv NAME}} are computed on every reference.
+// Referencing an undefined variable is an error.
+func (v Vars) Expand(s string) (string, error) {
+	return v.expand(s, 0)
+}
+
+// expand expands s, depth references deep.
+func (v Vars) expand(s string, depth int) (string, error) {
+	if depth > MaxDepth {
+		return "", fmt.Errorf("variables nested more than %d deep, they probably reference each other", MaxDepth)
+	}
+	var err error
+	out := refRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		fields := strings.Fields(refRe.FindStringSubmatch(ref)[1])
+		if fn, ok := dynamic[fields[0]]; ok {
+			var val string
+			val, err = fn(fields[1:])
+			return val
+		}
+		val, ok := v[fields[0]]
+		if !ok || len(fields) > 1 {
+			err = fmt.Errorf("variable \"%s\" is not defined", strings.Join(fields, " "))
+			return ref
+		}
+		val, err = v.expand(val, depth+1)
+		return val
+	})
+	return out, err
+}
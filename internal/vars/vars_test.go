@@ -0,0 +1,40 @@
+package vars
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+// TestExpand checks substitution of nested, built-in and undefined variables.
+func TestExpand(t *testing.T) {
+	v := Vars{"host": "https://example.com", "users": "{{host}}/users", "id": "42"}
+	t.Setenv("SCOUR_TEST_VAR", "env")
+	out, err := v.Expand("GET {{ users }}/{{id}}?x={{$processEnv SCOUR_TEST_VAR}}")
+	assert.NoError(t, err)
+	assert.Equal(t, "GET https://example.com/users/42?x=env", out)
+
+	out, err = v.Expand("{{$randomInt 5 6}}")
+	assert.NoError(t, err)
+	assert.Equal(t, "5", out)
+	out, err = v.Expand("{{$timestamp}}")
+	assert.NoError(t, err)
+	_, err = strconv.Atoi(out)
+	assert.NoError(t, err)
+	out, err = v.Expand("{{$guid}}")
+	assert.NoError(t, err)
+	assert.Len(t, out, 36)
+
+	_, err = v.Expand("{{missing}}")
+	assert.ErrorContains(t, err, "\"missing\" is not defined")
+	_, err = Vars{"a": "{{b}}", "b": "{{a}}"}.Expand("{{a}}")
+	assert.Error(t, err)
+	_, err = v.Expand("{{$randomInt 5}}")
+	assert.Error(t, err)
+}
+
+// TestMerge checks that later variables override earlier ones.
+func TestMerge(t *testing.T) {
+	merged := Vars{"a": "1", "b": "1"}.Merge(Vars{"b": "2"}, Vars{"c": "3"})
+	assert.Equal(t, Vars{"a": "1", "b": "2", "c": "3"}, merged)
+}